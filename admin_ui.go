@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"net/http"
+	"time"
+)
+
+// adminUI — одностраничная панель администратора: проекты и товары,
+// правка записей, перестановка приоритетов перетаскиванием и журнал аудита.
+// Страница обращается к тем же маршрутам API с арендатором и токеном,
+// которые вводит пользователь.
+//
+//go:embed ui/index.html
+var adminUI []byte
+
+func adminUIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeContent(w, r, "index.html", time.Time{}, bytes.NewReader(adminUI))
+	}
+}
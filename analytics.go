@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hezzl-test/internal/bind"
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"time"
+)
+
+const analyticsBuckets = 30
+
+var analyticsIntervals = map[string]struct {
+	step  time.Duration
+	trunc string
+}{
+	"1h": {time.Hour, "toStartOfHour"},
+	"1d": {24 * time.Hour, "toStartOfDay"},
+	"1w": {7 * 24 * time.Hour, "toMonday"},
+}
+
+type EventCount struct {
+	Bucket    time.Time `json:"bucket"`
+	EventType string    `json:"eventType"`
+	Count     int       `json:"count"`
+}
+
+type EditedGood struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Edits int    `json:"edits"`
+}
+
+type BucketCount struct {
+	Bucket time.Time `json:"bucket"`
+	Count  int       `json:"count"`
+}
+
+type GoodsActivity struct {
+	ProjectID       int           `json:"projectId"`
+	Interval        string        `json:"interval"`
+	From            time.Time     `json:"from"`
+	To              time.Time     `json:"to"`
+	Events          []EventCount  `json:"events"`
+	TopEdited       []EditedGood  `json:"topEdited"`
+	PriorityChanges []BucketCount `json:"priorityChanges"`
+	Editors         []BucketCount `json:"editors"`
+}
+
+// goodsActivityParams — параметры GET /analytics/goods/activity; interval — ключ
+// analyticsIntervals.
+type goodsActivityParams struct {
+	ProjectID int    `query:"projectId,required"`
+	Interval  string `query:"interval" enum:"1h,1d,1w"`
+}
+
+func goodsActivityHandler(projects storage.ProjectsRepository, clickhouse *sql.DB, redisClient deps.Cache, budgets *cachebudget.Budget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := goodsActivityParams{Interval: "1d"}
+		if err := bind.Query(r.URL.Query(), &params); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		projectID, interval := params.ProjectID, params.Interval
+		bucket := analyticsIntervals[interval]
+
+		tenantID := tenant.FromContext(r.Context())
+
+		if _, err := projects.Get(r.Context(), tenantID, projectID); err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		// Границы выравниваются по интервалу, чтобы соседние запросы попадали в один ключ кэша.
+		to := clk.Now().UTC().Truncate(bucket.step).Add(bucket.step)
+		from := to.Add(-analyticsBuckets * bucket.step)
+
+		cacheKey := fmt.Sprintf("analytics:activity:%d:%d:%s:%d", tenantID, projectID, interval, to.Unix())
+		if cached, err := redisClient.Get(r.Context(), cacheKey).Bytes(); err == nil {
+			var activity GoodsActivity
+			if err := json.Unmarshal(cached, &activity); err == nil {
+				response.JSON(w, r, http.StatusOK, activity)
+				return
+			}
+		}
+
+		activity := GoodsActivity{ProjectID: projectID, Interval: interval, From: from, To: to}
+		if err := loadGoodsActivity(r.Context(), clickhouse, bucket.trunc, &activity); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if data, err := json.Marshal(activity); err == nil {
+			budgets.Set(r.Context(), projectID, cacheKey, data, analyticsCacheTime)
+		}
+
+		response.JSON(w, r, http.StatusOK, activity)
+	}
+}
+
+func loadGoodsActivity(ctx context.Context, clickhouse *sql.DB, trunc string, activity *GoodsActivity) error {
+	activity.Events = []EventCount{}
+	activity.TopEdited = []EditedGood{}
+	activity.PriorityChanges = []BucketCount{}
+	activity.Editors = []BucketCount{}
+
+	// Счётчики событий и авторов берутся из почасовых агрегатов
+	// goods_log_hourly; интервалы длиннее часа складываются из них.
+	rows, err := clickhouse.QueryContext(ctx, fmt.Sprintf(`SELECT %s(Hour) AS bucket, EventType, sum(Events)
+		FROM goods_log_hourly
+		WHERE ProjectId = ? AND Hour >= ? AND Hour < ?
+		GROUP BY bucket, EventType
+		ORDER BY bucket, EventType`, trunc),
+		activity.ProjectID, activity.From, activity.To)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var c EventCount
+		if err := rows.Scan(&c.Bucket, &c.EventType, &c.Count); err != nil {
+			rows.Close()
+			return err
+		}
+		activity.Events = append(activity.Events, c)
+		if c.EventType == "good_reprioritized" || c.EventType == "goods_reordered" {
+			activity.PriorityChanges = append(activity.PriorityChanges, BucketCount{Bucket: c.Bucket, Count: c.Count})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = clickhouse.QueryContext(ctx, fmt.Sprintf(`SELECT %s(Hour) AS bucket, uniqMerge(Editors)
+		FROM goods_log_hourly
+		WHERE ProjectId = ? AND Hour >= ? AND Hour < ?
+		GROUP BY bucket
+		ORDER BY bucket`, trunc),
+		activity.ProjectID, activity.From, activity.To)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var c BucketCount
+		if err := rows.Scan(&c.Bucket, &c.Count); err != nil {
+			rows.Close()
+			return err
+		}
+		activity.Editors = append(activity.Editors, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = clickhouse.QueryContext(ctx, `SELECT Id, argMax(Name, EventTime), count() AS edits
+		FROM goods_log
+		WHERE ProjectId = ? AND EventTime >= ? AND EventTime < ? AND EventType = 'good_updated'
+		GROUP BY Id
+		ORDER BY edits DESC, Id
+		LIMIT 10`,
+		activity.ProjectID, activity.From, activity.To)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var g EditedGood
+		if err := rows.Scan(&g.ID, &g.Name, &g.Edits); err != nil {
+			return err
+		}
+		activity.TopEdited = append(activity.TopEdited, g)
+	}
+	return rows.Err()
+}
+
+type PriorityPoint struct {
+	Time      time.Time `json:"time"`
+	EventType string    `json:"event_type"`
+	Priority  int       `json:"priority"`
+}
+
+type PriorityHistory struct {
+	ID        int             `json:"id"`
+	ProjectID int             `json:"project_id"`
+	Points    []PriorityPoint `json:"points"`
+}
+
+// priorityHistoryHandler отдаёт по журналу goods_log, как менялся приоритет
+// товара id: точка на создание и на каждое событие, после которого приоритет
+// стал другим. История удалённого товара остаётся доступной.
+func priorityHistoryHandler(projects storage.ProjectsRepository, clickhouse *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goodID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		history, err := loadPriorityHistory(r.Context(), clickhouse, goodID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if history.ProjectID == 0 {
+			response.Fail(w, r, errGoodNotFound)
+			return
+		}
+
+		// В goods_log нет арендатора: товар принадлежит ему, если ему
+		// принадлежит последний проект товара.
+		_, err = projects.Get(r.Context(), tenant.FromContext(r.Context()), history.ProjectID)
+		if errors.Is(err, errs.ErrNotFound) {
+			err = errGoodNotFound
+		}
+		if err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, history)
+	}
+}
+
+func loadPriorityHistory(ctx context.Context, clickhouse *sql.DB, goodID int) (PriorityHistory, error) {
+	history := PriorityHistory{ID: goodID, Points: []PriorityPoint{}}
+
+	rows, err := clickhouse.QueryContext(ctx, `SELECT EventTime, EventType, Priority, ProjectId
+		FROM goods_log
+		WHERE Id = ? AND EventType IN ('new_good_created', 'good_updated', 'goods_reordered')
+		ORDER BY EventTime`, goodID)
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p PriorityPoint
+		if err := rows.Scan(&p.Time, &p.EventType, &p.Priority, &history.ProjectID); err != nil {
+			return history, err
+		}
+		if n := len(history.Points); n > 0 && history.Points[n-1].Priority == p.Priority {
+			continue
+		}
+		history.Points = append(history.Points, p)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hezzl-test/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// asOfSnapshot восстанавливает по журналу goods_log в ClickHouse состояние
+// каждого товара на момент asOf: последнее событие с полным состоянием
+// товара не позже asOf, кроме good_deleted. Строки goods_reordered несут
+// только приоритет, поэтому он берётся из последнего события вместе с
+// ними. Журнал не хранит labels, категории и просмотры, поэтому эти поля в
+// ответе пустые.
+const asOfSnapshot = `SELECT Id, ProjectId, Name, Description, Priority, Removed, FirstSeen
+	FROM (
+		SELECT Id,
+			argMaxIf(ProjectId, EventTime, EventType != 'goods_reordered') AS ProjectId,
+			argMaxIf(Name, EventTime, EventType != 'goods_reordered') AS Name,
+			argMaxIf(Description, EventTime, EventType != 'goods_reordered') AS Description,
+			argMaxIf(Priority, EventTime, EventType != 'good_deleted') AS Priority,
+			argMaxIf(Removed, EventTime, EventType != 'goods_reordered') AS Removed,
+			argMaxIf(EventType, EventTime, EventType != 'goods_reordered') AS LastEvent,
+			minIf(EventTime, EventType != 'goods_reordered') AS FirstSeen
+		FROM goods_log
+		WHERE EventType IN ('new_good_created', 'good_updated', 'good_deleted', 'goods_reordered') AND EventTime <= ? AND ProjectId IN (%s)
+		GROUP BY Id
+		HAVING countIf(EventType != 'goods_reordered') > 0
+	)
+	WHERE LastEvent != 'good_deleted'`
+
+func loadGoodsAsOf(ctx context.Context, db, clickhouse *sql.DB, tenantID int, asOf time.Time, limit, offset int) (storage.Page, []storage.Good, error) {
+	var page storage.Page
+	goods := []storage.Good{}
+
+	// В goods_log нет арендатора: товары отбираются по его проектам,
+	// включая удалённые и архивные.
+	rows, err := db.QueryContext(ctx, "SELECT id FROM projects WHERE tenant_id = $1", tenantID)
+	if err != nil {
+		return page, nil, err
+	}
+	var projects []string
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return page, nil, err
+		}
+		projects = append(projects, strconv.Itoa(id))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return page, nil, err
+	}
+	if len(projects) == 0 {
+		return page, goods, nil
+	}
+
+	snapshot := fmt.Sprintf(asOfSnapshot, strings.Join(projects, ", "))
+	err = clickhouse.QueryRowContext(ctx, "SELECT count(), countIf(Removed = 1) FROM ("+snapshot+")", asOf).
+		Scan(&page.Total, &page.Removed)
+	if err != nil {
+		return page, nil, err
+	}
+
+	rows, err = clickhouse.QueryContext(ctx, snapshot+" ORDER BY Priority, Id LIMIT ? OFFSET ?", asOf, limit, offset)
+	if err != nil {
+		return page, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var good storage.Good
+		if err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, &good.Description, &good.Priority, &good.Removed, &good.CreatedAt); err != nil {
+			return page, nil, err
+		}
+		goods = append(goods, good)
+	}
+	return page, goods, rows.Err()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hezzl-test/internal/objectstore"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"path"
+	"time"
+)
+
+type Attachment struct {
+	ID          int       `json:"id"`
+	GoodID      int       `json:"good_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	URL         string    `json:"url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type NewAttachment struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+}
+
+type AttachmentUpload struct {
+	Attachment Attachment `json:"attachment"`
+	UploadURL  string     `json:"upload_url"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+func createAttachmentHandler(db *sql.DB, s3 *objectstore.S3) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goodID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		var req NewAttachment
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if req.FileName == "" || req.ContentType == "" {
+			response.BadRequest(w, r, errors.New("file_name and content_type are required"))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		var projectID int
+		err = db.QueryRow("SELECT project_id FROM goods WHERE id = $1 AND tenant_id = $2", goodID, tenantID).Scan(&projectID)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errGoodNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := storage.CheckWritable(r.Context(), db, tenantID, projectID); err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		key := fmt.Sprintf("goods/%d/%s-%s", goodID, ids.NewID(), path.Base(req.FileName))
+
+		attachment := Attachment{GoodID: goodID, FileName: req.FileName, ContentType: req.ContentType}
+		err = db.QueryRow("INSERT INTO good_attachments (good_id, object_key, file_name, content_type) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+			goodID, key, req.FileName, req.ContentType).Scan(&attachment.ID, &attachment.CreatedAt)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		uploadURL, err := s3.PresignPut(key, s3PresignTime)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusCreated, AttachmentUpload{
+			Attachment: attachment,
+			UploadURL:  uploadURL,
+			ExpiresAt:  clk.Now().Add(s3PresignTime),
+		})
+	}
+}
+
+func listAttachmentsHandler(db *sql.DB, s3 *objectstore.S3) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goodID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		rows, err := db.Query(`SELECT a.id, a.good_id, a.object_key, a.file_name, a.content_type, a.created_at
+			FROM good_attachments a JOIN goods g ON g.id = a.good_id
+			WHERE a.good_id = $1 AND g.tenant_id = $2
+			ORDER BY a.id`,
+			goodID, tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		attachments := []Attachment{}
+		for rows.Next() {
+			var attachment Attachment
+			var key string
+			err := rows.Scan(&attachment.ID, &attachment.GoodID, &key, &attachment.FileName, &attachment.ContentType, &attachment.CreatedAt)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			if attachment.URL, err = s3.PresignGet(key, s3PresignTime); err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			attachments = append(attachments, attachment)
+		}
+
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, attachments)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/clock"
+	"hezzl-test/internal/objectstore"
+)
+
+func TestCreateAttachmentHandler(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	useClock(t, clock.NewManual(now))
+	useIDs(t, &clock.Sequence{Prefix: "att"})
+
+	db, _ := newFakeDB(t,
+		fakeQuery{match: "SELECT project_id FROM goods", columns: []string{"project_id"}, rows: [][]driver.Value{{int64(3)}}},
+		fakeQuery{match: "SELECT archived, removed FROM projects", columns: []string{"archived", "removed"}, rows: [][]driver.Value{{false, false}}},
+		fakeQuery{
+			match:   "INSERT INTO good_attachments",
+			args:    argEquals(1, "goods/5/att1-photo.jpg"),
+			columns: []string{"id", "created_at"},
+			rows:    [][]driver.Value{{int64(9), now}},
+		},
+	)
+	s3, err := objectstore.NewS3("http://localhost:9100", "us-east-1", "goods", "key", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serveBody(t, createAttachmentHandler(db, s3), "POST", "/good/attachments?id=5",
+		`{"file_name": "photos/photo.jpg", "content_type": "image/jpeg"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var upload AttachmentUpload
+	decodeBody(t, w, &upload)
+	if upload.Attachment.ID != 9 || !strings.Contains(upload.UploadURL, "/goods/5/att1-photo.jpg") {
+		t.Errorf("upload = %+v", upload)
+	}
+	if want := now.Add(s3PresignTime); !upload.ExpiresAt.Equal(want) {
+		t.Errorf("expires_at = %v, want %v", upload.ExpiresAt, want)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"hezzl-test/internal/bind"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"time"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func audit(ctx context.Context, q execer, action, entity string, entityID int, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, "INSERT INTO audit_log (tenant_id, action, entity, entity_id, payload) VALUES ($1, $2, $3, $4, $5)",
+		tenant.FromContext(ctx), action, entity, entityID, data)
+	return err
+}
+
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entity_id"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type AuditLog struct {
+	Meta    response.Meta       `json:"meta"`
+	Entries []AuditEntry        `json:"entries"`
+	Links   *response.PageLinks `json:"links,omitempty"`
+}
+
+// auditLogParams — параметры GET /admin/audit.
+type auditLogParams struct {
+	pageQuery
+	Entity   string `query:"entity"`
+	EntityID int    `query:"entityId"`
+}
+
+// auditLogHandler отдаёт журнал изменений арендатора от новых записей к
+// старым; entity и entityId сужают его до одной сущности.
+func auditLogHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := auditLogParams{pageQuery: pageQuery{Limit: defaultLimit}}
+		if err := bind.Query(r.URL.Query(), &params); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		limit, offset := params.Limit, params.Offset
+		entity, entityID := params.Entity, params.EntityID
+
+		tenantID := tenant.FromContext(r.Context())
+		log := AuditLog{
+			Meta:    response.Meta{Limit: limit, Offset: offset},
+			Entries: []AuditEntry{},
+		}
+
+		const filter = "tenant_id = $1 AND ($2 = '' OR entity = $2) AND ($3 = 0 OR entity_id = $3)"
+		err := db.QueryRowContext(r.Context(), "SELECT count(*) FROM audit_log WHERE "+filter, tenantID, entity, entityID).
+			Scan(&log.Meta.Total)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		rows, err := db.QueryContext(r.Context(), `SELECT id, action, entity, entity_id, payload, created_at
+			FROM audit_log WHERE `+filter+`
+			ORDER BY id DESC LIMIT $4 OFFSET $5`, tenantID, entity, entityID, limit, offset)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e AuditEntry
+			if err := rows.Scan(&e.ID, &e.Action, &e.Entity, &e.EntityID, &e.Payload, &e.CreatedAt); err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			log.Entries = append(log.Entries, e)
+		}
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		log.Links = pageLinks(r, log.Meta)
+		response.Pagination(w, r, log.Meta)
+		response.JSON(w, r, http.StatusOK, log)
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/labels"
+	"hezzl-test/internal/objectstore"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const backupVersion = 1
+
+// Backup — переносимый снимок проектов, их настроек и товаров арендатора.
+// Идентификаторы в нём служат только для связи товаров с проектами: при
+// восстановлении записи получают новые id.
+type Backup struct {
+	Version   int             `json:"version"`
+	TenantID  int             `json:"tenant_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Projects  []BackupProject `json:"projects"`
+	Goods     []BackupGood    `json:"goods"`
+}
+
+// BackupProject.Settings — nil, если проект не менял настройки по умолчанию.
+type BackupProject struct {
+	ID        int              `json:"id"`
+	Name      string           `json:"name"`
+	Archived  bool             `json:"archived"`
+	CreatedAt time.Time        `json:"created_at"`
+	Settings  *ProjectSettings `json:"settings,omitempty"`
+}
+
+type BackupGood struct {
+	ID          int           `json:"id"`
+	ProjectID   int           `json:"project_id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Priority    int           `json:"priority"`
+	Removed     bool          `json:"removed"`
+	RemovedAt   *time.Time    `json:"removed_at,omitempty"`
+	Labels      labels.Labels `json:"labels"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// backupHandler отдаёт снимок арендатора потоком, а с upload=true
+// загружает его в S3 и возвращает ключ и временную ссылку на скачивание.
+func backupHandler(db *sql.DB, s3 *objectstore.S3) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID := tenant.FromContext(r.Context())
+
+		if r.URL.Query().Get("upload") != "true" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="backup-%d-%s.json"`, tenantID, clk.Now().UTC().Format("20060102T150405Z")))
+			if err := writeBackup(r.Context(), db, tenantID, w); err != nil {
+				log.Printf("%s %s: backup: %v", r.Method, r.URL.Path, err)
+				panic(http.ErrAbortHandler)
+			}
+			return
+		}
+
+		f, err := os.CreateTemp("", "backup-*.json")
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		if err := writeBackup(r.Context(), db, tenantID, f); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		size, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		key := fmt.Sprintf("backups/%d/%s.json", tenantID, clk.Now().UTC().Format("20060102T150405Z"))
+		if err := s3.Put(r.Context(), key, "application/json", f, size); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		url, err := s3.PresignGet(key, s3PresignTime)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusCreated, map[string]interface{}{"key": key, "url": url, "size": size})
+	}
+}
+
+// writeBackup пишет снимок построчно из одной транзакции REPEATABLE READ,
+// чтобы проекты и товары были согласованы между собой.
+func writeBackup(ctx context.Context, db *sql.DB, tenantID int, w io.Writer) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	buf := bufio.NewWriterSize(w, listStreamBufferSize)
+	enc := json.NewEncoder(buf)
+
+	fmt.Fprintf(buf, `{"version":%d,"tenant_id":%d,"created_at":`, backupVersion, tenantID)
+	if err := enc.Encode(clk.Now().UTC()); err != nil {
+		return err
+	}
+
+	settings, err := backupSettings(ctx, tx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString(`,"projects":[`)
+	rows, err := tx.QueryContext(ctx, "SELECT id, name, archived, created_at FROM projects WHERE tenant_id = $1 AND NOT removed ORDER BY id", tenantID)
+	if err != nil {
+		return err
+	}
+	for i := 0; rows.Next(); i++ {
+		var p BackupProject
+		if err := rows.Scan(&p.ID, &p.Name, &p.Archived, &p.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		p.Settings = settings[p.ID]
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		if err := enc.Encode(p); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	buf.WriteString(`],"goods":[`)
+	rows, err = tx.QueryContext(ctx, `SELECT g.id, g.project_id, g.name, g.description, g.priority, g.removed, g.removed_at, g.labels, g.created_at
+		FROM goods g JOIN projects p ON p.id = g.project_id
+		WHERE g.tenant_id = $1 AND NOT p.removed ORDER BY g.project_id, g.priority, g.id`, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for i := 0; rows.Next(); i++ {
+		var g BackupGood
+		if err := rows.Scan(&g.ID, &g.ProjectID, &g.Name, decrypted{&g.Description}, &g.Priority, &g.Removed, &g.RemovedAt, &g.Labels, &g.CreatedAt); err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		if err := enc.Encode(g); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	buf.WriteString("]}\n")
+	return buf.Flush()
+}
+
+// backupSettings возвращает изменённые настройки проектов арендатора по id
+// проекта.
+func backupSettings(ctx context.Context, tx *sql.Tx, tenantID int) (map[int]*ProjectSettings, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT s.project_id, s.priority_strategy, s.priority_gap, s.cache_ttl, s.default_locale, s.webhooks_enabled, s.encrypt_description, s.max_goods
+		FROM project_settings s JOIN projects p ON p.id = s.project_id
+		WHERE p.tenant_id = $1 AND NOT p.removed`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	settings := make(map[int]*ProjectSettings)
+	for rows.Next() {
+		var s ProjectSettings
+		if err := rows.Scan(&s.ProjectID, &s.PriorityStrategy, &s.PriorityGap, &s.CacheTTL, &s.DefaultLocale, &s.WebhooksEnabled, &s.EncryptDescription, &s.MaxGoods); err != nil {
+			return nil, err
+		}
+		settings[s.ProjectID] = &s
+	}
+	return settings, rows.Err()
+}
+
+// validateBackupSettings проверяет настройки проекта из снимка так же, как
+// их изменение через API.
+func validateBackupSettings(s ProjectSettings) error {
+	patch := ProjectSettingsPatch{
+		PriorityStrategy:   &s.PriorityStrategy,
+		PriorityGap:        s.PriorityGap,
+		CacheTTL:           s.CacheTTL,
+		DefaultLocale:      &s.DefaultLocale,
+		WebhooksEnabled:    &s.WebhooksEnabled,
+		EncryptDescription: &s.EncryptDescription,
+	}
+	if err := patch.Validate(); err != nil {
+		return err
+	}
+	if s.MaxGoods != nil && *s.MaxGoods < 0 {
+		return fmt.Errorf("invalid max_goods %d", *s.MaxGoods)
+	}
+	return nil
+}
+
+// restoreHandler загружает снимок в текущего арендатора одной транзакцией:
+// проекты создаются заново вместе с настройками, товары копируются в них
+// через COPY. Товары в корзине сохраняют removed_at, чтобы срок хранения
+// корзины не начинался заново; в снимках без removed_at берётся время
+// восстановления.
+func restoreHandler(db *sql.DB, redisClient deps.Cache, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var backup Backup
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, importMaxBytes)).Decode(&backup); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if backup.Version != backupVersion {
+			response.BadRequest(w, r, fmt.Errorf("unsupported backup version %d", backup.Version))
+			return
+		}
+
+		settings := make(map[int]ProjectSettings, len(backup.Projects))
+		for _, p := range backup.Projects {
+			settings[p.ID] = defaultProjectSettings(p.ID)
+			if p.Settings == nil {
+				continue
+			}
+			if err := validateBackupSettings(*p.Settings); err != nil {
+				response.BadRequest(w, r, fmt.Errorf("project %d: %w", p.ID, err))
+				return
+			}
+			settings[p.ID] = *p.Settings
+		}
+		for _, g := range backup.Goods {
+			if _, ok := settings[g.ProjectID]; !ok {
+				response.BadRequest(w, r, fmt.Errorf("good %d refers to unknown project %d", g.ID, g.ProjectID))
+				return
+			}
+			if err := g.Labels.Validate(); err != nil {
+				response.BadRequest(w, r, fmt.Errorf("good %d: %w", g.ID, err))
+				return
+			}
+			if err := checkDescription(g.Description); err != nil {
+				response.BadRequest(w, r, fmt.Errorf("good %d: %w", g.ID, err))
+				return
+			}
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		newIDs := make(map[int]int, len(backup.Projects))
+		for _, p := range backup.Projects {
+			var id int
+			err := tx.QueryRowContext(r.Context(), "INSERT INTO projects (tenant_id, name, archived, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+				tenantID, p.Name, p.Archived, p.CreatedAt).Scan(&id)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			newIDs[p.ID] = id
+
+			if s := p.Settings; s != nil {
+				_, err := tx.ExecContext(r.Context(), `INSERT INTO project_settings (project_id, priority_strategy, priority_gap, cache_ttl, default_locale, webhooks_enabled, encrypt_description, max_goods)
+					VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0), $5, $6, $7, $8)`,
+					id, s.PriorityStrategy, s.PriorityGap, s.CacheTTL, s.DefaultLocale, s.WebhooksEnabled, s.EncryptDescription, s.MaxGoods)
+				if err != nil {
+					response.InternalError(w, r, err)
+					return
+				}
+			}
+		}
+
+		stmt, err := tx.PrepareContext(r.Context(), pq.CopyIn("goods",
+			"tenant_id", "project_id", "name", "description", "priority", "removed", "removed_at", "labels", "created_at"))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		now := clk.Now()
+		for _, g := range backup.Goods {
+			var removedAt *time.Time
+			if g.Removed {
+				removedAt = g.RemovedAt
+				if removedAt == nil {
+					removedAt = &now
+				}
+			}
+			labelsJSON, err := g.Labels.Value()
+			if err != nil {
+				stmt.Close()
+				response.InternalError(w, r, err)
+				return
+			}
+			description, err := sealDescription(settings[g.ProjectID], g.Description)
+			if err != nil {
+				stmt.Close()
+				response.InternalError(w, r, err)
+				return
+			}
+			_, err = stmt.ExecContext(r.Context(), tenantID, newIDs[g.ProjectID], g.Name, description, g.Priority, g.Removed, removedAt, labelsJSON, g.CreatedAt)
+			if err != nil {
+				stmt.Close()
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+		if _, err := stmt.ExecContext(r.Context()); err != nil {
+			stmt.Close()
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := stmt.Close(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		// Счётчики приоритетов восстановленных проектов продолжают нумерацию
+		// после последнего товара из резервной копии.
+		lastPriority := make(map[int]int, len(newIDs))
+		for _, g := range backup.Goods {
+			if id := newIDs[g.ProjectID]; g.Priority > lastPriority[id] {
+				lastPriority[id] = g.Priority
+			}
+		}
+		for _, id := range newIDs {
+			_, err := tx.ExecContext(r.Context(), "INSERT INTO project_priority_counters (project_id, last_priority) VALUES ($1, $2)",
+				id, lastPriority[id])
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		summary := map[string]int{"projects": len(backup.Projects), "goods": len(backup.Goods)}
+		for _, id := range newIDs {
+			if err := audit(r.Context(), tx, "restore", "project", id, summary); err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		err = effects.Submit(r.Context(), "invalidate_goods_lists", func(ctx context.Context) error {
+			return invalidateGoodsLists(ctx, redisClient, tenantID)
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, summary)
+	}
+}
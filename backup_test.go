@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWriteBackup(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	removedAt := time.Date(2024, 5, 2, 8, 30, 0, 0, time.UTC)
+	db, _ := newFakeDB(t,
+		fakeQuery{match: "FROM project_settings s JOIN projects p",
+			args:    argEquals(0, testTenantID),
+			columns: []string{"project_id", "priority_strategy", "priority_gap", "cache_ttl", "default_locale", "webhooks_enabled", "encrypt_description", "max_goods"},
+			rows:    [][]driver.Value{{int64(3), "gap", int64(100), nil, "ru", false, false, int64(50)}}},
+		fakeQuery{match: "FROM projects WHERE tenant_id = $1",
+			columns: []string{"id", "name", "archived", "created_at"},
+			rows:    [][]driver.Value{{int64(3), "Shop", false, created}, {int64(4), "Archive", true, created}}},
+		fakeQuery{match: "FROM goods g JOIN projects p",
+			columns: []string{"id", "project_id", "name", "description", "priority", "removed", "removed_at", "labels", "created_at"},
+			rows: [][]driver.Value{
+				{int64(11), int64(3), "Tea", "green tea", int64(1), false, nil, []byte("{}"), created},
+				{int64(12), int64(3), "Coffee", "", int64(2), true, removedAt, []byte("{}"), created},
+			}},
+	)
+
+	var buf bytes.Buffer
+	if err := writeBackup(context.Background(), db, testTenantID, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var backup Backup
+	if err := json.Unmarshal(buf.Bytes(), &backup); err != nil {
+		t.Fatalf("%v in %s", err, buf.Bytes())
+	}
+
+	if len(backup.Projects) != 2 {
+		t.Fatalf("projects = %+v", backup.Projects)
+	}
+	s := backup.Projects[0].Settings
+	if s == nil || s.PriorityStrategy != "gap" || s.PriorityGapSize() != 100 || s.GoodsLimit() != 50 || s.WebhooksEnabled {
+		t.Fatalf("project 3 settings = %+v", s)
+	}
+	// Снятые настройки проходят проверку при восстановлении.
+	if err := validateBackupSettings(*s); err != nil {
+		t.Errorf("validateBackupSettings = %v", err)
+	}
+	if backup.Projects[1].Settings != nil {
+		t.Errorf("project 4 has default settings, got %+v", backup.Projects[1].Settings)
+	}
+
+	if len(backup.Goods) != 2 {
+		t.Fatalf("goods = %+v", backup.Goods)
+	}
+	if backup.Goods[0].RemovedAt != nil {
+		t.Errorf("removed_at of a live good = %v", backup.Goods[0].RemovedAt)
+	}
+	if got := backup.Goods[1].RemovedAt; got == nil || !got.Equal(removedAt) {
+		t.Errorf("removed_at = %v, want %v", got, removedAt)
+	}
+}
+
+func TestRestoreRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+	}{
+		{"strategy", `{"priority_strategy":"random","default_locale":"ru"}`},
+		{"locale", `{"priority_strategy":"shift","default_locale":"xx"}`},
+		{"quota", `{"priority_strategy":"shift","default_locale":"ru","max_goods":-1}`},
+		{"encryption without keys", `{"priority_strategy":"shift","default_locale":"ru","encrypt_description":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// До базы запрос не доходит.
+			h := restoreHandler(nil, nil, nil)
+			body := `{"version":1,"projects":[{"id":3,"name":"Shop","settings":` + tt.settings + `}],"goods":[]}`
+			if w := serveBody(t, h, "POST", "/admin/restore", body); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, body %s", w.Code, w.Body)
+			}
+		})
+	}
+}
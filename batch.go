@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"log"
+	"net/http"
+	"time"
+)
+
+type GoodsBatch struct {
+	Goods   []Goods `json:"goods"`
+	Missing []int   `json:"missing"`
+}
+
+// batchGoodsHandler отдаёт товары по списку ids в порядке запроса. Карточки
+// читаются из кэша одним MGET, промахи — одним запросом к базе, после чего
+// попадают в кэш. Ненайденные id перечисляются в missing.
+func batchGoodsHandler(goods storage.GoodsRepository, redisClient deps.Cache, budgets *cachebudget.Budget, cacheTTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids, err := queryInts(r, "ids")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		ids = uniqueInts(ids)
+		if len(ids) > maxBatchIDs {
+			response.BadRequest(w, r, fmt.Errorf("at most %d ids are allowed", maxBatchIDs))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = goodCacheKey(tenantID, id)
+		}
+		cached, err := redisClient.MGet(r.Context(), keys...).Result()
+		if err != nil {
+			log.Printf("cache: mget goods: %v", err)
+			cached = nil
+		}
+
+		found := make(map[int]Goods, len(ids))
+		var misses []int
+		for i, id := range ids {
+			if i < len(cached) {
+				if data, ok := cached[i].(string); ok {
+					var good Goods
+					if err := json.Unmarshal([]byte(data), &good); err == nil {
+						found[id] = good
+						budgets.Touch(keys[i])
+						continue
+					}
+				}
+			}
+			misses = append(misses, id)
+		}
+
+		if len(misses) > 0 {
+			stored, err := goods.GetMany(r.Context(), tenantID, misses)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			for _, s := range stored {
+				good := goodFromStorage(s)
+				found[good.ID] = good
+
+				if data, err := json.Marshal(good); err == nil {
+					budgets.Set(r.Context(), good.ProjectID, goodCacheKey(tenantID, good.ID), data, cacheTTL)
+				}
+			}
+		}
+
+		batch := GoodsBatch{Goods: []Goods{}, Missing: []int{}}
+		for _, id := range ids {
+			if good, ok := found[id]; ok {
+				good.link()
+				batch.Goods = append(batch.Goods, good)
+			} else {
+				batch.Missing = append(batch.Missing, id)
+			}
+		}
+
+		response.JSON(w, r, http.StatusOK, batch)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/mock/gomock"
+
+	"hezzl-test/internal/cachebudget"
+	depsmocks "hezzl-test/internal/deps/mocks"
+	"hezzl-test/internal/localcache"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/storage/mocks"
+)
+
+func TestBatchGoodsHandler(t *testing.T) {
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	budgets := cachebudget.New(cache, 1<<20)
+
+	cached, _ := json.Marshal(Goods{ID: 1, ProjectID: 3, Name: "Cached"})
+	cache.Set(context.Background(), goodCacheKey(testTenantID, 1), cached, 0)
+
+	// Из базы читаются только промахи кэша, в порядке запроса.
+	ctrl := gomock.NewController(t)
+	goods := mocks.NewMockGoodsRepository(ctrl)
+	goods.EXPECT().GetMany(gomock.Any(), testTenantID, []int{2, 4}).Return([]storage.Good{{ID: 2, ProjectID: 3, Name: "Stored"}}, nil)
+
+	w := serve(t, batchGoodsHandler(goods, cache, budgets, time.Minute), "GET", "/goods?ids=2,1,4,2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var batch GoodsBatch
+	decodeBody(t, w, &batch)
+
+	var names []string
+	for _, good := range batch.Goods {
+		names = append(names, good.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Stored", "Cached"}) || !reflect.DeepEqual(batch.Missing, []int{4}) {
+		t.Fatalf("goods = %v, missing = %v", names, batch.Missing)
+	}
+
+	if _, err := cache.Get(context.Background(), goodCacheKey(testTenantID, 2)).Result(); err != nil {
+		t.Fatalf("good 2 is not cached: %v", err)
+	}
+}
+
+func TestBatchGoodsHandlerCacheDown(t *testing.T) {
+	// Ошибка кэша не ломает ответ: все товары читаются из базы.
+	ctrl := gomock.NewController(t)
+	cache := depsmocks.NewMockCache(ctrl)
+	cache.EXPECT().MGet(gomock.Any(), goodCacheKey(testTenantID, 1), goodCacheKey(testTenantID, 2)).
+		Return(redis.NewSliceResult(nil, errors.New("redis is down")))
+	cache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(redis.NewStatusResult("", errors.New("redis is down"))).AnyTimes()
+
+	goods := mocks.NewMockGoodsRepository(ctrl)
+	goods.EXPECT().GetMany(gomock.Any(), testTenantID, []int{1, 2}).Return([]storage.Good{{ID: 1, Name: "First"}, {ID: 2, Name: "Second"}}, nil)
+
+	w := serve(t, batchGoodsHandler(goods, cache, cachebudget.New(cache, 1<<20), time.Minute), "GET", "/goods?ids=1,2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var batch GoodsBatch
+	decodeBody(t, w, &batch)
+	if len(batch.Goods) != 2 || len(batch.Missing) != 0 {
+		t.Fatalf("batch = %+v", batch)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/metrics"
+	"hezzl-test/internal/response"
+	"net/http"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidateGoodsLists удаляет все закэшированные страницы списка товаров арендатора.
+func invalidateGoodsLists(ctx context.Context, redisClient deps.Cache, tenantID int) error {
+	return deleteKeys(ctx, redisClient, fmt.Sprintf("goods:list:%d:*", tenantID))
+}
+
+// invalidateProject удаляет из кэша всё, где могут встретиться проект и его
+// товары: карточки товаров, списки и связанные товары арендатора, аналитику
+// проекта и закэшированные ответы GET /projects.
+func invalidateProject(ctx context.Context, redisClient deps.Cache, tenantID, projectID int, goodIDs []int) error {
+	keys := make([]string, 0, len(goodIDs))
+	for _, id := range goodIDs {
+		keys = append(keys, goodCacheKey(tenantID, id))
+	}
+	if len(keys) > 0 {
+		if err := redisClient.Del(ctx, keys...).Err(); err != nil {
+			return err
+		}
+	}
+
+	patterns := []string{
+		fmt.Sprintf("goods:list:%d:*", tenantID),
+		fmt.Sprintf("goods:related:%d:*", tenantID),
+		fmt.Sprintf("analytics:activity:%d:%d:*", tenantID, projectID),
+		fmt.Sprintf("http:%d:*:/projects?*", tenantID),
+	}
+	for _, pattern := range patterns {
+		if err := deleteKeys(ctx, redisClient, pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pipeliner — клиенты Redis, умеющие конвейер: *redis.Client и кольцо
+// узлов. Кэш в памяти его не поддерживает и удаляет ключи одним Del.
+type pipeliner interface {
+	Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error)
+}
+
+// deleteKeys удаляет ключи по шаблону постранично: каждая страница SCAN
+// удаляется конвейером из UNLINK по cacheInvalidateBatch ключей, так что ни
+// память, ни одна команда не растут с числом ключей. Обход ограничен
+// cacheInvalidateMaxScans страницами; недочищенный шаблон возвращает ошибку.
+func deleteKeys(ctx context.Context, redisClient deps.Cache, pattern string) error {
+	cache := cacheName(pattern)
+	var cursor uint64
+	for scans := 0; ; scans++ {
+		if scans == cacheInvalidateMaxScans {
+			metrics.CacheInvalidationTruncated(cache)
+			return fmt.Errorf("invalidate %s: stopped after %d scans", pattern, scans)
+		}
+		keys, next, err := redisClient.Scan(ctx, cursor, pattern, cacheInvalidateScanCount).Result()
+		if err != nil {
+			return err
+		}
+		n, err := unlinkKeys(ctx, redisClient, keys)
+		metrics.CacheKeysInvalidated(cache, n)
+		if err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func unlinkKeys(ctx context.Context, redisClient deps.Cache, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	p, ok := redisClient.(pipeliner)
+	if !ok {
+		n, err := redisClient.Del(ctx, keys...).Result()
+		return int(n), err
+	}
+
+	removed := 0
+	for start := 0; start < len(keys); start += cacheInvalidateBatch {
+		batch := keys[start:min(start+cacheInvalidateBatch, len(keys))]
+		cmds, err := p.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range batch {
+				pipe.Unlink(ctx, key)
+			}
+			return nil
+		})
+		for _, cmd := range cmds {
+			if c, ok := cmd.(*redis.IntCmd); ok {
+				removed += int(c.Val())
+			}
+		}
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// cacheName — имя кэша для метрик: начало шаблона до первого id или
+// подстановки, например goods:list для goods:list:1:*.
+func cacheName(pattern string) string {
+	parts := strings.Split(pattern, ":")
+	n := 0
+	for n < len(parts) && parts[n] != "" && !strings.ContainsAny(parts[n], "*?[0123456789") {
+		n++
+	}
+	if n == 0 {
+		return "other"
+	}
+	return strings.Join(parts[:n], ":")
+}
+
+// cacheBudgetsHandler отдаёт место в кэше, занятое проектами по учёту этого
+// экземпляра, от больших к меньшим.
+func cacheBudgetsHandler(budgets *cachebudget.Budget) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, http.StatusOK, budgets.Usage())
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/mock/gomock"
+
+	"hezzl-test/internal/deps/mocks"
+)
+
+func TestDeleteKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cache := mocks.NewMockCache(ctrl)
+	ctx := context.Background()
+	pattern := "goods:list:7:*"
+
+	// SCAN продолжается с курсора предыдущей страницы, пока не вернёт 0;
+	// кэш без конвейера удаляет каждую страницу одним Del.
+	gomock.InOrder(
+		cache.EXPECT().Scan(ctx, uint64(0), pattern, int64(cacheInvalidateScanCount)).Return(redis.NewScanCmdResult([]string{"a", "b"}, 9, nil)),
+		cache.EXPECT().Del(ctx, "a", "b").Return(redis.NewIntResult(2, nil)),
+		cache.EXPECT().Scan(ctx, uint64(9), pattern, int64(cacheInvalidateScanCount)).Return(redis.NewScanCmdResult(nil, 4, nil)),
+		cache.EXPECT().Scan(ctx, uint64(4), pattern, int64(cacheInvalidateScanCount)).Return(redis.NewScanCmdResult([]string{"c"}, 0, nil)),
+		cache.EXPECT().Del(ctx, "c").Return(redis.NewIntResult(1, nil)),
+	)
+
+	if err := deleteKeys(ctx, cache, pattern); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteKeysErrors(t *testing.T) {
+	errCache := errors.New("redis is down")
+	tests := []struct {
+		name   string
+		expect func(cache *mocks.MockCache)
+	}{
+		{"scan", func(cache *mocks.MockCache) {
+			cache.EXPECT().Scan(gomock.Any(), uint64(0), gomock.Any(), gomock.Any()).Return(redis.NewScanCmdResult(nil, 0, errCache))
+		}},
+		{"del", func(cache *mocks.MockCache) {
+			cache.EXPECT().Scan(gomock.Any(), uint64(0), gomock.Any(), gomock.Any()).Return(redis.NewScanCmdResult([]string{"a"}, 3, nil))
+			cache.EXPECT().Del(gomock.Any(), "a").Return(redis.NewIntResult(0, errCache))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := mocks.NewMockCache(gomock.NewController(t))
+			tt.expect(cache)
+			if err := deleteKeys(context.Background(), cache, "goods:list:7:*"); !errors.Is(err, errCache) {
+				t.Fatalf("err = %v, want %v", err, errCache)
+			}
+		})
+	}
+}
+
+func TestInvalidateProject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	cache := mocks.NewMockCache(ctrl)
+
+	cache.EXPECT().Del(gomock.Any(), goodCacheKey(7, 1), goodCacheKey(7, 2)).Return(redis.NewIntResult(2, nil))
+	var patterns []string
+	cache.EXPECT().Scan(gomock.Any(), uint64(0), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uint64, match string, _ int64) *redis.ScanCmd {
+			patterns = append(patterns, match)
+			return redis.NewScanCmdResult(nil, 0, nil)
+		}).AnyTimes()
+
+	if err := invalidateProject(context.Background(), cache, 7, 3, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"goods:list:7:*": true, "goods:related:7:*": true}
+	for _, p := range patterns {
+		delete(want, p)
+	}
+	if len(want) != 0 {
+		t.Errorf("patterns %v miss %v", patterns, want)
+	}
+}
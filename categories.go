@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Категории хранятся списком смежности (parent_id) с материализованным
+// путём ltree из id предков: "1.5.12". Путь позволяет выбирать поддерево
+// одним запросом по GiST-индексу (path <@ ...).
+type Category struct {
+	ID        int         `json:"id"`
+	ParentID  *int        `json:"parent_id"`
+	Name      string      `json:"name"`
+	Path      string      `json:"path"`
+	CreatedAt time.Time   `json:"created_at"`
+	Children  []*Category `json:"children,omitempty"`
+}
+
+type GoodCategory struct {
+	CategoryID *int `json:"category_id"`
+}
+
+var (
+	errCategoryNotFound    = errs.NotFound("errors.category.notFound")
+	errCategoryCycle       = errs.Conflict("errors.category.cycle")
+	errCategoryHasChildren = errs.Conflict("errors.category.hasChildren")
+)
+
+func listCategoriesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := db.Query("SELECT id, parent_id, name, path, created_at FROM categories WHERE tenant_id = $1 ORDER BY path",
+			tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		roots := []*Category{}
+		byID := make(map[int]*Category)
+		for rows.Next() {
+			var c Category
+			if err := rows.Scan(&c.ID, &c.ParentID, &c.Name, &c.Path, &c.CreatedAt); err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			byID[c.ID] = &c
+			// Сортировка по path гарантирует, что родитель прочитан раньше детей.
+			if parent, ok := byID[derefInt(c.ParentID)]; ok {
+				parent.Children = append(parent.Children, &c)
+			} else {
+				roots = append(roots, &c)
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, roots)
+	}
+}
+
+func createCategoryHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var c Category
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if c.Name == "" {
+			response.BadRequest(w, r, errors.New("name is required"))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.Begin()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		parentPath := ""
+		if c.ParentID != nil {
+			err := tx.QueryRow("SELECT path FROM categories WHERE id = $1 AND tenant_id = $2", *c.ParentID, tenantID).Scan(&parentPath)
+			if err == sql.ErrNoRows {
+				response.Fail(w, r, errCategoryNotFound)
+				return
+			}
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		if err := tx.QueryRow("SELECT nextval('categories_id_seq')").Scan(&c.ID); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		c.Path = strconv.Itoa(c.ID)
+		if parentPath != "" {
+			c.Path = parentPath + "." + c.Path
+		}
+
+		err = tx.QueryRow(`INSERT INTO categories (id, tenant_id, parent_id, name, path)
+			VALUES ($1, $2, $3, $4, $5::ltree)
+			RETURNING created_at`,
+			c.ID, tenantID, c.ParentID, c.Name, c.Path).Scan(&c.CreatedAt)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusCreated, c)
+	}
+}
+
+// updateCategoryHandler переименовывает категорию и/или переносит её
+// вместе с поддеревом к другому родителю.
+func updateCategoryHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		var c Category
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.Begin()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var current Category
+		err = tx.QueryRow("SELECT id, parent_id, name, path, created_at FROM categories WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+			id, tenantID).Scan(&current.ID, &current.ParentID, &current.Name, &current.Path, &current.CreatedAt)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errCategoryNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if c.Name != "" {
+			current.Name = c.Name
+		}
+
+		if derefInt(c.ParentID) != derefInt(current.ParentID) {
+			newPath := strconv.Itoa(id)
+			if c.ParentID != nil {
+				var parentPath string
+				var inSubtree bool
+				err := tx.QueryRow("SELECT path, path <@ $3::ltree FROM categories WHERE id = $1 AND tenant_id = $2",
+					*c.ParentID, tenantID, current.Path).Scan(&parentPath, &inSubtree)
+				if err == sql.ErrNoRows {
+					response.Fail(w, r, errCategoryNotFound)
+					return
+				}
+				if err != nil {
+					response.InternalError(w, r, err)
+					return
+				}
+				if inSubtree {
+					response.Fail(w, r, errCategoryCycle)
+					return
+				}
+				newPath = parentPath + "." + newPath
+			}
+
+			_, err = tx.Exec(`UPDATE categories SET path = $1::ltree || subpath(path, nlevel($2::ltree))
+				WHERE path <@ $2::ltree AND tenant_id = $3`,
+				newPath, current.Path, tenantID)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			current.ParentID = c.ParentID
+			current.Path = newPath
+		}
+
+		_, err = tx.Exec("UPDATE categories SET name = $1, parent_id = $2 WHERE id = $3", current.Name, current.ParentID, id)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, current)
+	}
+}
+
+func removeCategoryHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		var hasChildren bool
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE parent_id = $1)", id).Scan(&hasChildren)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if hasChildren {
+			response.Fail(w, r, errCategoryHasChildren)
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM categories WHERE id = $1 AND tenant_id = $2", id, tenantID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			response.Fail(w, r, errCategoryNotFound)
+			return
+		}
+
+		response.NoContent(w)
+	}
+}
+
+func assignGoodCategoryHandler(db *sql.DB, redisClient deps.Cache, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goodID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		var req GoodCategory
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		if req.CategoryID != nil {
+			var exists bool
+			err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1 AND tenant_id = $2)", *req.CategoryID, tenantID).Scan(&exists)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			if !exists {
+				response.Fail(w, r, errCategoryNotFound)
+				return
+			}
+		}
+
+		var projectID int
+		err = db.QueryRow("SELECT project_id FROM goods WHERE id = $1 AND tenant_id = $2", goodID, tenantID).Scan(&projectID)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errGoodNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := storage.CheckWritable(r.Context(), db, tenantID, projectID); err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		if _, err := db.Exec("UPDATE goods SET category_id = $1 WHERE id = $2", req.CategoryID, goodID); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		err = effects.Submit(r.Context(), "invalidate_goods_lists", func(ctx context.Context) error {
+			return invalidateGoodsLists(ctx, redisClient, tenantID)
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, map[string]interface{}{"id": goodID, "category_id": req.CategoryID})
+	}
+}
+
+func derefInt(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
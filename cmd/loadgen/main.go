@@ -0,0 +1,227 @@
+// Команда loadgen нагружает запущенный сервис запросами list/create/reprioritize
+// с заданной параллельностью и печатает пропускную способность и перцентили
+// задержек по каждой операции. Зависимости поднимаются через docker-compose:
+//
+//	docker compose up -d
+//	go run .
+//	go run ./cmd/loadgen -duration 30s -concurrency 32 -mix list=8,create=1,reprioritize=1
+//
+// Цену самих обработчиков без стенда измеряет BenchmarkGoodsHandlers:
+//
+//	go test -run - -bench GoodsHandlers -cpu 1,8,32
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type op struct {
+	name   string
+	weight int
+	run    func(ctx context.Context, c *client, rng *rand.Rand) error
+}
+
+type client struct {
+	http    *http.Client
+	addr    string
+	tenant  int
+	project int
+	good    int
+}
+
+func (c *client) do(ctx context.Context, method, path string, body interface{}, want int) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Tenant-ID", strconv.Itoa(c.tenant))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != want {
+		return fmt.Errorf("%s %s: status %d", method, path, resp.StatusCode)
+	}
+	return nil
+}
+
+var ops = map[string]func(c *client) op{
+	"list": func(c *client) op {
+		return op{name: "list", run: func(ctx context.Context, c *client, rng *rand.Rand) error {
+			return c.do(ctx, http.MethodGet, fmt.Sprintf("/goods/list?limit=%d&offset=%d", 10, rng.Intn(10)*10), nil, http.StatusOK)
+		}}
+	},
+	"create": func(c *client) op {
+		return op{name: "create", run: func(ctx context.Context, c *client, rng *rand.Rand) error {
+			good := map[string]interface{}{
+				"project_id":  c.project,
+				"name":        fmt.Sprintf("loadgen %d", rng.Int63()),
+				"description": "created by loadgen",
+			}
+			return c.do(ctx, http.MethodPost, "/good/create?allowDuplicate=true", good, http.StatusCreated)
+		}}
+	},
+	"reprioritize": func(c *client) op {
+		return op{name: "reprioritize", run: func(ctx context.Context, c *client, rng *rand.Rand) error {
+			body := map[string]int{"newPriority": rng.Intn(1000) + 1}
+			return c.do(ctx, http.MethodPatch, fmt.Sprintf("/goods/reprioritize?id=%d&projectId=%d", c.good, c.project), body, http.StatusOK)
+		}}
+	},
+}
+
+type stats struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+func (s *stats) record(name string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.errors[name]++
+		return
+	}
+	s.latencies[name] = append(s.latencies[name], d)
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8080", "service address")
+	duration := flag.Duration("duration", 10*time.Second, "test duration")
+	concurrency := flag.Int("concurrency", 8, "number of concurrent workers")
+	mix := flag.String("mix", "list=8,create=1,reprioritize=1", "operation weights")
+	tenantID := flag.Int("tenant", 1, "tenant id")
+	projectID := flag.Int("project", 1, "project id for create and reprioritize")
+	goodID := flag.Int("good", 1, "good id for reprioritize, in the project")
+	seed := flag.Int64("seed", 1, "random seed, for reproducible runs")
+	flag.Parse()
+
+	c := &client{
+		http:    &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency}},
+		addr:    strings.TrimSuffix(*addr, "/"),
+		tenant:  *tenantID,
+		project: *projectID,
+		good:    *goodID,
+	}
+
+	plan, err := parseMix(*mix, c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := &stats{latencies: make(map[string][]time.Duration), errors: make(map[string]int)}
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				o := plan[rng.Intn(len(plan))]
+				began := time.Now()
+				err := o.run(ctx, c, rng)
+				if ctx.Err() != nil {
+					return
+				}
+				s.record(o.name, time.Since(began), err)
+			}
+		}(rand.New(rand.NewSource(*seed + int64(i))))
+	}
+	wg.Wait()
+
+	report(os.Stdout, s, time.Since(start))
+}
+
+// parseMix раскладывает веса в план, из которого операции выбираются
+// равновероятно: "list=2,create=1" даёт [list list create].
+func parseMix(mix string, c *client) ([]op, error) {
+	var plan []op
+	for _, part := range strings.Split(mix, ",") {
+		name, weight, _ := strings.Cut(strings.TrimSpace(part), "=")
+		newOp, ok := ops[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, weight)
+		}
+		for i := 0; i < w; i++ {
+			plan = append(plan, newOp(c))
+		}
+	}
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("empty operation mix")
+	}
+	return plan, nil
+}
+
+func report(w io.Writer, s *stats, elapsed time.Duration) {
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		if len(s.latencies[name]) > 0 || s.errors[name] > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\tok\terrors\trps\tp50\tp90\tp99\tmax\t")
+	for _, name := range names {
+		l := s.latencies[name]
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", name, len(l), s.errors[name],
+			float64(len(l))/elapsed.Seconds(),
+			percentile(l, 0.50), percentile(l, 0.90), percentile(l, 0.99), percentile(l, 1))
+	}
+	tw.Flush()
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i].Round(time.Microsecond)
+}
@@ -0,0 +1,54 @@
+package main
+
+import "reflect"
+
+// GoodChange — старое и новое значение изменившегося поля товара.
+type GoodChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// GoodUpdated — тело события good_updated: товар после изменения и
+// изменившиеся поля по их JSON-именам, чтобы потребителям не нужно было
+// хранить прошлые снимки.
+type GoodUpdated struct {
+	Goods
+	Diff map[string]GoodChange `json:"diff"`
+}
+
+// goodEvent — тело события о товаре. Описание товара проекта с
+// encrypt_description в событие не попадает: через NATS оно ушло бы в
+// goods_log и другим потребителям открытым.
+func goodEvent(good Goods, encrypted bool) Goods {
+	if encrypted {
+		good.Description = ""
+	}
+	return good
+}
+
+// updatedEvent — тело события good_updated; описание, как и в goodEvent,
+// опускается вместе с его изменением.
+func updatedEvent(old, updated Goods, encrypted bool) GoodUpdated {
+	old, updated = goodEvent(old, encrypted), goodEvent(updated, encrypted)
+	return GoodUpdated{Goods: updated, Diff: diffGoods(old, updated)}
+}
+
+func diffGoods(old, updated Goods) map[string]GoodChange {
+	diff := map[string]GoodChange{}
+	add := func(field string, o, n interface{}) {
+		if !reflect.DeepEqual(o, n) {
+			diff[field] = GoodChange{Old: o, New: n}
+		}
+	}
+
+	add("project_id", old.ProjectID, updated.ProjectID)
+	add("category_id", old.CategoryID, updated.CategoryID)
+	add("name", old.Name, updated.Name)
+	add("description", old.Description, updated.Description)
+	add("priority", old.Priority, updated.Priority)
+	add("removed", old.Removed, updated.Removed)
+	if len(old.Labels) != 0 || len(updated.Labels) != 0 {
+		add("labels", old.Labels, updated.Labels)
+	}
+	return diff
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"net/mail"
+	"time"
+)
+
+var errSubscriptionNotFound = errs.NotFound("errors.subscription.notFound")
+
+type DigestSubscription struct {
+	ID        int       `json:"id"`
+	ProjectID int       `json:"project_id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func listDigestSubscriptionsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "projectId")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		rows, err := db.Query(`SELECT s.id, s.project_id, s.email, s.created_at
+			FROM digest_subscriptions s JOIN projects p ON p.id = s.project_id
+			WHERE s.project_id = $1 AND p.tenant_id = $2
+			ORDER BY s.email`,
+			projectID, tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		subscriptions := []DigestSubscription{}
+		for rows.Next() {
+			var s DigestSubscription
+			if err := rows.Scan(&s.ID, &s.ProjectID, &s.Email, &s.CreatedAt); err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			subscriptions = append(subscriptions, s)
+		}
+
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, subscriptions)
+	}
+}
+
+func createDigestSubscriptionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "projectId")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		var s DigestSubscription
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if _, err := mail.ParseAddress(s.Email); err != nil {
+			response.BadRequest(w, r, errors.New("invalid email"))
+			return
+		}
+		s.ProjectID = projectID
+
+		err = db.QueryRow(`INSERT INTO digest_subscriptions (project_id, email)
+			SELECT id, $2 FROM projects WHERE id = $1 AND tenant_id = $3
+			ON CONFLICT (project_id, email) DO UPDATE SET email = EXCLUDED.email
+			RETURNING id, created_at`,
+			s.ProjectID, s.Email, tenant.FromContext(r.Context())).Scan(&s.ID, &s.CreatedAt)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusCreated, s)
+	}
+}
+
+func removeDigestSubscriptionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "projectId")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		result, err := db.Exec(`DELETE FROM digest_subscriptions s USING projects p
+			WHERE p.id = s.project_id AND s.project_id = $1 AND s.email = $2 AND p.tenant_id = $3`,
+			projectID, r.URL.Query().Get("email"), tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			response.Fail(w, r, errSubscriptionNotFound)
+			return
+		}
+
+		response.NoContent(w)
+	}
+}
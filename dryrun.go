@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// DryRun — ответ разрушающего запроса с dryRun=true: запрос проходит все
+// проверки и выполняется в транзакции, которая затем откатывается, а
+// клиент получает затронутые товары вместо результата.
+type DryRun struct {
+	DryRun bool  `json:"dry_run"`
+	Count  int   `json:"count"`
+	IDs    []int `json:"ids"`
+}
+
+func newDryRun(ids []int) DryRun {
+	if ids == nil {
+		ids = []int{}
+	}
+	return DryRun{DryRun: true, Count: len(ids), IDs: ids}
+}
+
+// dryRunParam разбирает параметр dryRun; без него запрос выполняется.
+func dryRunParam(r *http.Request) (bool, error) {
+	if !r.URL.Query().Has("dryRun") {
+		return false, nil
+	}
+	v := r.URL.Query().Get("dryRun")
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid dryRun %q", v)
+	}
+	return dryRun, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+type DuplicateCandidate struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	Similarity float64 `json:"similarity"`
+}
+
+// findDuplicates ищет в проекте товары с похожим названием (триграммное
+// сходство pg_trgm не ниже duplicateThreshold). Порог задаётся локально
+// для транзакции, чтобы оператор % использовал GIN-индекс по name.
+func findDuplicates(ctx context.Context, tx *sql.Tx, tenantID, projectID int, name string) ([]DuplicateCandidate, error) {
+	_, err := tx.ExecContext(ctx, "SELECT set_config('pg_trgm.similarity_threshold', $1, true)",
+		strconv.FormatFloat(duplicateThreshold, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, name, similarity(name, $1) AS score FROM goods
+		WHERE tenant_id = $2 AND project_id = $3 AND NOT removed AND name % $1
+		ORDER BY score DESC, id
+		LIMIT 5`,
+		name, tenantID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []DuplicateCandidate
+	for rows.Next() {
+		var c DuplicateCandidate
+		if err := rows.Scan(&c.ID, &c.Name, &c.Similarity); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
@@ -0,0 +1,452 @@
+//go:build integration
+
+// Package e2e прогоняет сквозной сценарий против сервиса и его
+// зависимостей: TestMain поднимает контейнеры из docker-compose.yml этого
+// каталога, применяет миграции, собирает и запускает сервис, а тест
+// создаёт отдельного арендатора, проходит по эндпоинтам и проверяет ответы,
+// инвалидацию кэша в Redis и события в NATS.
+//
+//	go test -tags integration ./e2e
+//
+// Нужны docker compose и свободные порты из docker-compose.yml.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+)
+
+const eventTimeout = 3 * time.Second
+
+type env struct {
+	addr   string
+	http   *http.Client
+	db     *sql.DB
+	redis  *redis.Client
+	tenant int
+	user   string
+
+	// Служебные маршруты /admin/* и /metrics — на отдельном порту.
+	adminAddr  string
+	adminToken string
+
+	mu     sync.Mutex
+	events []*nats.Msg
+}
+
+type result struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (e *env) call(method, path string, body interface{}) (result, error) {
+	var reader io.Reader
+	contentType := "application/json"
+	switch v := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(v)
+		contentType = "text/csv"
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return result{}, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	addr := e.addr
+	if path == "/metrics" || strings.HasPrefix(path, "/admin/") {
+		addr = e.adminAddr
+	}
+	req, err := http.NewRequest(method, addr+path, reader)
+	if err != nil {
+		return result{}, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.adminToken != "" {
+		req.Header.Set("X-Admin-Token", e.adminToken)
+	}
+	if e.tenant != 0 {
+		req.Header.Set("X-Tenant-ID", strconv.Itoa(e.tenant))
+	}
+	req.Header.Set("X-User-ID", e.user)
+
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return result{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	return result{status: resp.StatusCode, header: resp.Header, body: data}, err
+}
+
+// expect выполняет запрос и проверяет статус; тело ответа декодируется в out.
+func (e *env) expect(method, path string, body interface{}, status int, out interface{}) error {
+	res, err := e.call(method, path, body)
+	if err != nil {
+		return err
+	}
+	if res.status != status {
+		return fmt.Errorf("%s %s: status %d, want %d: %s", method, path, res.status, status, res.body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(res.body, out); err != nil {
+			return fmt.Errorf("%s %s: decode: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// waitEvent ждёт событие subject текущего арендатора.
+func (e *env) waitEvent(subject string) error {
+	deadline := time.Now().Add(eventTimeout)
+	for time.Now().Before(deadline) {
+		e.mu.Lock()
+		for i, msg := range e.events {
+			if msg.Subject == subject && msg.Header.Get("Tenant-Id") == strconv.Itoa(e.tenant) {
+				e.events = append(e.events[:i], e.events[i+1:]...)
+				e.mu.Unlock()
+				return nil
+			}
+		}
+		e.mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("no %s event within %s", subject, eventTimeout)
+}
+
+// listCacheKeys считает ключи кэша списков арендатора. SCAN отдаёт их
+// порциями, пока курсор не вернётся к 0.
+func (e *env) listCacheKeys() (int, error) {
+	match := fmt.Sprintf("goods:list:%d:*", e.tenant)
+	var cursor uint64
+	n := 0
+	for {
+		keys, next, err := e.redis.Scan(context.Background(), cursor, match, 1000).Result()
+		if err != nil {
+			return 0, err
+		}
+		n += len(keys)
+		if next == 0 {
+			return n, nil
+		}
+		cursor = next
+	}
+}
+
+// waitListCache ждёт, пока кэш списков арендатора станет непустым (want) или
+// пустым (!want): инвалидация выполняется асинхронно.
+func (e *env) waitListCache(want bool) error {
+	deadline := time.Now().Add(eventTimeout)
+	for time.Now().Before(deadline) {
+		n, err := e.listCacheKeys()
+		if err != nil {
+			return err
+		}
+		if (n > 0) == want {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if want {
+		return fmt.Errorf("goods list is not cached")
+	}
+	return fmt.Errorf("goods list cache is not invalidated")
+}
+
+type good struct {
+	ID        int  `json:"id"`
+	ProjectID int  `json:"project_id"`
+	Priority  int  `json:"priority"`
+	Favorite  bool `json:"favorite"`
+	Project   *struct {
+		ID int `json:"id"`
+	} `json:"project"`
+}
+
+type goodsList struct {
+	Meta struct {
+		Total int `json:"total"`
+	} `json:"meta"`
+	Goods []good `json:"goods"`
+}
+
+func TestScenario(t *testing.T) {
+	db := stack.db
+
+	natsConn, err := nats.Connect(natsURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer natsConn.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: redisAddr})
+	defer redisClient.Close()
+
+	e := &env{
+		addr:       "http://" + serviceAddr,
+		adminAddr:  "http://" + serviceAdminAddr,
+		adminToken: adminToken,
+		http:       &http.Client{Timeout: 10 * time.Second},
+		db:         db,
+		redis:      redisClient,
+		user:       "e2e",
+	}
+	sub, err := natsConn.Subscribe(">", func(msg *nats.Msg) {
+		e.mu.Lock()
+		e.events = append(e.events, msg)
+		e.mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		projectID, otherProjectID int
+		created                   good
+		categoryID                int
+	)
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"create tenant", func() error {
+			var t struct {
+				ID int `json:"id"`
+			}
+			err := e.expect("POST", "/admin/tenants", map[string]string{"name": fmt.Sprintf("e2e-%d", time.Now().UnixNano())}, http.StatusCreated, &t)
+			e.tenant = t.ID
+			return err
+		}},
+		{"create projects", func() error {
+			err := db.QueryRow("INSERT INTO projects (name, tenant_id) VALUES ('e2e', $1) RETURNING id", e.tenant).Scan(&projectID)
+			if err != nil {
+				return err
+			}
+			return db.QueryRow("INSERT INTO projects (name, tenant_id) VALUES ('e2e other', $1) RETURNING id", e.tenant).Scan(&otherProjectID)
+		}},
+		{"list projects", func() error {
+			var projects []struct {
+				ID int `json:"id"`
+			}
+			if err := e.expect("GET", "/projects", nil, http.StatusOK, &projects); err != nil {
+				return err
+			}
+			// Третий — проект по умолчанию, созданный вместе с арендатором.
+			if len(projects) != 3 {
+				return fmt.Errorf("got %d projects, want 3", len(projects))
+			}
+			return nil
+		}},
+		{"create good", func() error {
+			body := map[string]interface{}{"project_id": projectID, "name": "e2e good", "description": "end-to-end", "labels": map[string]string{"env": "e2e"}}
+			if err := e.expect("POST", "/good/create", body, http.StatusCreated, &created); err != nil {
+				return err
+			}
+			return e.waitEvent("new_good_created")
+		}},
+		{"reject duplicate good", func() error {
+			body := map[string]interface{}{"project_id": projectID, "name": "e2e good"}
+			return e.expect("POST", "/good/create", body, http.StatusConflict, nil)
+		}},
+		{"list goods fills cache", func() error {
+			var list goodsList
+			if err := e.expect("GET", "/goods/list", nil, http.StatusOK, &list); err != nil {
+				return err
+			}
+			if list.Meta.Total != 1 || len(list.Goods) != 1 {
+				return fmt.Errorf("got %d goods of %d, want 1", len(list.Goods), list.Meta.Total)
+			}
+			if err := e.waitEvent("list_goods"); err != nil {
+				return err
+			}
+			return e.waitListCache(true)
+		}},
+		{"list goods by labels", func() error {
+			var list goodsList
+			if err := e.expect("GET", "/goods/list?labels=env%3De2e", nil, http.StatusOK, &list); err != nil {
+				return err
+			}
+			if len(list.Goods) != 1 {
+				return fmt.Errorf("got %d goods, want 1", len(list.Goods))
+			}
+			return nil
+		}},
+		{"list goods with projects", func() error {
+			var list goodsList
+			if err := e.expect("GET", "/goods/list?include=project", nil, http.StatusOK, &list); err != nil {
+				return err
+			}
+			if len(list.Goods) != 1 || list.Goods[0].Project == nil || list.Goods[0].Project.ID != projectID {
+				return fmt.Errorf("got goods %+v, want one with project %d", list.Goods, projectID)
+			}
+			return nil
+		}},
+		{"search goods", func() error {
+			var found goodsList
+			if err := e.expect("GET", "/goods/search?engine=pg&q=e2e", nil, http.StatusOK, &found); err != nil {
+				return err
+			}
+			if found.Meta.Total != 1 {
+				return fmt.Errorf("found %d goods, want 1", found.Meta.Total)
+			}
+			return nil
+		}},
+		{"create category", func() error {
+			var c struct {
+				ID int `json:"id"`
+			}
+			err := e.expect("POST", "/category/create", map[string]string{"name": "e2e"}, http.StatusCreated, &c)
+			categoryID = c.ID
+			return err
+		}},
+		{"assign category invalidates lists", func() error {
+			path := fmt.Sprintf("/good/category?id=%d", created.ID)
+			if err := e.expect("PATCH", path, map[string]int{"category_id": categoryID}, http.StatusOK, nil); err != nil {
+				return err
+			}
+			return e.waitListCache(false)
+		}},
+		{"list categories", func() error {
+			return e.expect("GET", "/categories", nil, http.StatusOK, nil)
+		}},
+		{"favorites", func() error {
+			path := fmt.Sprintf("/good/favorite?id=%d", created.ID)
+			if err := e.expect("POST", path, nil, http.StatusNoContent, nil); err != nil {
+				return err
+			}
+			var list goodsList
+			if err := e.expect("GET", "/goods/list?withFavorites=true", nil, http.StatusOK, &list); err != nil {
+				return err
+			}
+			if len(list.Goods) != 1 || !list.Goods[0].Favorite {
+				return fmt.Errorf("good is not marked as favorite")
+			}
+			if err := e.expect("GET", "/goods/favorites", nil, http.StatusOK, nil); err != nil {
+				return err
+			}
+			return e.expect("DELETE", path, nil, http.StatusNoContent, nil)
+		}},
+		{"related goods", func() error {
+			return e.expect("GET", fmt.Sprintf("/good/related?id=%d", created.ID), nil, http.StatusOK, nil)
+		}},
+		{"copy goods to another project", func() error {
+			body := map[string]interface{}{"ids": []int{created.ID}, "projectId": otherProjectID, "mode": "copy"}
+			if err := e.expect("POST", "/goods/transfer", body, http.StatusOK, nil); err != nil {
+				return err
+			}
+			return e.waitEvent("new_good_created")
+		}},
+		{"import goods", func() error {
+			var res struct {
+				Inserted int `json:"inserted"`
+				Rejected int `json:"rejected"`
+			}
+			csv := "name,description,labels\nimported 1,first,env=e2e\nimported 2,second,\n,missing name,\n"
+			if err := e.expect("POST", fmt.Sprintf("/goods/import?projectId=%d", projectID), csv, http.StatusOK, &res); err != nil {
+				return err
+			}
+			if res.Inserted != 2 || res.Rejected != 1 {
+				return fmt.Errorf("inserted %d, rejected %d; want 2 and 1", res.Inserted, res.Rejected)
+			}
+			return e.waitEvent("goods_imported")
+		}},
+		{"digest subscriptions", func() error {
+			path := fmt.Sprintf("/digest/subscription?projectId=%d", projectID)
+			if err := e.expect("POST", path, map[string]string{"email": "e2e@example.com"}, http.StatusCreated, nil); err != nil {
+				return err
+			}
+			if err := e.expect("GET", fmt.Sprintf("/digest/subscriptions?projectId=%d", projectID), nil, http.StatusOK, nil); err != nil {
+				return err
+			}
+			return e.expect("DELETE", path+"&email=e2e@example.com", nil, http.StatusNoContent, nil)
+		}},
+		{"goods activity", func() error {
+			return e.expect("GET", fmt.Sprintf("/analytics/goods/activity?projectId=%d&interval=1d", projectID), nil, http.StatusOK, nil)
+		}},
+		{"archive project blocks writes", func() error {
+			path := fmt.Sprintf("/project/archive?id=%d", otherProjectID)
+			if err := e.expect("PATCH", path, nil, http.StatusOK, nil); err != nil {
+				return err
+			}
+			if err := e.waitEvent("project_archived"); err != nil {
+				return err
+			}
+			body := map[string]interface{}{"project_id": otherProjectID, "name": "archived"}
+			return e.expect("POST", "/good/create", body, http.StatusConflict, nil)
+		}},
+		{"reprioritize goods", func() error {
+			path := fmt.Sprintf("/goods/reprioritize?id=%d&projectId=%d", created.ID, projectID)
+			if err := e.expect("PATCH", path, map[string]int{"newPriority": 10}, http.StatusOK, nil); err != nil {
+				return err
+			}
+			return e.waitEvent("goods_reordered")
+		}},
+		{"update good", func() error {
+			body := map[string]interface{}{"name": "e2e good updated", "priority": 1}
+			path := fmt.Sprintf("/good/update?id=%d&projectId=%d", created.ID, projectID)
+			if err := e.expect("PATCH", path, body, http.StatusOK, nil); err != nil {
+				return err
+			}
+			return e.waitEvent("good_updated")
+		}},
+		{"delete good", func() error {
+			if err := e.expect("DELETE", fmt.Sprintf("/good/delete?id=%d&projectId=%d", created.ID, projectID), nil, http.StatusNoContent, nil); err != nil {
+				return err
+			}
+			return e.waitEvent("good_deleted")
+		}},
+		{"admin endpoints", func() error {
+			for _, path := range []string{"/admin/tenants", "/admin/jobs", "/admin/workers", "/metrics"} {
+				if err := e.expect("GET", path, nil, http.StatusOK, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"unknown route", func() error {
+			var body struct {
+				Code int `json:"code"`
+			}
+			if err := e.expect("GET", "/goods/unknown", nil, http.StatusNotFound, &body); err != nil {
+				return err
+			}
+			res, err := e.call("PUT", "/goods/list", nil)
+			if err != nil {
+				return err
+			}
+			if res.status != http.StatusMethodNotAllowed || res.header.Get("Allow") == "" {
+				return fmt.Errorf("PUT /goods/list: status %d, Allow %q", res.status, res.header.Get("Allow"))
+			}
+			return nil
+		}},
+	}
+
+	// Шаги зависят от предыдущих, но упавший шаг не останавливает
+	// остальные: так за один прогон видно больше поломок.
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if err := step.run(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
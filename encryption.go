@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/crypt"
+	"hezzl-test/internal/errs"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// fieldKeys шифрует description товаров проектов с encrypt_description.
+// Ключи задаются переменной ENCRYPTION_KEYS; без неё шифрование включить
+// нельзя, а открытые описания читаются как обычно.
+var fieldKeys *crypt.Keyring
+
+// decrypted — приёмник Scan для description: зашифрованное значение
+// расшифровывается, открытое копируется как есть.
+type decrypted struct {
+	dst *string
+}
+
+func (d decrypted) Scan(src interface{}) error {
+	var value sql.NullString
+	if err := value.Scan(src); err != nil {
+		return err
+	}
+	plaintext, err := fieldKeys.Decrypt(value.String)
+	if err != nil {
+		return fmt.Errorf("decrypt description: %w", err)
+	}
+	*d.dst = plaintext
+	return nil
+}
+
+// errReservedDescription — описание начинается с префикса шифротекста.
+// Зашифрованность определяется по префиксу, так что такое описание при
+// чтении приняли бы за шифротекст, и запрос отклоняется.
+var errReservedDescription = errs.Validation(fmt.Errorf("description must not start with %q", crypt.Prefix))
+
+// checkDescription проверяет открытое описание перед записью.
+func checkDescription(description string) error {
+	if crypt.IsEncrypted(description) {
+		return errReservedDescription
+	}
+	return nil
+}
+
+// sealDescription шифрует описание, если этого требуют настройки проекта.
+func sealDescription(settings ProjectSettings, description string) (string, error) {
+	if err := checkDescription(description); err != nil {
+		return "", err
+	}
+	if !settings.EncryptDescription {
+		return description, nil
+	}
+	return fieldKeys.Encrypt(description)
+}
+
+// encryptedProjects возвращает те из проектов ids, описания товаров которых
+// шифруются.
+func encryptedProjects(ctx context.Context, q querier, ids []int) (map[int]bool, error) {
+	var encrypted []int64
+	err := q.QueryRowContext(ctx, `SELECT COALESCE(array_agg(project_id), '{}') FROM project_settings
+		WHERE project_id = ANY($1) AND encrypt_description`, pq.Array(ids)).Scan(pq.Array(&encrypted))
+	if err != nil {
+		return nil, err
+	}
+	projects := make(map[int]bool, len(encrypted))
+	for _, id := range encrypted {
+		projects[int(id)] = true
+	}
+	return projects, nil
+}
+
+// sealingSource шифрует описания строк импорта по настройкам проекта.
+type sealingSource struct {
+	bulk.Source
+	settings ProjectSettings
+}
+
+func (s sealingSource) Next() (bulk.Row, error) {
+	row, err := s.Source.Next()
+	if err != nil {
+		return row, err
+	}
+	if crypt.IsEncrypted(row.Description) {
+		return row, &bulk.RowError{Line: row.Line, Reason: errReservedDescription.Error()}
+	}
+	row.Description, err = sealDescription(s.settings, row.Description)
+	return row, err
+}
+
+// reencryptDescriptions приводит хранимые описания к настройкам проектов:
+// шифрует текущим ключом открытые и зашифрованные прежними ключами описания
+// проектов с encrypt_description и расшифровывает описания остальных
+// проектов. После ротации ключей старый ключ можно убрать из
+// ENCRYPTION_KEYS, когда задача отработает.
+func reencryptDescriptions(ctx context.Context, db *sql.DB) error {
+	if !fieldKeys.Enabled() {
+		log.Printf("reencrypt: ENCRYPTION_KEYS is not set, skipping")
+		return nil
+	}
+
+	total := 0
+	for {
+		rows, err := db.QueryContext(ctx, `SELECT g.id, g.description, COALESCE(s.encrypt_description, false)
+			FROM goods g LEFT JOIN project_settings s ON s.project_id = g.project_id
+			WHERE CASE WHEN COALESCE(s.encrypt_description, false)
+				THEN NOT starts_with(g.description, $1)
+				ELSE starts_with(g.description, 'enc:') END
+			ORDER BY g.id LIMIT $2`,
+			fieldKeys.CurrentPrefix(), reencryptBatchSize)
+		if err != nil {
+			return err
+		}
+
+		type pending struct {
+			id          int
+			description string
+		}
+		var batch []pending
+		for rows.Next() {
+			var p pending
+			var stored string
+			var encrypt bool
+			if err := rows.Scan(&p.id, &stored, &encrypt); err != nil {
+				rows.Close()
+				return err
+			}
+			plaintext, err := fieldKeys.Decrypt(stored)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("good %d: %w", p.id, err)
+			}
+			p.description = plaintext
+			if encrypt {
+				if p.description, err = fieldKeys.Encrypt(plaintext); err != nil {
+					rows.Close()
+					return err
+				}
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, p := range batch {
+			if _, err := db.ExecContext(ctx, "UPDATE goods SET description = $1 WHERE id = $2", p.description, p.id); err != nil {
+				return err
+			}
+		}
+		total += len(batch)
+	}
+
+	log.Printf("reencrypt: %d descriptions updated", total)
+	return nil
+}
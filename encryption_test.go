@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/crypt"
+	"hezzl-test/internal/errs"
+)
+
+// useKeys подменяет fieldKeys на время теста.
+func useKeys(t *testing.T, k *crypt.Keyring) {
+	old := fieldKeys
+	fieldKeys = k
+	t.Cleanup(func() { fieldKeys = old })
+}
+
+func TestSealDescription(t *testing.T) {
+	keys, err := crypt.Parse("k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useKeys(t, keys)
+
+	tests := []struct {
+		name        string
+		encrypt     bool
+		description string
+		rejected    bool
+	}{
+		{"plain", false, "green tea", false},
+		{"encrypted", true, "green tea", false},
+		{"prefix in plain project", false, "enc:see attached", true},
+		{"prefix in encrypted project", true, "enc:see attached", true},
+		{"prefix inside text", false, "see enc:attached", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := sealDescription(ProjectSettings{EncryptDescription: tt.encrypt}, tt.description)
+			if tt.rejected {
+				if !errors.Is(err, errs.ErrValidation) {
+					t.Fatalf("err = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if crypt.IsEncrypted(sealed) != tt.encrypt {
+				t.Fatalf("sealed = %q, encrypted %v", sealed, tt.encrypt)
+			}
+			var read string
+			if err := (decrypted{&read}).Scan(sealed); err != nil || read != tt.description {
+				t.Fatalf("read back %q, %v", read, err)
+			}
+		})
+	}
+}
+
+type rowsSource []bulk.Row
+
+func (s *rowsSource) Next() (bulk.Row, error) {
+	if len(*s) == 0 {
+		return bulk.Row{}, io.EOF
+	}
+	row := (*s)[0]
+	*s = (*s)[1:]
+	return row, nil
+}
+
+func TestSealingSourceRejectsPrefix(t *testing.T) {
+	src := sealingSource{Source: &rowsSource{{Line: 2, Name: "Tea", Description: "enc:see attached"}}}
+	_, err := src.Next()
+	var rowErr *bulk.RowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 2 {
+		t.Fatalf("err = %v, want a row error for line 2", err)
+	}
+}
+
+func TestGoodHandlersRejectPrefix(t *testing.T) {
+	body := `{"project_id":3,"name":"Tea","description":"enc:see attached"}`
+	for name, h := range map[string]http.Handler{
+		"create": createGoodHandler(nil, nil, 0, nil, nil),
+		"update": updateGoodHandler(nil, nil, 0, nil, nil),
+	} {
+		w := serveBody(t, h, "POST", "/good?id=5&projectId=3", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400, body %s", name, w.Code, w.Body)
+		}
+	}
+}
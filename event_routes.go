@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/eventroute"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"log"
+	"net/http"
+)
+
+var errEventRouteNotFound = errs.NotFound("errors.eventRoute.notFound")
+
+// listEventRoutesHandler отдаёт действующие на этом экземпляре правила
+// маршрутизации событий арендатора в порядке применения.
+func listEventRoutesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, http.StatusOK, eventRoutes.Rules(tenant.FromContext(r.Context())))
+	}
+}
+
+// createEventRouteHandler добавляет правило маршрутизации событий и сразу
+// перечитывает правила; другие экземпляры подхватят его за
+// eventRoutesReload.
+func createEventRouteHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rule eventroute.Rule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if err := rule.Validate(); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		if rule.ProjectID != nil {
+			var exists bool
+			err := tx.QueryRowContext(r.Context(), "SELECT EXISTS(SELECT 1 FROM projects WHERE id = $1 AND tenant_id = $2)",
+				*rule.ProjectID, tenantID).Scan(&exists)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			if !exists {
+				response.Fail(w, r, errProjectNotFound)
+				return
+			}
+		}
+
+		err = tx.QueryRowContext(r.Context(), `INSERT INTO event_routes (tenant_id, subject, project_id, action, target)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+			tenantID, rule.Subject, rule.ProjectID, rule.Action, rule.Target).Scan(&rule.ID, &rule.CreatedAt)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := audit(r.Context(), tx, "create", "event_route", rule.ID, rule); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := eventRoutes.Load(r.Context(), db); err != nil {
+			log.Printf("eventroute: reload rules: %v", err)
+		}
+
+		response.JSON(w, r, http.StatusCreated, rule)
+	}
+}
+
+func removeEventRouteHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(r.Context(), "DELETE FROM event_routes WHERE id = $1 AND tenant_id = $2",
+			id, tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			response.Fail(w, r, errEventRouteNotFound)
+			return
+		}
+		if err := audit(r.Context(), tx, "remove", "event_route", id, struct{}{}); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := eventRoutes.Load(r.Context(), db); err != nil {
+			log.Printf("eventroute: reload rules: %v", err)
+		}
+
+		response.NoContent(w)
+	}
+}
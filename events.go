@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"hezzl-test/internal/breaker"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/eventroute"
+	"hezzl-test/internal/spool"
+	"hezzl-test/internal/tenant"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// eventRoutes — правила маршрутизации исходящих событий; загружаются при
+// запуске и перечитываются раз в eventRoutesReload.
+var eventRoutes = eventroute.New()
+
+// Во время остановки (stopping) событие, которое не удалось опубликовать,
+// откладывается в eventSpool и уходит при следующем запуске; nil —
+// откладывать некуда.
+var (
+	eventSpool *spool.Spool
+	stopping   atomic.Bool
+)
+
+var natsBreaker = breaker.New(breaker.Settings{
+	Name:        "nats",
+	MaxFailures: breakerMaxFailures,
+	OpenTimeout: breakerOpenTimeout,
+})
+
+// Следующая версия схемы событий: прежнее тело события оборачивается в
+// конверт с метаданными. Пока потребители не переключились, она
+// публикуется только в теневые темы.
+const (
+	eventSchemaVersion  = 2
+	schemaVersionHeader = "Schema-Version"
+	shadowSubjectSuffix = ".shadow"
+)
+
+// События одного товара несут его id и номер версии: потребитель видит по
+// ним пропуски, отбрасывает повторы и применяет изменения по порядку.
+// Nats-Msg-Id из тех же значений позволяет JetStream отсеять повторную
+// публикацию.
+const (
+	entityHeader   = "Entity-Id"
+	sequenceHeader = "Sequence"
+)
+
+// occurredAtField — время события, которое publishMsg добавляет в тело:
+// потребители берут его вместо времени получения, в том числе для событий,
+// отложенных в eventSpool до перезапуска.
+const occurredAtField = "occurred_at"
+
+type EventEnvelope struct {
+	Version    int             `json:"version"`
+	Subject    string          `json:"subject"`
+	TenantID   int             `json:"tenantId"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	EntityID   string          `json:"entityId,omitempty"`
+	Sequence   int64           `json:"sequence,omitempty"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func publish(ctx context.Context, natsConn deps.Publisher, subject string, data []byte) error {
+	return publishMsg(ctx, natsConn, subject, "", 0, data)
+}
+
+// publishGood публикует событие товара id в версии version.
+func publishGood(ctx context.Context, natsConn deps.Publisher, subject string, id int, version int64, data []byte) error {
+	return publishMsg(ctx, natsConn, subject, "good:"+strconv.Itoa(id), version, data)
+}
+
+// publishMsg публикует событие в темы, которые выбирают для него правила
+// eventRoutes; отброшенное правилами событие считается опубликованным.
+func publishMsg(ctx context.Context, natsConn deps.Publisher, subject, entity string, sequence int64, data []byte) error {
+	tenantID := tenant.FromContext(ctx)
+	subjects := eventRoutes.Subjects(tenantID, subject, data)
+	if len(subjects) == 0 {
+		return nil
+	}
+	occurredAt := clk.Now().UTC()
+	data = withOccurredAt(data, occurredAt)
+
+	for _, target := range subjects {
+		msg := nats.NewMsg(target)
+		msg.Data = data
+		msg.Header.Set(tenant.NATSHeader, strconv.Itoa(tenantID))
+		if user := tenant.UserFromContext(ctx); user != "" {
+			msg.Header.Set(tenant.NATSUserHeader, user)
+		}
+		if entity != "" {
+			seq := strconv.FormatInt(sequence, 10)
+			msg.Header.Set(entityHeader, entity)
+			msg.Header.Set(sequenceHeader, seq)
+			msg.Header.Set(nats.MsgIdHdr, entity+":"+seq)
+		}
+		err := retryPolicy.Do(ctx, func(ctx context.Context) error {
+			return natsBreaker.Execute(func() error {
+				return natsConn.PublishMsg(msg)
+			})
+		})
+		if err != nil && stopping.Load() && eventSpool != nil {
+			// ctx задачи к этому времени может истечь, а отложить событие
+			// всё равно нужно.
+			if serr := eventSpool.Save(context.WithoutCancel(ctx), msg); serr != nil {
+				log.Printf("events: spool %s: %v", target, serr)
+				return err
+			}
+			log.Printf("events: %s spooled until restart: %v", target, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if rand.Intn(100) < eventShadowPercent {
+		publishShadow(ctx, natsConn, subject, entity, sequence, occurredAt, data)
+	}
+	return nil
+}
+
+// spoolUnconfirmed откладывает в eventSpool события, получение которых NATS
+// не подтвердил до остановки.
+func spoolUnconfirmed(ctx context.Context, msgs []*nats.Msg) {
+	spooled := 0
+	for _, msg := range msgs {
+		if err := eventSpool.Save(ctx, msg); err != nil {
+			log.Printf("events: spool %s: %v", msg.Subject, err)
+			continue
+		}
+		spooled++
+	}
+	if len(msgs) > 0 {
+		log.Printf("events: %d of %d unconfirmed events spooled until restart", spooled, len(msgs))
+	}
+}
+
+// withOccurredAt добавляет в JSON-объект data поле occurredAtField. Поле
+// ставится первым, так что одноимённое поле самого события перекрывает его;
+// тело, которое не является объектом, возвращается как есть.
+func withOccurredAt(data []byte, at time.Time) []byte {
+	if len(data) < 2 || data[0] != '{' {
+		return data
+	}
+	stamp, err := json.Marshal(at)
+	if err != nil {
+		return data
+	}
+	out := make([]byte, 0, len(data)+len(occurredAtField)+len(stamp)+4)
+	out = append(out, '{', '"')
+	out = append(out, occurredAtField...)
+	out = append(out, '"', ':')
+	out = append(out, stamp...)
+	if rest := bytes.TrimSpace(data[1:]); len(rest) > 0 && rest[0] != '}' {
+		out = append(out, ',')
+	}
+	return append(out, data[1:]...)
+}
+
+// bumpGoodVersion увеличивает версию товара id в транзакции изменения и
+// возвращает новую.
+func bumpGoodVersion(ctx context.Context, q querier, id int) (int64, error) {
+	var version int64
+	err := q.QueryRowContext(ctx, "UPDATE goods SET version = version + 1 WHERE id = $1 RETURNING version", id).Scan(&version)
+	return version, err
+}
+
+// publishShadow дублирует событие в теневую тему в новой схеме. Ошибки
+// только логируются: теневая копия не должна влиять на основную публикацию.
+func publishShadow(ctx context.Context, natsConn deps.Publisher, subject, entity string, sequence int64, occurredAt time.Time, data []byte) {
+	tenantID := tenant.FromContext(ctx)
+	envelope, err := json.Marshal(EventEnvelope{
+		Version:    eventSchemaVersion,
+		Subject:    subject,
+		TenantID:   tenantID,
+		OccurredAt: occurredAt,
+		EntityID:   entity,
+		Sequence:   sequence,
+		Data:       data,
+	})
+	if err != nil {
+		log.Printf("events: shadow %s: %v", subject, err)
+		return
+	}
+
+	msg := nats.NewMsg(subject + shadowSubjectSuffix)
+	msg.Data = envelope
+	msg.Header.Set(tenant.NATSHeader, strconv.Itoa(tenantID))
+	msg.Header.Set(schemaVersionHeader, strconv.Itoa(eventSchemaVersion))
+	err = natsBreaker.Execute(func() error {
+		return natsConn.PublishMsg(msg)
+	})
+	if err != nil {
+		log.Printf("events: shadow %s: %v", subject, err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/mock/gomock"
+
+	"hezzl-test/internal/breaker"
+	"hezzl-test/internal/deps/mocks"
+	"hezzl-test/internal/localcache"
+	"hezzl-test/internal/spool"
+	"hezzl-test/internal/tenant"
+)
+
+func TestWithOccurredAt(t *testing.T) {
+	at := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"object", `{"id":1}`, `{"occurred_at":"2024-03-01T10:00:00Z","id":1}`},
+		{"empty object", `{}`, `{"occurred_at":"2024-03-01T10:00:00Z"}`},
+		{"array", `[1,2]`, `[1,2]`},
+		{"event wins", `{"occurred_at":"2020-01-01T00:00:00Z"}`, `{"occurred_at":"2024-03-01T10:00:00Z","occurred_at":"2020-01-01T00:00:00Z"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withOccurredAt([]byte(tt.data), at)
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("invalid JSON %s", got)
+			}
+		})
+	}
+}
+
+func TestUpdatedEventOmitsEncryptedDescription(t *testing.T) {
+	old := Goods{ID: 1, ProjectID: 2, Name: "Tea", Description: "secret"}
+	updated := old
+	updated.Description = "new secret"
+	updated.Name = "Green tea"
+
+	event := updatedEvent(old, updated, true)
+	if event.Description != "" {
+		t.Errorf("Description = %q, want empty", event.Description)
+	}
+	if _, ok := event.Diff["description"]; ok {
+		t.Error("diff contains description")
+	}
+	if _, ok := event.Diff["name"]; !ok {
+		t.Error("diff lost name")
+	}
+
+	event = updatedEvent(old, updated, false)
+	if event.Description != "new secret" || event.Diff["description"].New != "new secret" {
+		t.Errorf("plain project event = %+v", event)
+	}
+}
+
+// freshBreaker даёт тесту свой natsBreaker, чтобы ошибки публикации в
+// других тестах его не разомкнули.
+func freshBreaker(t *testing.T) {
+	old := natsBreaker
+	natsBreaker = breaker.New(breaker.Settings{Name: "nats", MaxFailures: breakerMaxFailures, OpenTimeout: breakerOpenTimeout})
+	t.Cleanup(func() { natsBreaker = old })
+}
+
+func TestPublishGoodRetries(t *testing.T) {
+	freshBreaker(t)
+	ctrl := gomock.NewController(t)
+	publisher := mocks.NewMockPublisher(ctrl)
+	var sent *nats.Msg
+	gomock.InOrder(
+		publisher.EXPECT().PublishMsg(gomock.Any()).Return(nats.ErrTimeout),
+		publisher.EXPECT().PublishMsg(gomock.Any()).DoAndReturn(func(msg *nats.Msg) error {
+			sent = msg
+			return nil
+		}),
+	)
+
+	ctx := tenant.WithUser(tenant.WithTenant(context.Background(), testTenantID), "u1")
+	if err := publishGood(ctx, publisher, "good_updated", 11, 4, []byte(`{"id":11}`)); err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{
+		tenant.NATSHeader:     "7",
+		tenant.NATSUserHeader: "u1",
+		entityHeader:          "good:11",
+		sequenceHeader:        "4",
+		nats.MsgIdHdr:         "good:11:4",
+	}
+	for name, want := range headers {
+		if got := sent.Header.Get(name); got != want {
+			t.Errorf("header %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPublishGoodFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{"transient", nats.ErrTimeout, retryMaxAttempts},
+		{"permanent", errors.New("nats: invalid subject"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freshBreaker(t)
+			publisher := mocks.NewMockPublisher(gomock.NewController(t))
+			publisher.EXPECT().PublishMsg(gomock.Any()).Return(tt.err).Times(tt.attempts)
+
+			ctx := tenant.WithTenant(context.Background(), testTenantID)
+			if err := publishGood(ctx, publisher, "good_updated", 11, 4, []byte(`{"id":11}`)); !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSpoolUnconfirmed(t *testing.T) {
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	prev := eventSpool
+	eventSpool = spool.New(cache, eventSpoolPrefix)
+	t.Cleanup(func() { eventSpool = prev })
+
+	ctx := context.Background()
+	first, second := nats.NewMsg("new_good_created"), nats.NewMsg("good_updated")
+	first.Data, second.Data = []byte(`{"id":1}`), []byte(`{"id":1,"name":"Tea"}`)
+	spoolUnconfirmed(ctx, []*nats.Msg{first, second})
+
+	publisher := mocks.NewMockPublisher(gomock.NewController(t))
+	gomock.InOrder(
+		publisher.EXPECT().PublishMsg(gomock.Any()).DoAndReturn(func(msg *nats.Msg) error {
+			if msg.Subject != "new_good_created" || string(msg.Data) != `{"id":1}` {
+				t.Errorf("first replayed %s %s", msg.Subject, msg.Data)
+			}
+			return nil
+		}),
+		publisher.EXPECT().PublishMsg(gomock.Any()).DoAndReturn(func(msg *nats.Msg) error {
+			if msg.Subject != "good_updated" {
+				t.Errorf("second replayed %s", msg.Subject)
+			}
+			return nil
+		}),
+	)
+	if n, err := eventSpool.Replay(ctx, publisher); err != nil || n != 2 {
+		t.Fatalf("Replay = %d, %v, want 2 events", n, err)
+	}
+}
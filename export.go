@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hezzl-test/internal/labels"
+	"hezzl-test/internal/objectstore"
+	"hezzl-test/internal/parquet"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// goodsParquetColumns повторяет колонки таблицы goods.
+var goodsParquetColumns = []parquet.Column{
+	{Name: "id", Type: parquet.Int32},
+	{Name: "tenant_id", Type: parquet.Int32},
+	{Name: "project_id", Type: parquet.Int32},
+	{Name: "category_id", Type: parquet.Int32, Optional: true},
+	{Name: "name", Type: parquet.String},
+	{Name: "description", Type: parquet.String},
+	{Name: "priority", Type: parquet.Int32},
+	{Name: "removed", Type: parquet.Boolean},
+	{Name: "removed_at", Type: parquet.Timestamp, Optional: true},
+	{Name: "labels", Type: parquet.JSON},
+	{Name: "created_at", Type: parquet.Timestamp},
+	{Name: "views", Type: parquet.Int64},
+	{Name: "version", Type: parquet.Int64},
+}
+
+// exportGoodsHandler выгружает товары арендатора в S3 для хранилищ данных
+// и возвращает ключ и временную ссылку на файл. Пока поддерживается только
+// format=parquet.
+func exportGoodsHandler(db *sql.DB, s3 *objectstore.S3) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format != "parquet" {
+			response.BadRequest(w, r, fmt.Errorf("unsupported format %q", format))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		f, err := os.CreateTemp("", "export-*.parquet")
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		rows, err := writeGoodsParquet(r.Context(), db, tenantID, f)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		size, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		key := fmt.Sprintf("exports/%d/goods-%s.parquet", tenantID, clk.Now().UTC().Format("20060102T150405Z"))
+		if err := s3.Put(r.Context(), key, "application/vnd.apache.parquet", f, size); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		url, err := s3.PresignGet(key, s3PresignTime)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusCreated, map[string]interface{}{"key": key, "url": url, "size": size, "rows": rows})
+	}
+}
+
+// writeGoodsParquet пишет товары арендатора из одной транзакции REPEATABLE
+// READ группами по exportRowGroupSize строк. Описания расшифровываются.
+func writeGoodsParquet(ctx context.Context, db *sql.DB, tenantID int, w io.Writer) (int, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	pw, err := parquet.NewWriter(w, goodsParquetColumns, exportRowGroupSize)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, tenant_id, project_id, category_id, name, description, priority, removed, removed_at, labels, created_at, views, version
+		FROM goods WHERE tenant_id = $1 ORDER BY id`, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var (
+			id, goodTenantID, projectID, priority int
+			categoryID                            sql.NullInt32
+			name, description                     string
+			removed                               bool
+			removedAt                             sql.NullTime
+			goodLabels                            labels.Labels
+			createdAt                             time.Time
+			views, version                        int64
+		)
+		err := rows.Scan(&id, &goodTenantID, &projectID, &categoryID, &name, decrypted{&description}, &priority, &removed, &removedAt, &goodLabels, &createdAt, &views, &version)
+		if err != nil {
+			return n, err
+		}
+		labelsJSON, err := goodLabels.Value()
+		if err != nil {
+			return n, err
+		}
+		if labelsJSON == nil {
+			labelsJSON = "{}"
+		}
+		err = pw.Write(id, goodTenantID, projectID, nullable(categoryID.Int32, categoryID.Valid), name, description, priority, removed,
+			nullable(removedAt.Time, removedAt.Valid), labelsJSON, createdAt, views, version)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, pw.Close()
+}
+
+func nullable(v interface{}, valid bool) interface{} {
+	if !valid {
+		return nil
+	}
+	return v
+}
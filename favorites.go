@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"net/http"
+)
+
+var errFavoriteNotFound = errs.NotFound("errors.favorite.notFound")
+
+func addFavoriteHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := tenant.UserFromContext(r.Context())
+		if user == "" {
+			response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "errors.user.required")
+			return
+		}
+
+		goodID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		result, err := db.Exec(`INSERT INTO good_favorites (user_id, good_id)
+			SELECT $1, id FROM goods WHERE id = $2 AND tenant_id = $3
+			ON CONFLICT DO NOTHING`,
+			user, goodID, tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			var exists bool
+			err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM good_favorites WHERE user_id = $1 AND good_id = $2)", user, goodID).Scan(&exists)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			if !exists {
+				response.Fail(w, r, errGoodNotFound)
+				return
+			}
+		}
+
+		response.NoContent(w)
+	}
+}
+
+func removeFavoriteHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := tenant.UserFromContext(r.Context())
+		if user == "" {
+			response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "errors.user.required")
+			return
+		}
+
+		goodID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		result, err := db.Exec("DELETE FROM good_favorites WHERE user_id = $1 AND good_id = $2", user, goodID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			response.Fail(w, r, errFavoriteNotFound)
+			return
+		}
+
+		response.NoContent(w)
+	}
+}
+
+func listFavoritesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := tenant.UserFromContext(r.Context())
+		if user == "" {
+			response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "errors.user.required")
+			return
+		}
+
+		limit, offset, err := pageParams(r)
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+		list := GoodsList{
+			Meta:  response.Meta{Limit: limit, Offset: offset},
+			Goods: []Goods{},
+		}
+
+		err = db.QueryRow(`SELECT COUNT(*), COUNT(*) FILTER (WHERE g.removed)
+			FROM good_favorites f JOIN goods g ON g.id = f.good_id
+			WHERE f.user_id = $1 AND g.tenant_id = $2 AND g.project_id NOT IN (SELECT id FROM projects WHERE removed)`,
+			user, tenantID).Scan(&list.Meta.Total, &list.Meta.Removed)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		rows, err := db.Query(`SELECT g.id, g.project_id, g.name, g.description, g.priority, g.removed, g.created_at
+			FROM good_favorites f JOIN goods g ON g.id = f.good_id
+			WHERE f.user_id = $1 AND g.tenant_id = $2 AND g.project_id NOT IN (SELECT id FROM projects WHERE removed)
+			ORDER BY f.created_at DESC, g.id
+			LIMIT $3 OFFSET $4`,
+			user, tenantID, limit, offset)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		favorite := true
+		for rows.Next() {
+			good := Goods{Favorite: &favorite}
+			err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, decrypted{&good.Description}, &good.Priority, &good.Removed, &good.CreatedAt)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			list.Goods = append(list.Goods, good)
+		}
+
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		list.link(r)
+		response.Pagination(w, r, list.Meta)
+		response.JSON(w, r, http.StatusOK, list)
+	}
+}
+
+// markFavorites проставляет признак favorite товарам страницы для user.
+func markFavorites(ctx context.Context, goods storage.GoodsRepository, user string, list []Goods) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(list))
+	for i, good := range list {
+		ids[i] = good.ID
+	}
+	favorites, err := goods.Favorites(ctx, user, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range list {
+		favorite := favorites[list[i].ID]
+		list[i].Favorite = &favorite
+	}
+	return nil
+}
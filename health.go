@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/goodslog"
+	"hezzl-test/internal/health"
+	"hezzl-test/internal/response"
+	"net/http"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func healthChecks(db *sql.DB, redisClient deps.Cache) []health.Check {
+	return []health.Check{
+		{Name: "postgres", Threshold: postgresDegradedLatency, Ping: func(ctx context.Context) error {
+			var one int
+			return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+		}},
+		{Name: "redis", Threshold: redisDegradedLatency, Ping: func(ctx context.Context) error {
+			err := redisClient.Get(ctx, "health:ping").Err()
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			return err
+		}},
+	}
+}
+
+// readyzHandler отдаёт последние результаты проверок зависимостей: 503, если
+// какая-то из них недоступна, и 200, если все отвечают, пусть и медленно.
+func readyzHandler(monitor *health.Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		if !monitor.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		response.JSON(w, r, status, map[string]interface{}{
+			"degraded": monitor.Degraded(),
+			"checks":   monitor.Results(),
+		})
+	}
+}
+
+// consumeHealthzHandler отдаёт состояние записи журнала товаров в ClickHouse:
+// 503, если последняя вставка не удалась. На репликах и без NATS журнал не
+// ведётся, и ответ — 200 с enabled: false.
+func consumeHealthzHandler(consumer *goodslog.Consumer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if consumer == nil {
+			response.JSON(w, r, http.StatusOK, map[string]interface{}{"enabled": false})
+			return
+		}
+		h := consumer.Health()
+		status := http.StatusOK
+		if !h.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+		response.JSON(w, r, status, map[string]interface{}{
+			"enabled":   true,
+			"goods_log": h,
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/labels"
+	"hezzl-test/internal/metrics"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// csvSource читает CSV с заголовком name,description,labels; labels
+// записываются как "k1=v1;k2=v2".
+type csvSource struct {
+	r       *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func newCSVSource(r io.Reader) (*csvSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errs.Validation(fmt.Errorf("read csv header: %w", err))
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errs.Validation(errors.New(`csv header must contain "name" column`))
+	}
+	return &csvSource{r: reader, columns: columns, line: 1}, nil
+}
+
+func (s *csvSource) Next() (bulk.Row, error) {
+	record, err := s.r.Read()
+	s.line++
+	if err == io.EOF {
+		return bulk.Row{}, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return bulk.Row{}, &bulk.RowError{Line: s.line, Reason: parseErr.Err.Error()}
+		}
+		return bulk.Row{}, err
+	}
+
+	row := bulk.Row{
+		Line:        s.line,
+		Name:        s.field(record, "name"),
+		Description: s.field(record, "description"),
+	}
+	if v := s.field(record, "labels"); v != "" {
+		row.Labels = labels.Labels{}
+		for _, pair := range strings.Split(v, ";") {
+			k, v, _ := strings.Cut(pair, "=")
+			row.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		if err := row.Labels.Validate(); err != nil {
+			return bulk.Row{}, &bulk.RowError{Line: s.line, Reason: err.Error()}
+		}
+	}
+	return row, nil
+}
+
+func (s *csvSource) field(record []string, name string) string {
+	i, ok := s.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// importTarget проверяет проект импорта из projectId и загружает его
+// настройки; при ошибке ответ уже отправлен и ok ложно.
+func importTarget(w http.ResponseWriter, r *http.Request, db *sql.DB) (tenantID, projectID int, settings ProjectSettings, ok bool) {
+	projectID, err := queryInt(r, "projectId")
+	if err != nil {
+		response.BadRequest(w, r, err)
+		return
+	}
+
+	tenantID = tenant.FromContext(r.Context())
+
+	var exists bool
+	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM projects WHERE id = $1 AND tenant_id = $2 AND NOT removed)", projectID, tenantID).Scan(&exists)
+	if err != nil {
+		response.InternalError(w, r, err)
+		return
+	}
+	if !exists {
+		response.Fail(w, r, errProjectNotFound)
+		return
+	}
+	if err := storage.CheckWritable(r.Context(), db, tenantID, projectID); err != nil {
+		response.Fail(w, r, err)
+		return
+	}
+
+	settings, err = loadProjectSettings(r.Context(), db, projectID)
+	if err != nil {
+		response.InternalError(w, r, err)
+		return
+	}
+	return tenantID, projectID, settings, true
+}
+
+func importGoodsHandler(db *sql.DB, redisClient deps.Cache, natsConn deps.Publisher, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, projectID, settings, ok := importTarget(w, r, db)
+		if !ok {
+			return
+		}
+
+		csvSrc, err := newCSVSource(http.MaxBytesReader(w, r.Body, importMaxBytes))
+		if err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+		src := sealingSource{Source: csvSrc, settings: settings}
+
+		result, err := bulk.Load(r.Context(), db, importInto(tenantID, projectID, settings), src)
+		if err == bulk.ErrQuotaExceeded {
+			response.Fail(w, r, errQuotaExceeded)
+			return
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				response.Error(w, r, http.StatusRequestEntityTooLarge, response.CodeBadRequest, "errors.import.tooLarge")
+				return
+			}
+			response.InternalError(w, r, err)
+			return
+		}
+		err = effects.Submit(r.Context(), "goods_imported", func(ctx context.Context) error {
+			return goodsImported(ctx, redisClient, natsConn, tenantID, projectID, settings, result)
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, result)
+	}
+}
+
+// importInto — цель bulk.Load для проекта: товары встают в конец с шагом
+// стратегии приоритетов проекта, как при создании по одному.
+func importInto(tenantID, projectID int, settings ProjectSettings) bulk.Target {
+	step := 1
+	if settings.PriorityStrategy == priorityGap {
+		step = settings.PriorityGapSize()
+	}
+	return bulk.Target{
+		TenantID:  tenantID,
+		ProjectID: projectID,
+		MaxGoods:  settings.GoodsLimit(),
+		Step:      step,
+		Reserve: func(ctx context.Context, tx *sql.Tx, n int) (int, error) {
+			return reservePriorities(ctx, tx, tenantID, projectID, n)
+		},
+		CreatedAt: clk.Now(),
+	}
+}
+
+// goodsImported сбрасывает кэши списков арендатора и после загрузки товаров
+// в проект публикует new_good_created по каждому товару, чтобы их увидели
+// индексатор и goods_log, и сводное goods_imported.
+func goodsImported(ctx context.Context, redisClient deps.Cache, natsConn deps.Publisher, tenantID, projectID int, settings ProjectSettings, result bulk.Result) error {
+	metrics.GoodsCreated(projectID, result.Inserted)
+
+	if err := invalidateGoodsLists(ctx, redisClient, tenantID); err != nil {
+		log.Printf("cache: invalidate goods lists: %v", err)
+	}
+	for _, g := range result.Goods {
+		good := Goods{
+			ID:          g.ID,
+			ProjectID:   projectID,
+			Name:        g.Name,
+			Description: g.Description,
+			Priority:    g.Priority,
+			Labels:      g.Labels,
+			CreatedAt:   g.CreatedAt,
+		}
+		data, err := json.Marshal(goodEvent(good, settings.EncryptDescription))
+		if err != nil {
+			return err
+		}
+		if err := publishGood(ctx, natsConn, "new_good_created", good.ID, 1, data); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(map[string]int{"projectId": projectID, "inserted": result.Inserted})
+	if err != nil {
+		return err
+	}
+	return publish(ctx, natsConn, "goods_imported", data)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/clock"
+	"hezzl-test/internal/localcache"
+)
+
+func TestImportInto(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	useClock(t, clock.NewManual(now))
+	gap := 100
+
+	tests := []struct {
+		name     string
+		settings ProjectSettings
+		step     int
+	}{
+		{name: "default", step: 1},
+		{name: "shift", settings: ProjectSettings{PriorityStrategy: priorityShift}, step: 1},
+		{name: "gap", settings: ProjectSettings{PriorityStrategy: priorityGap}, step: defaultPriorityGap},
+		{name: "custom gap", settings: ProjectSettings{PriorityStrategy: priorityGap, PriorityGap: &gap}, step: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := importInto(testTenantID, 3, tt.settings)
+			if target.Step != tt.step || target.ProjectID != 3 || target.TenantID != testTenantID || !target.CreatedAt.Equal(now) {
+				t.Fatalf("target = %+v, want step %d at %s", target, tt.step, now)
+			}
+		})
+	}
+}
+
+func TestGoodsImportedPublishesEveryGood(t *testing.T) {
+	publisher, _, events := recordEvents(t)
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+
+	result := bulk.Result{Inserted: 2, Goods: []bulk.Good{
+		{ID: 11, Name: "Tea", Description: "enc:sealed", Priority: 101},
+		{ID: 12, Name: "Coffee", Description: "enc:sealed", Priority: 201},
+	}}
+	settings := ProjectSettings{EncryptDescription: true}
+	if err := goodsImported(context.Background(), cache, publisher, testTenantID, 3, settings, result); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := events()
+	created := msgs["new_good_created"]
+	if len(created) != 2 {
+		t.Fatalf("new_good_created published %d times, want 2", len(created))
+	}
+	for i, msg := range created {
+		var event Goods
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		want := result.Goods[i]
+		if event.ID != want.ID || event.ProjectID != 3 || event.Priority != want.Priority || event.Description != "" {
+			t.Errorf("event %d = %+v, want good %d without description", i, event, want.ID)
+		}
+		if got := msg.Header.Get(sequenceHeader); got != "1" {
+			t.Errorf("event %d sequence = %s, want 1", i, got)
+		}
+	}
+	if len(msgs["goods_imported"]) != 1 {
+		t.Errorf("goods_imported published %d times, want 1", len(msgs["goods_imported"]))
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"hezzl-test/internal/errgroup"
+	"hezzl-test/internal/storage"
+)
+
+// includeProject — значение include списка товаров, встраивающее в каждый
+// товар его проект.
+const includeProject = "project"
+
+// expandGoods дополняет уже прочитанную страницу товаров проектами, если
+// includeProjects, и отметками избранного пользователя favoritesOf, если он
+// задан. Запросы независимы и идут параллельно, поэтому расширения добавляют
+// к ответу время самого долгого из них, а не их сумму; ошибка одного
+// отменяет другой.
+func expandGoods(ctx context.Context, goods storage.GoodsRepository, projects storage.ProjectsRepository, tenantID int, list []Goods, includeProjects bool, favoritesOf string) error {
+	g, ctx := errgroup.WithContext(ctx)
+	if includeProjects {
+		g.Go(func() error {
+			return embedProjects(ctx, projects, tenantID, list)
+		})
+	}
+	if favoritesOf != "" {
+		g.Go(func() error {
+			return markFavorites(ctx, goods, favoritesOf, list)
+		})
+	}
+	return g.Wait()
+}
+
+// loadGoodsWithProjects читает страницу товаров и проекты арендатора
+// параллельно: проекты страницы до её чтения неизвестны, поэтому читаются
+// все неудалённые проекты арендатора, а в товары встраиваются их собственные.
+func loadGoodsWithProjects(ctx context.Context, goods storage.GoodsRepository, projectsRepo storage.ProjectsRepository, q storage.GoodsQuery) (GoodsList, error) {
+	var (
+		list     GoodsList
+		projects []storage.Project
+	)
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		list, err = loadGoodsPage(ctx, goods, q)
+		return err
+	})
+	g.Go(func() (err error) {
+		projects, err = projectsRepo.List(ctx, q.TenantID)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return list, err
+	}
+	attachProjects(list.Goods, projects)
+	return list, nil
+}
+
+// embedProjects читает проекты товаров одним запросом и проставляет их в
+// Goods.Project; товары одного проекта делят один объект.
+func embedProjects(ctx context.Context, projects storage.ProjectsRepository, tenantID int, goods []Goods) error {
+	if len(goods) == 0 {
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	var ids []int
+	for _, good := range goods {
+		if !seen[good.ProjectID] {
+			seen[good.ProjectID] = true
+			ids = append(ids, good.ProjectID)
+		}
+	}
+
+	found, err := projects.GetMany(ctx, tenantID, ids)
+	if err != nil {
+		return err
+	}
+	attachProjects(goods, found)
+	return nil
+}
+
+func attachProjects(goods []Goods, projects []storage.Project) {
+	byID := make(map[int]*Projects, len(projects))
+	for _, p := range projects {
+		project := Projects{ID: p.ID, Name: p.Name, CreatedAt: p.CreatedAt}
+		project.link()
+		byID[p.ID] = &project
+	}
+	for i := range goods {
+		goods[i].Project = byID[goods[i].ProjectID]
+	}
+}
@@ -0,0 +1,174 @@
+// Package bind разбирает параметры запроса в типизированные структуры.
+//
+// Поле связывается с параметром тегом query:"name" или
+// query:"name,required". Поддерживаются int, bool, string, time.Time
+// (RFC3339), []int и []string (через запятую), а также вложенные без имени
+// структуры. Теги enum:"a,b" и min/max ограничивают значения строк и чисел.
+// Отсутствующий параметр оставляет поле как есть, поэтому значения по
+// умолчанию задаются в структуре до разбора.
+package bind
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors — сообщения об ошибках по именам параметров.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + e[name]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Fields отдаёт ошибки для details ответа 400.
+func (e Errors) Fields() map[string]string {
+	return e
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Query заполняет структуру по указателю dst из values. Ошибки всех полей
+// собираются в Errors; ошибка в описании структуры вызывает панику.
+func Query(values url.Values, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("bind: %T is not a pointer to struct", dst))
+	}
+	errs := Errors{}
+	bindStruct(values, v.Elem(), errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func bindStruct(values url.Values, v reflect.Value, errs Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			bindStruct(values, v.Field(i), errs)
+			continue
+		}
+		tag, ok := f.Tag.Lookup("query")
+		if !ok {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		raw, present := values[name]
+		if !present || raw[0] == "" {
+			if opts == "required" {
+				errs[name] = "is required"
+			}
+			continue
+		}
+		if msg := bindField(v.Field(i), f, raw[0]); msg != "" {
+			errs[name] = msg
+		}
+	}
+}
+
+func bindField(v reflect.Value, f reflect.StructField, raw string) string {
+	if v.Type() == timeType {
+		ts, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return "must be an RFC3339 timestamp"
+		}
+		v.Set(reflect.ValueOf(ts))
+		return ""
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if msg := checkEnum(f, raw); msg != "" {
+			return msg
+		}
+		v.SetString(raw)
+	case reflect.Int:
+		n, msg := parseInt(f, raw)
+		if msg != "" {
+			return msg
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "must be true or false"
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var parts []string
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		list := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			switch v.Type().Elem().Kind() {
+			case reflect.Int:
+				n, msg := parseInt(f, part)
+				if msg != "" {
+					return msg
+				}
+				list.Index(i).SetInt(int64(n))
+			case reflect.String:
+				if msg := checkEnum(f, part); msg != "" {
+					return msg
+				}
+				list.Index(i).SetString(part)
+			default:
+				panic(fmt.Sprintf("bind: unsupported field %s %s", f.Name, f.Type))
+			}
+		}
+		v.Set(list)
+	default:
+		panic(fmt.Sprintf("bind: unsupported field %s %s", f.Name, f.Type))
+	}
+	return ""
+}
+
+func parseInt(f reflect.StructField, raw string) (int, string) {
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, "must be an integer"
+	}
+	if limit, ok := f.Tag.Lookup("min"); ok {
+		if min, _ := strconv.Atoi(limit); n < min {
+			return 0, "must be at least " + limit
+		}
+	}
+	if limit, ok := f.Tag.Lookup("max"); ok {
+		if max, _ := strconv.Atoi(limit); n > max {
+			return 0, "must be at most " + limit
+		}
+	}
+	return n, ""
+}
+
+func checkEnum(f reflect.StructField, raw string) string {
+	enum, ok := f.Tag.Lookup("enum")
+	if !ok {
+		return ""
+	}
+	for _, allowed := range strings.Split(enum, ",") {
+		if raw == allowed {
+			return ""
+		}
+	}
+	return "must be one of " + enum
+}
@@ -0,0 +1,125 @@
+package bind
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Page struct {
+	Limit  int `query:"limit" min:"1" max:"100"`
+	Offset int `query:"offset" min:"0"`
+}
+
+type params struct {
+	Page
+	ProjectID int       `query:"projectId,required"`
+	Sort      string    `query:"sort" enum:"priority,name"`
+	IDs       []int     `query:"ids" min:"1"`
+	Fields    []string  `query:"fields" enum:"id,name"`
+	Removed   bool      `query:"removed"`
+	Since     time.Time `query:"since"`
+	Ignored   string
+}
+
+func TestQuery(t *testing.T) {
+	since := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query string
+		want  params
+		errs  Errors
+	}{
+		{
+			name:  "all fields",
+			query: "projectId=3&limit=20&offset=40&sort=name&ids=1,%202,,3&fields=id,name&removed=true&since=2026-03-10T12:00:00Z&Ignored=x",
+			want:  params{Page: Page{Limit: 20, Offset: 40}, ProjectID: 3, Sort: "name", IDs: []int{1, 2, 3}, Fields: []string{"id", "name"}, Removed: true, Since: since},
+		},
+		{
+			name:  "defaults kept",
+			query: "projectId=3&sort=",
+			want:  params{Page: Page{Limit: 10}, ProjectID: 3, Sort: "priority"},
+		},
+		{
+			name:  "first value wins",
+			query: "projectId=3&projectId=4&limit=7&limit=x",
+			want:  params{Page: Page{Limit: 7}, ProjectID: 3, Sort: "priority"},
+		},
+		{
+			name:  "required",
+			query: "limit=5",
+			errs:  Errors{"projectId": "is required"},
+		},
+		{
+			name:  "bounds",
+			query: "projectId=3&limit=0&offset=-1&ids=2,0",
+			errs:  Errors{"limit": "must be at least 1", "offset": "must be at least 0", "ids": "must be at least 1"},
+		},
+		{
+			name:  "max",
+			query: "projectId=3&limit=101",
+			errs:  Errors{"limit": "must be at most 100"},
+		},
+		{
+			name:  "types",
+			query: "projectId=x&removed=maybe&since=yesterday",
+			errs:  Errors{"projectId": "must be an integer", "removed": "must be true or false", "since": "must be an RFC3339 timestamp"},
+		},
+		{
+			name:  "enum",
+			query: "projectId=3&sort=created&fields=id,price",
+			errs:  Errors{"sort": "must be one of priority,name", "fields": "must be one of id,name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := params{Page: Page{Limit: 10}, Sort: "priority"}
+			err = Query(values, &got)
+			if tt.errs != nil {
+				if !reflect.DeepEqual(err, tt.errs) {
+					t.Fatalf("err = %v, want %v", err, tt.errs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	errs := Errors{"b": "is required", "a": "must be an integer"}
+	if got, want := errs.Error(), "a: must be an integer; b: is required"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestQueryPanicsOnBadDestination(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic for a non-pointer destination")
+		}
+	}()
+	Query(url.Values{}, params{})
+}
+
+func TestQueryPanicsOnUnsupportedField(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic for a float field")
+		}
+	}()
+	var dst struct {
+		Price float64 `query:"price"`
+	}
+	Query(url.Values{"price": {"1.5"}}, &dst)
+}
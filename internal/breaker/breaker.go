@@ -0,0 +1,159 @@
+package breaker
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type Settings struct {
+	Name string
+	// MaxFailures подряд идущих ошибок размыкает цепь.
+	MaxFailures int
+	// OpenTimeout — сколько цепь остаётся разомкнутой до пробных вызовов.
+	OpenTimeout time.Duration
+	// HalfOpenRequests — число одновременных пробных вызовов; успех всех
+	// замыкает цепь, любая ошибка снова размыкает её.
+	HalfOpenRequests int
+}
+
+// Breaker отсекает вызовы к зависимости, пока она стабильно отказывает,
+// чтобы запросы сразу получали ErrOpen вместо ожидания таймаутов.
+type Breaker struct {
+	settings Settings
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	probes    int
+	successes int
+	openedAt  time.Time
+}
+
+func New(settings Settings) *Breaker {
+	if settings.MaxFailures <= 0 {
+		settings.MaxFailures = 5
+	}
+	if settings.HalfOpenRequests <= 0 {
+		settings.HalfOpenRequests = 1
+	}
+	return &Breaker{settings: settings}
+}
+
+func (b *Breaker) Name() string {
+	return b.settings.Name
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh(time.Now())
+	return b.state
+}
+
+// Execute выполняет fn, если цепь пропускает вызов; ошибка fn считается
+// отказом зависимости.
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err == nil)
+	return err
+}
+
+// Allow резервирует вызов. Вызывающий обязан сообщить его исход через done;
+// исход, не зависящий от здоровья зависимости (например, отмену запроса
+// клиентом), следует передавать как успех.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh(time.Now())
+	switch b.state {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.settings.HalfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+
+	state := b.state
+	return func(success bool) { b.report(state, success) }, nil
+}
+
+func (b *Breaker) report(state State, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Исход вызова, начатого в другом состоянии цепи, уже не показателен.
+	if state != b.state {
+		return
+	}
+
+	switch b.state {
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.settings.MaxFailures {
+			b.setState(Open)
+		}
+	case HalfOpen:
+		if !success {
+			b.setState(Open)
+			return
+		}
+		b.successes++
+		if b.successes >= b.settings.HalfOpenRequests {
+			b.setState(Closed)
+		}
+	}
+}
+
+func (b *Breaker) refresh(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.settings.OpenTimeout {
+		b.setState(HalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	log.Printf("breaker: %s: %s -> %s", b.settings.Name, b.state, state)
+
+	b.state = state
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == Open {
+		b.openedAt = time.Now()
+	}
+}
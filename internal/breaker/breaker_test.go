@@ -0,0 +1,146 @@
+package breaker
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var errDown = errors.New("connection refused")
+
+func fail() error { return errDown }
+func ok() error   { return nil }
+
+func TestOpensAfterMaxFailures(t *testing.T) {
+	b := New(Settings{Name: "test", MaxFailures: 3, OpenTimeout: time.Hour})
+
+	// Успех сбрасывает счётчик: размыкают только отказы подряд.
+	b.Execute(fail)
+	b.Execute(fail)
+	b.Execute(ok)
+	b.Execute(fail)
+	b.Execute(fail)
+	if b.State() != Closed {
+		t.Fatalf("state = %s after non-consecutive failures", b.State())
+	}
+	b.Execute(fail)
+	if b.State() != Open {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+
+	called := false
+	err := b.Execute(func() error { called = true; return nil })
+	if !errors.Is(err, ErrOpen) || called {
+		t.Fatalf("open breaker: err = %v, called = %v", err, called)
+	}
+}
+
+func TestHalfOpen(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe func() error
+		want  State
+	}{
+		{"probe succeeds", ok, Closed},
+		{"probe fails", fail, Open},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(Settings{MaxFailures: 1, OpenTimeout: 10 * time.Millisecond})
+			b.Execute(fail)
+			time.Sleep(20 * time.Millisecond)
+			if b.State() != HalfOpen {
+				t.Fatalf("state = %s, want half-open after OpenTimeout", b.State())
+			}
+
+			done, err := b.Allow()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Пока пробный вызов не завершён, остальные отсекаются.
+			if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+				t.Fatalf("second probe: err = %v, want ErrOpen", err)
+			}
+			done(tt.probe() == nil)
+			if b.State() != tt.want {
+				t.Errorf("state = %s, want %s", b.State(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStaleOutcomeIgnored(t *testing.T) {
+	b := New(Settings{MaxFailures: 1, OpenTimeout: time.Hour})
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Execute(fail)
+	if b.State() != Open {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	// Вызов начат при замкнутой цепи; его успех не замыкает разомкнутую.
+	done(true)
+	if b.State() != Open {
+		t.Errorf("state = %s after a stale success, want open", b.State())
+	}
+}
+
+type fakeConnector struct{ err error }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return nil, c.err }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+func TestConnector(t *testing.T) {
+	b := New(Settings{MaxFailures: 1, OpenTimeout: time.Hour})
+
+	// Отменённый запрос — не отказ базы.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	Connector(b, fakeConnector{err: context.Canceled}).Connect(ctx)
+	if b.State() != Closed {
+		t.Fatalf("state = %s after a cancelled connect", b.State())
+	}
+
+	c := Connector(b, fakeConnector{err: errDown})
+	if _, err := c.Connect(context.Background()); !errors.Is(err, errDown) {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := c.Connect(context.Background()); !errors.Is(err, ErrOpen) {
+		t.Fatalf("err = %v, want ErrOpen", err)
+	}
+}
+
+// serverError — ошибка, которую вернул сам Redis.
+type serverError string
+
+func (e serverError) Error() string { return string(e) }
+func (serverError) RedisError()     {}
+
+func TestRedisHealthy(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"ok", context.Background(), nil, true},
+		{"missing key", context.Background(), redis.Nil, true},
+		{"server error", context.Background(), serverError("WRONGTYPE Operation against a key holding the wrong kind of value"), true},
+		{"cancelled", cancelled, context.Canceled, true},
+		{"network", context.Background(), errDown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redisHealthy(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("redisHealthy(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
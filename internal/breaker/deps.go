@@ -0,0 +1,78 @@
+package breaker
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Connector пропускает установку новых соединений database/sql через
+// breaker. Пока база недоступна, пул получает ErrOpen без ожидания
+// таймаута подключения.
+func Connector(b *Breaker, c driver.Connector) driver.Connector {
+	return &connector{Connector: c, breaker: b}
+}
+
+type connector struct {
+	driver.Connector
+	breaker *Breaker
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	done, err := c.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.Connector.Connect(ctx)
+	done(err == nil || ctx.Err() != nil)
+	return conn, err
+}
+
+// RedisHook пропускает команды Redis через breaker. Отсутствие ключа и
+// отмена запроса отказом не считаются.
+func RedisHook(b *Breaker) redis.Hook {
+	return redisHook{breaker: b}
+}
+
+type redisHook struct {
+	breaker *Breaker
+}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			return err
+		}
+		err = next(ctx, cmd)
+		done(redisHealthy(ctx, err))
+		return err
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			return err
+		}
+		err = next(ctx, cmds)
+		done(redisHealthy(ctx, err))
+		return err
+	}
+}
+
+func redisHealthy(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || ctx.Err() != nil {
+		return true
+	}
+	// Ошибки сервера (WRONGTYPE и т.п.) говорят о запросе, а не о доступности.
+	var redisErr redis.Error
+	return errors.As(err, &redisErr)
+}
@@ -0,0 +1,53 @@
+package budget
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type Step int
+
+const (
+	DB Step = iota
+	Cache
+	Publish
+)
+
+// Доли бюджета запроса, которые может занять один шаг. Медленный кэш не
+// должен съедать время, нужное базе, поэтому каждый шаг ограничен своей долей.
+var shares = map[Step]float64{
+	DB:      0.7,
+	Cache:   0.2,
+	Publish: 0.1,
+}
+
+type ctxKey struct{}
+
+// WithBudget ограничивает ctx общим временем total и запоминает его для For.
+func WithBudget(ctx context.Context, total time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, total)
+	return context.WithValue(ctx, ctxKey{}, total), cancel
+}
+
+// Middleware выдаёт каждому запросу бюджет total.
+func Middleware(total time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := WithBudget(r.Context(), total)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// For возвращает контекст для шага step: его срок — доля шага от бюджета
+// запроса, но не позже срока самого запроса. Без бюджета в ctx шаг ничем
+// дополнительно не ограничен.
+func For(ctx context.Context, step Step) (context.Context, context.CancelFunc) {
+	total, ok := ctx.Value(ctxKey{}).(time.Duration)
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(float64(total)*shares[step]))
+}
@@ -0,0 +1,224 @@
+package bulk
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"time"
+
+	"github.com/lib/pq"
+
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/labels"
+)
+
+const maxReportedRejections = 100
+
+// ErrQuotaExceeded — после загрузки в проекте оказалось бы больше maxGoods
+// товаров; в этом случае не загружается ни одна строка.
+var ErrQuotaExceeded = errs.Conflict("errors.project.quotaExceeded")
+
+type Row struct {
+	Line        int
+	Name        string
+	Description string
+	Labels      labels.Labels
+}
+
+// Source отдаёт строки импорта по одной; конец данных — io.EOF.
+// Ошибка типа *RowError отклоняет одну строку, не прерывая загрузку.
+type Source interface {
+	Next() (Row, error)
+}
+
+type RowError struct {
+	Line   int
+	Reason string
+}
+
+func (e *RowError) Error() string {
+	return e.Reason
+}
+
+type Rejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+type Result struct {
+	Inserted int         `json:"inserted"`
+	Rejected int         `json:"rejected"`
+	Errors   []Rejection `json:"errors"`
+	// Goods — вставленные товары в порядке строк; по ним публикуются события.
+	Goods []Good `json:"-"`
+}
+
+// Good — товар, вставленный загрузкой; Description — в том виде, в котором
+// он записан в goods.
+type Good struct {
+	ID          int
+	Name        string
+	Description string
+	Priority    int
+	Labels      labels.Labels
+	CreatedAt   time.Time
+}
+
+// Target — проект, в который загружаются товары, и правила вставки.
+type Target struct {
+	TenantID  int
+	ProjectID int
+	// MaxGoods, если положителен, ограничивает число товаров проекта вне
+	// корзины.
+	MaxGoods int
+	// Step — шаг между приоритетами соседних товаров; 0 равносилен 1.
+	Step int
+	// Reserve резервирует n приоритетов в счётчике проекта и возвращает
+	// последний приоритет до резерва.
+	Reserve func(ctx context.Context, tx *sql.Tx, n int) (int, error)
+	// CreatedAt — время создания загруженных товаров.
+	CreatedAt time.Time
+}
+
+// Load загружает товары в проект через COPY во временную таблицу,
+// проверяет их там же и одной вставкой переносит корректные строки в goods.
+// Всё выполняется в одной транзакции: либо видны все строки, либо ни одной.
+// Товары встают в конец проекта с шагом target.Step в порядке следования
+// строк.
+func Load(ctx context.Context, db *sql.DB, target Target, src Source) (Result, error) {
+	result := Result{Errors: []Rejection{}}
+	step := target.Step
+	if step < 1 {
+		step = 1
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `CREATE TEMP TABLE goods_import
+		(line INT, name TEXT, description TEXT, labels JSONB) ON COMMIT DROP`)
+	if err != nil {
+		return result, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("goods_import", "line", "name", "description", "labels"))
+	if err != nil {
+		return result, err
+	}
+	for {
+		row, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if rowErr, ok := err.(*RowError); ok {
+			result.reject(rowErr.Line, rowErr.Reason)
+			continue
+		}
+		if err != nil {
+			stmt.Close()
+			return result, err
+		}
+
+		labelsJSON, err := row.Labels.Value()
+		if err != nil {
+			stmt.Close()
+			return result, err
+		}
+		if _, err := stmt.ExecContext(ctx, row.Line, row.Name, row.Description, labelsJSON); err != nil {
+			stmt.Close()
+			return result, err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return result, err
+	}
+	if err := stmt.Close(); err != nil {
+		return result, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM goods_import
+		WHERE btrim(coalesce(name, '')) = '' OR length(name) > 255
+		RETURNING line, CASE WHEN btrim(coalesce(name, '')) = '' THEN 'name is required' ELSE 'name is too long' END`)
+	if err != nil {
+		return result, err
+	}
+	for rows.Next() {
+		var r Rejection
+		if err := rows.Scan(&r.Line, &r.Reason); err != nil {
+			rows.Close()
+			return result, err
+		}
+		result.reject(r.Line, r.Reason)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	var locked int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM projects WHERE id = $1 AND tenant_id = $2 FOR UPDATE", target.ProjectID, target.TenantID).Scan(&locked)
+	if err != nil {
+		return result, err
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM goods_import").Scan(&count); err != nil {
+		return result, err
+	}
+	if count == 0 {
+		return result, tx.Commit()
+	}
+
+	if target.MaxGoods > 0 {
+		var exceeded bool
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) + $2 > $3 FROM goods WHERE project_id = $1 AND NOT removed",
+			target.ProjectID, count, target.MaxGoods).Scan(&exceeded)
+		if err != nil {
+			return result, err
+		}
+		if exceeded {
+			return result, ErrQuotaExceeded
+		}
+	}
+
+	// Приоритеты для всех строк резервируются в счётчике проекта одним запросом.
+	last, err := target.Reserve(ctx, tx, count*step)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `INSERT INTO goods (tenant_id, project_id, name, description, priority, labels, created_at)
+		SELECT $1, $2, btrim(name), coalesce(description, ''), $3 + $4 * ROW_NUMBER() OVER (ORDER BY line),
+			coalesce(labels, '{}'), $5
+		FROM goods_import ORDER BY line
+		RETURNING id, name, description, priority, labels, created_at`,
+		target.TenantID, target.ProjectID, last, step, target.CreatedAt)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var good Good
+		if err := rows.Scan(&good.ID, &good.Name, &good.Description, &good.Priority, &good.Labels, &good.CreatedAt); err != nil {
+			return result, err
+		}
+		result.Goods = append(result.Goods, good)
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	result.Inserted = len(result.Goods)
+
+	return result, tx.Commit()
+}
+
+func (r *Result) reject(line int, reason string) {
+	r.Rejected++
+	if len(r.Errors) < maxReportedRejections {
+		r.Errors = append(r.Errors, Rejection{Line: line, Reason: reason})
+	}
+}
@@ -0,0 +1,176 @@
+// Package cachebudget ограничивает место, которое ключи одного проекта
+// занимают в общем кэше, чтобы большой каталог не вытеснял кэш остальных.
+//
+// Размер ключа — длина имени и значения при записи. Учёт ведётся в памяти процесса
+// по ключам, которые записал этот экземпляр сервиса, поэтому бюджет
+// действует на экземпляр, а не на весь кэш. Ключи, удалённые по шаблону
+// или истёкшие, остаются в учёте до вытеснения или до истечения срока.
+package cachebudget
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"hezzl-test/internal/deps"
+)
+
+// Evictions считает ключи, вытесненные при превышении бюджета проекта; её
+// нужно зарегистрировать в Prometheus вместе с остальными метриками.
+var Evictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_budget_evictions_total",
+	Help: "Cache keys evicted because their project exceeded its cache budget.",
+}, []string{"project"})
+
+type entry struct {
+	key     string
+	project int
+	size    int64
+	expires time.Time
+}
+
+type project struct {
+	bytes int64
+	// lru — ключи проекта от давно использованных к недавним.
+	lru *list.List
+}
+
+// Budget пишет ключи проектов в кэш, держа каждый проект в пределах limit
+// байт: при превышении удаляются давно не использованные ключи проекта.
+type Budget struct {
+	cache deps.Cache
+	limit int64
+
+	mu       sync.Mutex
+	projects map[int]*project
+	keys     map[string]*list.Element
+}
+
+func New(cache deps.Cache, limit int64) *Budget {
+	return &Budget{
+		cache:    cache,
+		limit:    limit,
+		projects: make(map[int]*project),
+		keys:     make(map[string]*list.Element),
+	}
+}
+
+// Set записывает value в кэш как ключ проекта и вытесняет его давно не
+// использованные ключи, если проект вышел за бюджет.
+func (b *Budget) Set(ctx context.Context, projectID int, key string, value []byte, ttl time.Duration) error {
+	if err := b.cache.Set(ctx, key, value, ttl).Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.remove(key)
+	p := b.projects[projectID]
+	if p == nil {
+		p = &project{lru: list.New()}
+		b.projects[projectID] = p
+	}
+	e := &entry{key: key, project: projectID, size: int64(len(key) + len(value))}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	b.keys[key] = p.lru.PushBack(e)
+	p.bytes += e.size
+
+	var evict []string
+	for p.bytes > b.limit && p.lru.Len() > 1 {
+		oldest := p.lru.Front().Value.(*entry)
+		evict = append(evict, oldest.key)
+		b.remove(oldest.key)
+	}
+	b.mu.Unlock()
+
+	if len(evict) == 0 {
+		return nil
+	}
+	Evictions.WithLabelValues(strconv.Itoa(projectID)).Add(float64(len(evict)))
+	return b.cache.Del(ctx, evict...).Err()
+}
+
+// Touch отмечает попадание в кэш по ключам, чтобы они вытеснялись позже.
+func (b *Budget) Touch(keys ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := b.keys[key]; ok {
+			b.projects[el.Value.(*entry).project].lru.MoveToBack(el)
+		}
+	}
+}
+
+// Forget убирает из учёта ключи, удалённые из кэша в обход Budget.
+func (b *Budget) Forget(keys ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		b.remove(key)
+	}
+}
+
+// ForgetProject убирает из учёта все ключи проекта, например после его
+// удаления.
+func (b *Budget) ForgetProject(projectID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p, ok := b.projects[projectID]; ok {
+		for el := p.lru.Front(); el != nil; el = el.Next() {
+			delete(b.keys, el.Value.(*entry).key)
+		}
+		delete(b.projects, projectID)
+	}
+}
+
+func (b *Budget) remove(key string) {
+	el, ok := b.keys[key]
+	if !ok {
+		return
+	}
+	e := el.Value.(*entry)
+	p := b.projects[e.project]
+	p.lru.Remove(el)
+	p.bytes -= e.size
+	delete(b.keys, key)
+	if p.lru.Len() == 0 {
+		delete(b.projects, e.project)
+	}
+}
+
+type Usage struct {
+	ProjectID int   `json:"projectId"`
+	Keys      int   `json:"keys"`
+	Bytes     int64 `json:"bytes"`
+	Limit     int64 `json:"limit"`
+}
+
+// Usage возвращает занятое проектами место от больших к меньшим, попутно
+// убирая из учёта истёкшие ключи.
+func (b *Budget) Usage() []Usage {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage := make([]Usage, 0, len(b.projects))
+	for id, p := range b.projects {
+		for el := p.lru.Front(); el != nil; {
+			next := el.Next()
+			if e := el.Value.(*entry); !e.expires.IsZero() && now.After(e.expires) {
+				b.remove(e.key)
+			}
+			el = next
+		}
+		if p.lru.Len() > 0 {
+			usage = append(usage, Usage{ProjectID: id, Keys: p.lru.Len(), Bytes: p.bytes, Limit: b.limit})
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Bytes > usage[j].Bytes })
+	return usage
+}
@@ -0,0 +1,69 @@
+// Package chaos внедряет искусственные задержки и отказы в вызовы Postgres,
+// Redis и NATS, чтобы на стенде проверить breaker'ы, повторы и деградацию
+// до того, как их проверит продакшен.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrInjected выглядит как обрыв соединения: retry считает его временным,
+// а breaker — отказом зависимости.
+var ErrInjected = fmt.Errorf("chaos: injected fault: %w", syscall.ECONNRESET)
+
+type Config struct {
+	// DelayRate — доля вызовов, которые ждут случайное время до MaxDelay.
+	DelayRate float64
+	MaxDelay  time.Duration
+	// FailRate — доля вызовов, которые завершаются ErrInjected.
+	FailRate float64
+}
+
+// Injector решает для каждого вызова, задержать ли его и сломать ли.
+// Нулевой *Injector ничего не внедряет.
+type Injector struct {
+	name string
+	cfg  Config
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func New(name string, cfg Config) *Injector {
+	return &Injector{name: name, cfg: cfg, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Inject выполняет решение для одного вызова: ждёт, если выпала задержка,
+// и возвращает ErrInjected, если выпал отказ. Отмена ctx прерывает ожидание.
+func (i *Injector) Inject(ctx context.Context) error {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	var delay time.Duration
+	if i.cfg.MaxDelay > 0 && i.rand.Float64() < i.cfg.DelayRate {
+		delay = time.Duration(i.rand.Int63n(int64(i.cfg.MaxDelay) + 1))
+	}
+	fail := i.rand.Float64() < i.cfg.FailRate
+	i.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if fail {
+		return fmt.Errorf("%s: %w", i.name, ErrInjected)
+	}
+	return nil
+}
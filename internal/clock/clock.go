@@ -0,0 +1,81 @@
+// Package clock отделяет код сервиса от системного времени и случайных id,
+// чтобы в тестах и при воспроизведении трафика их можно было подменить
+// детерминированными.
+package clock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator выдаёт непредсказуемые для клиента, но не секретные id:
+// имена объектов, id снимков и заданий. Секреты так получать нельзя.
+type IDGenerator interface {
+	NewID() string
+}
+
+// System — системные часы.
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Manual — часы, которые стоят, пока их не переведут Set или Advance.
+type Manual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (c *Manual) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Manual) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *Manual) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Random выдаёт 16 случайных hex-символов.
+type Random struct{}
+
+func (Random) NewID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// Sequence выдаёт Prefix1, Prefix2 и так далее.
+type Sequence struct {
+	Prefix string
+
+	mu   sync.Mutex
+	next int
+}
+
+func (s *Sequence) NewID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	return s.Prefix + strconv.Itoa(s.next)
+}
@@ -0,0 +1,371 @@
+// Package config собирает настройки сервиса. Значения по умолчанию задаёт
+// вызывающий, поверх них читаются переменные окружения, а поверх
+// окружения — необязательный файл: YAML для .yaml и .yml, иначе JSON.
+//
+// Поле связывается с переменной тегом env:"NAME" и с ключом файла тегом
+// json:"name". Поддерживаются string, int, float64, bool, []string (в
+// окружении через запятую) и time.Duration (строкой вида "1m30s"). Пустая переменная
+// окружения считается незаданной.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hezzl-test/internal/crypt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	PostgresDSN   string        `json:"postgres_dsn" env:"POSTGRES_DSN"`
+	ClickHouseDSN string        `json:"clickhouse_dsn" env:"CLICKHOUSE_DSN"`
+	RedisAddr     string        `json:"redis_addr" env:"REDIS_ADDR"`
+	RedisDB       int           `json:"redis_db" env:"REDIS_DB"`
+	RedisNodes    []string      `json:"redis_nodes" env:"REDIS_NODES"`
+	NATSURL       string        `json:"nats_url" env:"NATS_URL"`
+	ElasticAddr   string        `json:"elastic_addr" env:"ELASTIC_ADDR"`
+	S3Endpoint    string        `json:"s3_endpoint" env:"S3_ENDPOINT"`
+	S3Region      string        `json:"s3_region" env:"S3_REGION"`
+	S3Bucket      string        `json:"s3_bucket" env:"S3_BUCKET"`
+	S3AccessKey   string        `json:"s3_access_key" env:"S3_ACCESS_KEY"`
+	S3SecretKey   string        `json:"s3_secret_key" env:"S3_SECRET_KEY"`
+	HTTPAddr      string        `json:"http_addr" env:"HTTP_ADDR"`
+	AdminAddr     string        `json:"admin_addr" env:"ADMIN_ADDR"`
+	AdminToken    string        `json:"admin_token" env:"ADMIN_TOKEN"`
+	CacheTTL      time.Duration `json:"cache_ttl" env:"CACHE_TTL"`
+
+	// PostgresPreparedStatements включает подготовленные запросы; за
+	// PgBouncer в режиме transaction pooling их нужно выключить.
+	PostgresPreparedStatements bool `json:"postgres_prepared_statements" env:"POSTGRES_PREPARED_STATEMENTS"`
+
+	// CacheBackend — где держать кэш: CacheRedis, CacheRing (узлы из
+	// RedisNodes) или CacheMemory.
+	CacheBackend string `json:"cache_backend" env:"CACHE_BACKEND"`
+
+	// JWTSecret — ключ HS256 токенов клиентов. Пока он пуст, арендатор
+	// берётся из заголовка X-Tenant-ID; так можно запускать сервис только
+	// локально.
+	JWTSecret string `json:"jwt_secret" env:"JWT_SECRET"`
+
+	// EncryptionKeys — ключи шифрования описаний товаров в формате
+	// crypt.Parse; пустой — шифрование включить нельзя.
+	EncryptionKeys string `json:"encryption_keys" env:"ENCRYPTION_KEYS"`
+
+	// Исходящие запросы по адресам пользователей (см. egress.Config):
+	// прокси, разрешённые и запрещённые хосты, доступ к частным сетям.
+	EgressProxy        string   `json:"egress_proxy" env:"EGRESS_PROXY"`
+	EgressAllowHosts   []string `json:"egress_allow_hosts" env:"EGRESS_ALLOW_HOSTS"`
+	EgressDenyHosts    []string `json:"egress_deny_hosts" env:"EGRESS_DENY_HOSTS"`
+	EgressAllowPrivate bool     `json:"egress_allow_private" env:"EGRESS_ALLOW_PRIVATE"`
+
+	// TelegramToken — токен бота для уведомлений в Telegram.
+	TelegramToken string `json:"telegram_token" env:"TELEGRAM_TOKEN"`
+
+	// Почтовый сервер дайджестов; без имени пользователя письма уходят без
+	// авторизации.
+	SMTPAddr     string `json:"smtp_addr" env:"SMTP_ADDR"`
+	SMTPUsername string `json:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `json:"smtp_password" env:"SMTP_PASSWORD"`
+	SMTPFrom     string `json:"smtp_from" env:"SMTP_FROM"`
+
+	// Внедрение сбоев в вызовы Postgres, Redis и NATS для проверки
+	// breaker'ов и повторов на стенде (см. chaos.Config); по умолчанию
+	// выключено.
+	ChaosEnabled   bool          `json:"chaos_enabled" env:"CHAOS_ENABLED"`
+	ChaosDelayRate float64       `json:"chaos_delay_rate" env:"CHAOS_DELAY_RATE"`
+	ChaosMaxDelay  time.Duration `json:"chaos_max_delay" env:"CHAOS_MAX_DELAY"`
+	ChaosFailRate  float64       `json:"chaos_fail_rate" env:"CHAOS_FAIL_RATE"`
+
+	// Пачки записи событий товаров в goods_log.
+	GoodsLogBatchSize     int           `json:"goods_log_batch_size" env:"GOODS_LOG_BATCH_SIZE"`
+	GoodsLogFlushInterval time.Duration `json:"goods_log_flush_interval" env:"GOODS_LOG_FLUSH_INTERVAL"`
+}
+
+// CacheMemory держит кэш в памяти процесса и позволяет запускаться без
+// Redis; годится для разработки и одного экземпляра сервиса.
+// CacheRing раскладывает ключи по нескольким узлам Redis без кластерного
+// режима консистентным хешированием на стороне клиента.
+const (
+	CacheRedis  = "redis"
+	CacheRing   = "ring"
+	CacheMemory = "memory"
+)
+
+// Errors — сообщения об ошибках по ключам файла.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + e[name]
+	}
+	return "config: " + strings.Join(parts, "; ")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load начинает с defaults, применяет окружение и файл path, если он
+// задан, и проверяет результат.
+func Load(defaults Config, path string) (Config, error) {
+	cfg := defaults
+	errs := Errors{}
+	fromEnv(&cfg, errs)
+	if path != "" {
+		if err := fromFile(&cfg, path, errs); err != nil {
+			return cfg, err
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+	return cfg, cfg.Validate()
+}
+
+func fromEnv(cfg *Config, errs Errors) {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		raw := os.Getenv(f.Tag.Get("env"))
+		if raw == "" {
+			continue
+		}
+		if err := set(v.Field(i), raw); err != nil {
+			errs[f.Tag.Get("json")] = f.Tag.Get("env") + " " + err.Error()
+		}
+	}
+}
+
+// fromFile читает объект JSON или YAML; неизвестный ключ — ошибка, чтобы
+// опечатка не оставила значение по умолчанию незаметно.
+func fromFile(cfg *Config, path string, errs Errors) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		values, err = parseYAML(data)
+	default:
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		fields[v.Type().Field(i).Tag.Get("json")] = v.Field(i)
+	}
+	for name, value := range values {
+		field, ok := fields[name]
+		if !ok {
+			errs[name] = "unknown setting"
+			continue
+		}
+		if err := set(field, fileValue(value)); err != nil {
+			errs[name] = err.Error()
+		}
+	}
+	return nil
+}
+
+// fileValue приводит значение из файла к строке окружения: числа — без
+// дробной части, если её нет, списки — через запятую.
+func fileValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(value))
+		for i, item := range value {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// set записывает в поле значение raw. Ошибка — сообщение для Errors; поле
+// неподдерживаемого типа тоже ошибка, а не паника, хотя это и ошибка в
+// описании Config.
+func set(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("must be a duration like 30s or 1m")
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// Validate проверяет, что заданы адреса обязательных зависимостей и
+// адреса для прослушивания корректны. NATS необязателен: без него события
+// отбрасываются. Узлы кольца Redis нужны только бэкенду кэша CacheRing.
+func (c Config) Validate() error {
+	errs := Errors{}
+	required := map[string]string{
+		"postgres_dsn":   c.PostgresDSN,
+		"clickhouse_dsn": c.ClickHouseDSN,
+		"redis_addr":     c.RedisAddr,
+		"elastic_addr":   c.ElasticAddr,
+		"s3_endpoint":    c.S3Endpoint,
+		"s3_bucket":      c.S3Bucket,
+	}
+	for name, value := range required {
+		if value == "" {
+			errs[name] = "is required"
+		}
+	}
+	for name, addr := range map[string]string{"http_addr": c.HTTPAddr, "admin_addr": c.AdminAddr} {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			errs[name] = "must be host:port or :port"
+		}
+	}
+	if c.RedisDB < 0 {
+		errs["redis_db"] = "must not be negative"
+	}
+	if c.CacheTTL <= 0 {
+		errs["cache_ttl"] = "must be positive"
+	}
+	switch c.CacheBackend {
+	case CacheRedis, CacheMemory:
+	case CacheRing:
+		if len(c.RedisNodes) == 0 {
+			errs["redis_nodes"] = "is required for the ring cache backend"
+		}
+	default:
+		errs["cache_backend"] = fmt.Sprintf("must be %s, %s or %s", CacheRedis, CacheRing, CacheMemory)
+	}
+	if c.GoodsLogBatchSize <= 0 {
+		errs["goods_log_batch_size"] = "must be positive"
+	}
+	if c.GoodsLogFlushInterval <= 0 {
+		errs["goods_log_flush_interval"] = "must be positive"
+	}
+	for name, rate := range map[string]float64{"chaos_delay_rate": c.ChaosDelayRate, "chaos_fail_rate": c.ChaosFailRate} {
+		if rate < 0 || rate > 1 {
+			errs[name] = "must be between 0 and 1"
+		}
+	}
+	if c.ChaosMaxDelay < 0 {
+		errs["chaos_max_delay"] = "must not be negative"
+	}
+	if c.EncryptionKeys != "" {
+		if _, err := crypt.Parse(c.EncryptionKeys); err != nil {
+			errs["encryption_keys"] = err.Error()
+		}
+	}
+	if c.EgressProxy != "" {
+		if u, err := url.Parse(c.EgressProxy); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			errs["egress_proxy"] = "must be an http, https or socks5 URL"
+		}
+	}
+	for name, hosts := range map[string][]string{"egress_allow_hosts": c.EgressAllowHosts, "egress_deny_hosts": c.EgressDenyHosts} {
+		for _, host := range hosts {
+			if !validHostPattern(host) {
+				errs[name] = fmt.Sprintf("%q must be a host name or *.domain", host)
+				break
+			}
+		}
+	}
+	if c.TelegramToken != "" {
+		if id, secret, ok := strings.Cut(c.TelegramToken, ":"); !ok || secret == "" || !digits(id) {
+			errs["telegram_token"] = "must look like 123456:secret"
+		}
+	}
+	if _, _, err := net.SplitHostPort(c.SMTPAddr); err != nil {
+		errs["smtp_addr"] = "must be host:port"
+	}
+	if _, err := mail.ParseAddress(c.SMTPFrom); err != nil {
+		errs["smtp_from"] = "must be an email address"
+	}
+	if (c.SMTPUsername == "") != (c.SMTPPassword == "") {
+		errs["smtp_password"] = "must be set together with smtp_username"
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validHostPattern — имя хоста или IP, возможно с "*." в начале, как их
+// сравнивает egress.
+func validHostPattern(host string) bool {
+	host = strings.TrimPrefix(host, "*.")
+	if host == "" || strings.ContainsAny(host, "*/:@ ") {
+		return net.ParseIP(host) != nil
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func digits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
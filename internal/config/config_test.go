@@ -0,0 +1,208 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// valid — конфигурация, которая проходит Validate.
+func valid() Config {
+	return Config{
+		PostgresDSN:           "postgres://localhost/goods",
+		ClickHouseDSN:         "tcp://localhost:9000",
+		RedisAddr:             "localhost:6379",
+		ElasticAddr:           "http://localhost:9200",
+		S3Endpoint:            "http://localhost:9100",
+		S3Bucket:              "goods",
+		HTTPAddr:              ":8080",
+		AdminAddr:             ":8081",
+		CacheTTL:              time.Minute,
+		CacheBackend:          CacheRedis,
+		SMTPAddr:              "localhost:25",
+		SMTPFrom:              "catalog@localhost",
+		GoodsLogBatchSize:     100,
+		GoodsLogFlushInterval: time.Second,
+	}
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+# connections
+postgres_dsn: "postgres://user:p#ss@db/goods"  # comment
+redis_db: 2
+cache_ttl: 90s
+egress_allow_private: true
+cache_backend: ring
+postgres_prepared_statements: false
+chaos_enabled: true
+chaos_fail_rate: 0.25
+chaos_max_delay: 200ms
+egress_allow_hosts: [api.example.com, "*.hooks.example.com"]
+redis_nodes:
+  - redis-1:6379
+  - 'redis-2:6379'
+smtp_from: catalog@example.com
+`)
+	cfg, err := Load(valid(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PostgresDSN != "postgres://user:p#ss@db/goods" {
+		t.Errorf("PostgresDSN = %q", cfg.PostgresDSN)
+	}
+	if cfg.RedisDB != 2 || cfg.CacheTTL != 90*time.Second || !cfg.EgressAllowPrivate {
+		t.Errorf("RedisDB = %d, CacheTTL = %s, EgressAllowPrivate = %v", cfg.RedisDB, cfg.CacheTTL, cfg.EgressAllowPrivate)
+	}
+	if want := []string{"api.example.com", "*.hooks.example.com"}; !reflect.DeepEqual(cfg.EgressAllowHosts, want) {
+		t.Errorf("EgressAllowHosts = %q, want %q", cfg.EgressAllowHosts, want)
+	}
+	if want := []string{"redis-1:6379", "redis-2:6379"}; !reflect.DeepEqual(cfg.RedisNodes, want) {
+		t.Errorf("RedisNodes = %q, want %q", cfg.RedisNodes, want)
+	}
+	if cfg.PostgresPreparedStatements {
+		t.Error("PostgresPreparedStatements = true, want false from the file")
+	}
+	if !cfg.ChaosEnabled || cfg.ChaosFailRate != 0.25 || cfg.ChaosMaxDelay != 200*time.Millisecond {
+		t.Errorf("ChaosEnabled = %v, ChaosFailRate = %v, ChaosMaxDelay = %s", cfg.ChaosEnabled, cfg.ChaosFailRate, cfg.ChaosMaxDelay)
+	}
+	if cfg.CacheBackend != CacheRing {
+		t.Errorf("CacheBackend = %q, want %q", cfg.CacheBackend, CacheRing)
+	}
+	if cfg.SMTPFrom != "catalog@example.com" {
+		t.Errorf("SMTPFrom = %q", cfg.SMTPFrom)
+	}
+}
+
+func TestLoadChaosFromEnv(t *testing.T) {
+	cfg, err := Load(valid(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ChaosEnabled {
+		t.Fatal("chaos must be off unless CHAOS_ENABLED is set")
+	}
+
+	t.Setenv("CHAOS_ENABLED", "true")
+	t.Setenv("CHAOS_DELAY_RATE", "0.5")
+	cfg, err = Load(valid(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.ChaosEnabled || cfg.ChaosDelayRate != 0.5 {
+		t.Errorf("ChaosEnabled = %v, ChaosDelayRate = %v", cfg.ChaosEnabled, cfg.ChaosDelayRate)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name, file, data, key string
+	}{
+		{"unknown json key", "config.json", `{"postgress_dsn": "x"}`, "postgress_dsn"},
+		{"unknown yaml key", "config.yml", "postgress_dsn: x\n", "postgress_dsn"},
+		{"bad int", "config.yaml", "redis_db: two\n", "redis_db"},
+		{"bad float", "config.yaml", "chaos_fail_rate: often\n", "chaos_fail_rate"},
+		{"bad bool", "config.yaml", "egress_allow_private: maybe\n", "egress_allow_private"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(valid(), writeFile(t, tt.file, tt.data))
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("err = %v, want Errors", err)
+			}
+			if _, ok := errs[tt.key]; !ok {
+				t.Errorf("errors %v have no %s", errs, tt.key)
+			}
+		})
+	}
+}
+
+func TestParseYAMLRejectsNesting(t *testing.T) {
+	for _, data := range []string{"s3:\n  bucket: goods\n", "s3: {bucket: goods}\n", "key: |\n  text\n", "- item\n"} {
+		if _, err := parseYAML([]byte(data)); err == nil {
+			t.Errorf("parseYAML(%q) succeeded", data)
+		}
+	}
+}
+
+func TestSetUnsupportedType(t *testing.T) {
+	var target struct{ F map[string]string }
+	if err := set(reflect.ValueOf(&target).Elem().Field(0), "a=b"); err == nil {
+		t.Fatal("want error for map field")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key := "k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32))
+	tests := []struct {
+		name   string
+		mutate func(c *Config)
+		key    string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"full", func(c *Config) {
+			c.EncryptionKeys = key
+			c.EgressProxy = "socks5://proxy:1080"
+			c.EgressAllowHosts = []string{"*.example.com", "10.0.0.1"}
+			c.TelegramToken = "123456:secret"
+			c.SMTPUsername, c.SMTPPassword = "user", "pass"
+		}, ""},
+		{"postgres_dsn", func(c *Config) { c.PostgresDSN = "" }, "postgres_dsn"},
+		{"s3_bucket", func(c *Config) { c.S3Bucket = "" }, "s3_bucket"},
+		{"http_addr", func(c *Config) { c.HTTPAddr = "8080" }, "http_addr"},
+		{"admin_addr", func(c *Config) { c.AdminAddr = "" }, "admin_addr"},
+		{"redis_db", func(c *Config) { c.RedisDB = -1 }, "redis_db"},
+		{"cache_ttl", func(c *Config) { c.CacheTTL = 0 }, "cache_ttl"},
+		{"ring", func(c *Config) { c.CacheBackend, c.RedisNodes = CacheRing, []string{"redis-1:6379"} }, ""},
+		{"memory", func(c *Config) { c.CacheBackend = CacheMemory }, ""},
+		{"cache_backend", func(c *Config) { c.CacheBackend = "memcached" }, "cache_backend"},
+		{"ring without nodes", func(c *Config) { c.CacheBackend = CacheRing }, "redis_nodes"},
+		{"batch size", func(c *Config) { c.GoodsLogBatchSize = 0 }, "goods_log_batch_size"},
+		{"flush interval", func(c *Config) { c.GoodsLogFlushInterval = -time.Second }, "goods_log_flush_interval"},
+		{"chaos_delay_rate", func(c *Config) { c.ChaosDelayRate = 1.5 }, "chaos_delay_rate"},
+		{"chaos_fail_rate", func(c *Config) { c.ChaosFailRate = -0.1 }, "chaos_fail_rate"},
+		{"chaos_max_delay", func(c *Config) { c.ChaosMaxDelay = -time.Second }, "chaos_max_delay"},
+		{"short key", func(c *Config) { c.EncryptionKeys = "k1:c2hvcnQ=" }, "encryption_keys"},
+		{"key without id", func(c *Config) { c.EncryptionKeys = "c2hvcnQ=" }, "encryption_keys"},
+		{"proxy scheme", func(c *Config) { c.EgressProxy = "ftp://proxy:21" }, "egress_proxy"},
+		{"proxy without host", func(c *Config) { c.EgressProxy = "http://" }, "egress_proxy"},
+		{"allow host with port", func(c *Config) { c.EgressAllowHosts = []string{"example.com:443"} }, "egress_allow_hosts"},
+		{"deny host empty label", func(c *Config) { c.EgressDenyHosts = []string{"a..b"} }, "egress_deny_hosts"},
+		{"deny bare wildcard", func(c *Config) { c.EgressDenyHosts = []string{"*."} }, "egress_deny_hosts"},
+		{"telegram without secret", func(c *Config) { c.TelegramToken = "123456:" }, "telegram_token"},
+		{"telegram bot id", func(c *Config) { c.TelegramToken = "bot:secret" }, "telegram_token"},
+		{"smtp_addr", func(c *Config) { c.SMTPAddr = "localhost" }, "smtp_addr"},
+		{"smtp_from", func(c *Config) { c.SMTPFrom = "catalog" }, "smtp_from"},
+		{"smtp password only", func(c *Config) { c.SMTPPassword = "pass" }, "smtp_password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(&cfg)
+			err := cfg.Validate()
+			if tt.key == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			errs, ok := err.(Errors)
+			if !ok || len(errs) != 1 || errs[tt.key] == "" {
+				t.Fatalf("err = %v, want only %s", err, tt.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML разбирает плоский YAML-документ, которого достаточно для
+// Config: ключи верхнего уровня со скалярами, списки в строку ([a, b]) или
+// блоком ("- a"), строки в кавычках и комментарии. Вложенные объекты и
+// многострочные скаляры — ошибка. Значения возвращаются в том же виде, что
+// и после json.Unmarshal: строки, nil и []interface{}.
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	var list string // ключ, к которому относятся строки "- item"
+
+	for i, line := range strings.Split(string(data), "\n") {
+		n := i + 1
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if item, ok := strings.CutPrefix(trimmed, "-"); ok && (item == "" || item[0] == ' ') {
+			if list == "" {
+				return nil, fmt.Errorf("line %d: list item without a key", n)
+			}
+			value, err := yamlScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			items, _ := values[list].([]interface{})
+			values[list] = append(items, value)
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", n)
+		}
+
+		key, raw, ok := strings.Cut(line, ":")
+		if !ok || (raw != "" && raw[0] != ' ' && raw[0] != '\t') {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		key = strings.TrimSpace(key)
+		if _, dup := values[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n, key)
+		}
+		raw = strings.TrimSpace(raw)
+
+		list = ""
+		switch {
+		case raw == "":
+			// Значение — список следующими строками или null.
+			list = key
+			values[key] = nil
+		case raw[0] == '[':
+			items, err := yamlFlowList(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			values[key] = items
+		case raw[0] == '{' || raw[0] == '|' || raw[0] == '>' || raw[0] == '&' || raw[0] == '*':
+			return nil, fmt.Errorf("line %d: unsupported value %q", n, raw)
+		default:
+			value, err := yamlScalar(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// stripComment отрезает комментарий: # в начале строки или после пробела,
+// вне кавычек.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func yamlFlowList(raw string) ([]interface{}, error) {
+	if !strings.HasSuffix(raw, "]") {
+		return nil, fmt.Errorf("unterminated list %q", raw)
+	}
+	inner := strings.TrimSpace(raw[1 : len(raw)-1])
+	items := []interface{}{}
+	if inner == "" {
+		return items, nil
+	}
+	for _, part := range splitFlow(inner) {
+		value, err := yamlScalar(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+// splitFlow делит элементы списка по запятым вне кавычек.
+func splitFlow(s string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func yamlScalar(raw string) (interface{}, error) {
+	switch {
+	case raw == "" || raw == "~" || raw == "null" || raw == "Null" || raw == "NULL":
+		return nil, nil
+	case raw[0] == '"':
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("bad quoted string %s", raw)
+		}
+		return s, nil
+	case raw[0] == '\'':
+		if len(raw) < 2 || raw[len(raw)-1] != '\'' {
+			return nil, fmt.Errorf("bad quoted string %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	case raw[0] == '[' || raw[0] == '{':
+		return nil, fmt.Errorf("nested collections are not supported")
+	}
+	return raw, nil
+}
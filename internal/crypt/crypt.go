@@ -0,0 +1,134 @@
+// Package crypt шифрует отдельные текстовые поля AES-256-GCM. Зашифрованное
+// значение хранит id ключа, поэтому после ротации старые записи читаются
+// прежним ключом, пока их не перешифруют текущим.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix начинает каждое зашифрованное значение. Формат значения:
+// enc:<key id>:<base64(nonce || ciphertext)>; открытый текст с таким
+// началом хранить нельзя — его прочитают как шифротекст.
+const Prefix = "enc:"
+
+var (
+	ErrNoKeys     = errors.New("crypt: no encryption keys configured")
+	ErrUnknownKey = errors.New("crypt: unknown key id")
+	ErrMalformed  = errors.New("crypt: malformed ciphertext")
+)
+
+// Keyring — набор ключей; новые значения шифруются текущим. Нулевой
+// *Keyring допустим: он пропускает открытый текст и не умеет шифровать.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// Parse разбирает список ключей вида "id1:base64key1,id2:base64key2";
+// первый ключ становится текущим. Ключи — 32 байта для AES-256.
+func Parse(spec string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(part, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("crypt: key %q: expected id:base64key", part)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("crypt: key %q: %w", id, err)
+		}
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("crypt: key %q: want 32 bytes, got %d", id, len(raw))
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("crypt: duplicate key id %q", id)
+		}
+		k.keys[id] = aead
+		if k.current == "" {
+			k.current = id
+		}
+	}
+	if k.current == "" {
+		return nil, ErrNoKeys
+	}
+	return k, nil
+}
+
+// Enabled сообщает, можно ли шифровать.
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.current != ""
+}
+
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if !k.Enabled() {
+		return "", ErrNoKeys
+	}
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + k.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение; незашифрованное возвращается как есть.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoKeys
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !ok {
+		return "", ErrMalformed
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Current сообщает, что значение зашифровано текущим ключом.
+func (k *Keyring) Current(value string) bool {
+	return k.Enabled() && strings.HasPrefix(value, Prefix+k.current+":")
+}
+
+// CurrentPrefix — префикс значений, зашифрованных текущим ключом; годится
+// для поиска записей, которые пора перешифровать.
+func (k *Keyring) CurrentPrefix() string {
+	return Prefix + k.current + ":"
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
@@ -0,0 +1,165 @@
+package crypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func key(b byte) string {
+	raw := make([]byte, 32)
+	for i := range raw {
+		raw[i] = b
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		ok   bool
+	}{
+		{"one key", "k1:" + key(1), true},
+		{"rotation", " k2:" + key(2) + " , k1:" + key(1), true},
+		{"empty", " , ", false},
+		{"no id", ":" + key(1), false},
+		{"no separator", key(1), false},
+		{"bad base64", "k1:not base64", false},
+		{"short key", "k1:" + base64.StdEncoding.EncodeToString(make([]byte, 16)), false},
+		{"duplicate id", "k1:" + key(1) + ",k1:" + key(2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := Parse(tt.spec)
+			if tt.ok != (err == nil) {
+				t.Fatalf("Parse(%q) error = %v, want ok %v", tt.spec, err, tt.ok)
+			}
+			if tt.ok && !k.Enabled() {
+				t.Error("parsed keyring is not enabled")
+			}
+		})
+	}
+	if _, err := Parse(""); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("Parse(\"\") error = %v, want ErrNoKeys", err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := Parse("k1:" + key(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.Encrypt("green tea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, Prefix+"k1:") || strings.Contains(sealed, "green tea") {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	again, _ := k.Encrypt("green tea")
+	if again == sealed {
+		t.Error("two encryptions share a nonce")
+	}
+	if plain, err := k.Decrypt(sealed); err != nil || plain != "green tea" {
+		t.Fatalf("Decrypt = %q, %v", plain, err)
+	}
+	if plain, err := k.Decrypt("plain text"); err != nil || plain != "plain text" {
+		t.Errorf("Decrypt of plain text = %q, %v", plain, err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, err := Parse("k1:" + key(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := old.Encrypt("green tea")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := Parse("k2:" + key(2) + ",k1:" + key(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Current(sealed) {
+		t.Error("value sealed with the previous key is reported current")
+	}
+	if plain, err := rotated.Decrypt(sealed); err != nil || plain != "green tea" {
+		t.Fatalf("Decrypt with the previous key = %q, %v", plain, err)
+	}
+	resealed, err := rotated.Encrypt("green tea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rotated.Current(resealed) || !strings.HasPrefix(resealed, rotated.CurrentPrefix()) {
+		t.Errorf("resealed = %q, want prefix %q", resealed, rotated.CurrentPrefix())
+	}
+
+	withoutOld, err := Parse("k2:" + key(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := withoutOld.Decrypt(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt after the key is dropped: %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	k, err := Parse("k1:" + key(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.Encrypt("green tea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, Prefix+"k1:"))
+	raw[len(raw)-1] ^= 1
+	tampered := Prefix + "k1:" + base64.StdEncoding.EncodeToString(raw)
+
+	other, err := Parse("k1:" + key(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		k     *Keyring
+		value string
+		want  error
+	}{
+		{"tampered", k, tampered, nil},
+		{"wrong key", other, sealed, nil},
+		{"no key id", k, Prefix + "abc", ErrMalformed},
+		{"bad base64", k, Prefix + "k1:!!!", ErrMalformed},
+		{"shorter than nonce", k, Prefix + "k1:" + base64.StdEncoding.EncodeToString([]byte("x")), ErrMalformed},
+		{"nil keyring", nil, sealed, ErrNoKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.k.Decrypt(tt.value)
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilKeyring(t *testing.T) {
+	var k *Keyring
+	if k.Enabled() {
+		t.Error("nil keyring is enabled")
+	}
+	if _, err := k.Encrypt("x"); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("Encrypt err = %v, want ErrNoKeys", err)
+	}
+	if plain, err := k.Decrypt("x"); err != nil || plain != "x" {
+		t.Errorf("Decrypt = %q, %v", plain, err)
+	}
+}
@@ -0,0 +1,33 @@
+// Package deps описывает узкие интерфейсы внешних клиентов, которыми
+// пользуются обработчики, чтобы в тестах их можно было подменить моками из
+// deps/mocks. *redis.Client и *nats.Conn реализуют их без обёрток.
+package deps
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+)
+
+//go:generate go run go.uber.org/mock/mockgen -destination=mocks/deps.go -package=mocks hezzl-test/internal/deps Cache,Publisher
+
+type Cache interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	GetDel(ctx context.Context, key string) *redis.StringCmd
+	IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+}
+
+type Publisher interface {
+	PublishMsg(msg *nats.Msg) error
+}
+
+var (
+	_ Cache     = (*redis.Client)(nil)
+	_ Publisher = (*nats.Conn)(nil)
+)
@@ -0,0 +1,24 @@
+package deps
+
+import (
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DroppedMessages считает сообщения, отброшенные NopPublisher; её нужно
+// зарегистрировать в Prometheus вместе с остальными метриками.
+var DroppedMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nats_dropped_messages_total",
+	Help: "Messages dropped because NATS is not configured.",
+}, []string{"subject"})
+
+// NopPublisher заменяет NATS, когда брокер не настроен: сообщения никуда не
+// уходят, а только учитываются в DroppedMessages.
+type NopPublisher struct{}
+
+func (NopPublisher) PublishMsg(msg *nats.Msg) error {
+	DroppedMessages.WithLabelValues(msg.Subject).Inc()
+	return nil
+}
+
+var _ Publisher = NopPublisher{}
@@ -0,0 +1,7 @@
+//go:build tools
+
+package deps
+
+import (
+	_ "go.uber.org/mock/mockgen"
+)
@@ -0,0 +1,171 @@
+package digest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SMTPConfig struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+type projectSummary struct {
+	events map[string]int
+	goods  []string
+}
+
+// Digest собирает события товаров за сутки из ClickHouse и рассылает
+// сводку подписчикам каждого проекта.
+type Digest struct {
+	db         *sql.DB
+	clickhouse *sql.DB
+	smtp       SMTPConfig
+}
+
+func New(db, clickhouse *sql.DB, smtpConfig SMTPConfig) *Digest {
+	return &Digest{db: db, clickhouse: clickhouse, smtp: smtpConfig}
+}
+
+// Run отправляет сводку за сутки, начинающиеся в day (UTC).
+func (d *Digest) Run(ctx context.Context, day time.Time) error {
+	from := day.UTC().Truncate(24 * time.Hour)
+	to := from.Add(24 * time.Hour)
+
+	subscribers, err := d.subscribers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for projectID, emails := range subscribers {
+		summary, err := d.summary(ctx, projectID, from, to)
+		if err != nil {
+			return err
+		}
+		if len(summary.events) == 0 {
+			continue
+		}
+
+		subject := fmt.Sprintf("Project %d: goods changes for %s", projectID, from.Format("2006-01-02"))
+		if err := d.send(emails, subject, render(projectID, from, summary)); err != nil {
+			log.Printf("digest: project %d: %v", projectID, err)
+		}
+	}
+	return nil
+}
+
+func (d *Digest) subscribers(ctx context.Context) (map[int][]string, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT project_id, email FROM digest_subscriptions ORDER BY project_id, email")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscribers := make(map[int][]string)
+	for rows.Next() {
+		var projectID int
+		var email string
+		if err := rows.Scan(&projectID, &email); err != nil {
+			return nil, err
+		}
+		subscribers[projectID] = append(subscribers[projectID], email)
+	}
+	return subscribers, rows.Err()
+}
+
+func (d *Digest) summary(ctx context.Context, projectID int, from, to time.Time) (projectSummary, error) {
+	summary := projectSummary{events: make(map[string]int)}
+
+	rows, err := d.clickhouse.QueryContext(ctx, `SELECT EventType, count()
+		FROM goods_log
+		WHERE ProjectId = ? AND EventTime >= ? AND EventTime < ?
+		GROUP BY EventType`,
+		projectID, from, to)
+	if err != nil {
+		return summary, err
+	}
+	for rows.Next() {
+		var eventType string
+		var count int
+		if err := rows.Scan(&eventType, &count); err != nil {
+			rows.Close()
+			return summary, err
+		}
+		summary.events[eventType] = count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return summary, err
+	}
+
+	rows, err = d.clickhouse.QueryContext(ctx, `SELECT argMax(Name, EventTime)
+		FROM goods_log
+		WHERE ProjectId = ? AND EventTime >= ? AND EventTime < ?
+		GROUP BY Id
+		ORDER BY Id
+		LIMIT 50`,
+		projectID, from, to)
+	if err != nil {
+		return summary, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return summary, err
+		}
+		summary.goods = append(summary.goods, name)
+	}
+	return summary, rows.Err()
+}
+
+func (d *Digest) send(to []string, subject, body string) error {
+	var auth smtp.Auth
+	if d.smtp.Username != "" {
+		host := d.smtp.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", d.smtp.Username, d.smtp.Password, host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", d.smtp.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return smtp.SendMail(d.smtp.Addr, auth, d.smtp.From, to, []byte(msg.String()))
+}
+
+func render(projectID int, day time.Time, summary projectSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Goods activity in project %d on %s\n\n", projectID, day.Format("2006-01-02"))
+
+	eventTypes := make([]string, 0, len(summary.events))
+	for eventType := range summary.events {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	for _, eventType := range eventTypes {
+		fmt.Fprintf(&b, "  %-20s %d\n", eventType, summary.events[eventType])
+	}
+
+	if len(summary.goods) > 0 {
+		b.WriteString("\nChanged goods:\n")
+		for _, name := range summary.goods {
+			fmt.Fprintf(&b, "  - %s\n", name)
+		}
+	}
+	return b.String()
+}
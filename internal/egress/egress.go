@@ -0,0 +1,136 @@
+// Package egress — общий транспорт исходящих HTTP-запросов к адресам,
+// которые задают пользователи (вебхуки уведомлений, импорт по ссылке):
+// прокси, списки разрешённых и запрещённых хостов и защита от SSRF.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrForbidden = errors.New("egress: destination is not allowed")
+
+type Config struct {
+	// Proxy — адрес HTTP-прокси; пустой берёт HTTPS_PROXY, HTTP_PROXY и
+	// NO_PROXY из окружения.
+	Proxy string
+	// Allow ограничивает запросы перечисленными хостами, если не пуст; Deny
+	// запрещает хосты в любом случае. "*.example.com" совпадает с любым
+	// поддоменом example.com.
+	Allow []string
+	Deny  []string
+	// AllowPrivate разрешает адреса частных сетей. Loopback, link-local
+	// (в том числе метаданные облака 169.254.169.254) и multicast
+	// запрещены всегда.
+	AllowPrivate bool
+}
+
+// cgnat — 100.64.0.0/10, где у части облаков живут сервисы метаданных.
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// NewTransport возвращает транспорт, который проверяет хост каждого
+// запроса, включая переходы по редиректам. Без прокси проверяются и
+// адреса, в которые хост разрешился при подключении, чтобы DNS не увёл
+// запрос во внутреннюю сеть; через прокси это остаётся на прокси.
+func NewTransport(cfg Config) (http.RoundTripper, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("egress: parse proxy: %w", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d := *dialer
+		if !proxied(ctx) {
+			d.Control = func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				return cfg.checkIP(net.ParseIP(host))
+			}
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	return &transport{cfg: cfg, next: t, proxy: proxy}, nil
+}
+
+type transport struct {
+	cfg   Config
+	next  http.RoundTripper
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+type proxiedKey struct{}
+
+func proxied(ctx context.Context) bool {
+	v, _ := ctx.Value(proxiedKey{}).(bool)
+	return v
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.cfg.checkHost(req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	if u, err := t.proxy(req); err == nil && u != nil {
+		req = req.WithContext(context.WithValue(req.Context(), proxiedKey{}, true))
+	}
+	return t.next.RoundTrip(req)
+}
+
+func (c Config) checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		if err := c.checkIP(ip); err != nil {
+			return err
+		}
+	} else if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbidden, host)
+	}
+
+	if matchHost(c.Deny, host) || (len(c.Allow) > 0 && !matchHost(c.Allow, host)) {
+		return fmt.Errorf("%w: %s", ErrForbidden, host)
+	}
+	return nil
+}
+
+func (c Config) checkIP(ip net.IP) error {
+	if ip == nil {
+		return fmt.Errorf("%w: unresolved address", ErrForbidden)
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrForbidden, ip)
+	}
+	if !c.AllowPrivate && (ip.IsPrivate() || cgnat.Contains(ip)) {
+		return fmt.Errorf("%w: %s", ErrForbidden, ip)
+	}
+	return nil
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if suffix, ok := strings.CutPrefix(p, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package egress
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		host    string
+		allowed bool
+	}{
+		{"public ip", Config{}, "93.184.216.34", true},
+		{"public name", Config{}, "example.com", true},
+		{"loopback", Config{}, "127.0.0.1", false},
+		{"loopback v6", Config{}, "::1", false},
+		{"metadata", Config{}, "169.254.169.254", false},
+		{"unspecified", Config{}, "0.0.0.0", false},
+		{"multicast", Config{}, "224.0.0.1", false},
+		{"private", Config{}, "10.1.2.3", false},
+		{"private allowed", Config{AllowPrivate: true}, "10.1.2.3", true},
+		{"cgnat", Config{}, "100.100.100.200", false},
+		{"cgnat allowed", Config{AllowPrivate: true}, "100.100.100.200", true},
+		{"loopback with private allowed", Config{AllowPrivate: true}, "127.0.0.1", false},
+		{"localhost", Config{}, "localhost", false},
+		{"localhost subdomain", Config{}, "api.LOCALHOST.", false},
+		{"allow match", Config{Allow: []string{"*.example.com"}}, "hooks.example.com", true},
+		{"allow miss", Config{Allow: []string{"*.example.com"}}, "example.org", false},
+		{"allow exact", Config{Allow: []string{"Example.com"}}, "example.com", true},
+		{"deny match", Config{Deny: []string{"*.example.com"}}, "hooks.example.com", false},
+		{"deny wins over allow", Config{Allow: []string{"*.example.com"}, Deny: []string{"internal.example.com"}}, "internal.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.checkHost(tt.host)
+			if tt.allowed && err != nil {
+				t.Fatalf("checkHost(%q) = %v", tt.host, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbidden) {
+				t.Fatalf("checkHost(%q) = %v, want ErrForbidden", tt.host, err)
+			}
+		})
+	}
+}
+
+func TestCheckIPUnresolved(t *testing.T) {
+	if err := (Config{AllowPrivate: true}).checkIP(net.ParseIP("not an ip")); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestTransportRejectsLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the server")
+	}))
+	defer srv.Close()
+
+	rt, err := NewTransport(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: rt}).Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden", err)
+	}
+}
+
+func TestTransportThroughProxy(t *testing.T) {
+	// Прокси слушает loopback: через прокси адрес подключения не
+	// проверяется, а хост запроса и редиректов — проверяется.
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		http.Redirect(w, r, "http://127.0.0.1/admin", http.StatusFound)
+	}))
+	defer proxy.Close()
+
+	rt, err := NewTransport(Config{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: rt}).Get("http://hooks.example.com/event")
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden for the redirect", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "hooks.example.com" {
+		t.Errorf("proxy saw %v, want one request to hooks.example.com", hosts)
+	}
+
+	if _, err := NewTransport(Config{Proxy: "://bad"}); err == nil {
+		t.Error("NewTransport accepted a malformed proxy URL")
+	}
+}
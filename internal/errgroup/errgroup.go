@@ -0,0 +1,49 @@
+// Package errgroup запускает связанные задачи параллельно и возвращает
+// первую ошибку, отменяя контекст остальных. API повторяет
+// golang.org/x/sync/errgroup в той части, которая нужна сервису.
+package errgroup
+
+import (
+	"context"
+	"sync"
+)
+
+type Group struct {
+	cancel func()
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext возвращает группу и контекст, который отменяется, когда
+// первая задача вернёт ошибку или завершится Wait.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go запускает fn в отдельной горутине.
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				if g.cancel != nil {
+					g.cancel()
+				}
+			})
+		}
+	}()
+}
+
+// Wait ждёт все задачи и возвращает первую ошибку.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+	return g.err
+}
@@ -0,0 +1,48 @@
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitReturnsFirstErrorAndCancels(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	failed := errors.New("failed")
+
+	g.Go(func() error { return failed })
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return errors.New("context was not cancelled")
+		}
+	})
+
+	if err := g.Wait(); err != failed {
+		t.Fatalf("Wait() = %v, want %v", err, failed)
+	}
+}
+
+func TestWaitWithoutErrors(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	results := make([]int, 3)
+	for i := range results {
+		i := i
+		g.Go(func() error {
+			results[i] = i + 1
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if results[0]+results[1]+results[2] != 6 {
+		t.Fatalf("results = %v", results)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("context is not cancelled after Wait")
+	}
+}
@@ -0,0 +1,58 @@
+// Package errs описывает ошибки предметной области. Хранилище и обработка
+// запросов возвращают их, а в HTTP-ответ их переводит response.Fail: вид
+// ошибки задаёт статус и код, ключ — сообщение.
+//
+// Вид проверяется через errors.Is, например errors.Is(err, errs.ErrNotFound);
+// конкретная ошибка — сравнением с её переменной.
+package errs
+
+import "errors"
+
+// Виды ошибок.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+)
+
+// Error — ошибка вида Kind с ключом сообщения Key; Err — причина, если
+// она есть.
+type Error struct {
+	Kind error
+	Key  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Key
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(key string) error {
+	return &Error{Kind: ErrNotFound, Key: key}
+}
+
+func Conflict(key string) error {
+	return &Error{Kind: ErrConflict, Key: key}
+}
+
+func Forbidden(key string) error {
+	return &Error{Kind: ErrForbidden, Key: key}
+}
+
+// Validation оборачивает ошибку проверки входных данных; ошибки по полям
+// (метод Fields, как у bind.Errors) попадают в details ответа.
+func Validation(err error) error {
+	return &Error{Kind: ErrValidation, Key: "errors.request.invalid", Err: err}
+}
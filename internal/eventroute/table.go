@@ -0,0 +1,201 @@
+// Package eventroute решает по правилам арендатора, в какие темы уходит
+// исходящее событие и не отбрасывается ли оно, чтобы шумные события можно
+// было заглушить без выкладки. Правила хранятся в таблице event_routes и
+// перечитываются на лету.
+package eventroute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Действия правила.
+const (
+	ActionDrop  = "drop"
+	ActionRoute = "route"
+	ActionCopy  = "copy"
+)
+
+// Dropped считает события, отброшенные правилами; её нужно
+// зарегистрировать в Prometheus вместе с остальными метриками.
+var Dropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "event_route_dropped_total",
+	Help: "Outgoing events dropped by event routing rules.",
+}, []string{"subject"})
+
+// Rule — правило для событий темы Subject проекта ProjectID; пустая тема и
+// nil проект подходят к любым. Target — тема для route и copy.
+type Rule struct {
+	ID        int       `json:"id"`
+	Subject   string    `json:"subject"`
+	ProjectID *int      `json:"project_id"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate проверяет действие и темы правила: в них не должно быть
+// пробелов и подстановочных знаков NATS.
+func (r Rule) Validate() error {
+	switch r.Action {
+	case ActionDrop:
+		if r.Target != "" {
+			return fmt.Errorf("target is not allowed for %s", r.Action)
+		}
+	case ActionRoute, ActionCopy:
+		if r.Target == "" {
+			return fmt.Errorf("target is required for %s", r.Action)
+		}
+	default:
+		return fmt.Errorf("invalid action %q", r.Action)
+	}
+	for _, subject := range []string{r.Subject, r.Target} {
+		if strings.ContainsAny(subject, " \t\r\n*>") || strings.HasPrefix(subject, ".") || strings.HasSuffix(subject, ".") {
+			return fmt.Errorf("invalid subject %q", subject)
+		}
+	}
+	return nil
+}
+
+func (r Rule) specificity() int {
+	s := 0
+	if r.ProjectID != nil {
+		s += 2
+	}
+	if r.Subject != "" {
+		s++
+	}
+	return s
+}
+
+func (r Rule) matches(subject string, projectID int) bool {
+	return (r.Subject == "" || r.Subject == subject) && (r.ProjectID == nil || *r.ProjectID == projectID)
+}
+
+// Table держит правила всех арендаторов в памяти. Событие обрабатывает
+// самое точное подходящее правило, из равных — созданное раньше; без
+// правил событие уходит в свою тему.
+type Table struct {
+	mu    sync.RWMutex
+	rules map[int][]Rule
+}
+
+func New() *Table {
+	return &Table{rules: make(map[int][]Rule)}
+}
+
+// Subjects возвращает темы, в которые нужно опубликовать событие subject с
+// телом data; пустой список означает, что событие отброшено. Проект
+// события берётся из поля project_id или projectId тела, как у
+// уведомлений.
+func (t *Table) Subjects(tenantID int, subject string, data []byte) []string {
+	t.mu.RLock()
+	rules := t.rules[tenantID]
+	t.mu.RUnlock()
+	if len(rules) == 0 {
+		return []string{subject}
+	}
+
+	projectID := 0
+	for _, r := range rules {
+		if r.ProjectID != nil {
+			projectID = projectOf(data)
+			break
+		}
+	}
+	for _, r := range rules {
+		if !r.matches(subject, projectID) {
+			continue
+		}
+		switch r.Action {
+		case ActionDrop:
+			Dropped.WithLabelValues(subject).Inc()
+			return nil
+		case ActionRoute:
+			return []string{r.Target}
+		case ActionCopy:
+			return []string{subject, r.Target}
+		}
+	}
+	return []string{subject}
+}
+
+func projectOf(data []byte) int {
+	var e struct {
+		ProjectID      int `json:"project_id"`
+		ProjectIDCamel int `json:"projectId"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return 0
+	}
+	if e.ProjectID != 0 {
+		return e.ProjectID
+	}
+	return e.ProjectIDCamel
+}
+
+// Load читает правила из event_routes и заменяет ими текущие.
+func (t *Table) Load(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT id, tenant_id, subject, project_id, action, target, created_at
+		FROM event_routes ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rules := make(map[int][]Rule)
+	for rows.Next() {
+		var r Rule
+		var tenantID int
+		if err := rows.Scan(&r.ID, &tenantID, &r.Subject, &r.ProjectID, &r.Action, &r.Target, &r.CreatedAt); err != nil {
+			return err
+		}
+		rules[tenantID] = append(rules[tenantID], r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// Сначала правила для темы и проекта, затем для проекта, для темы и
+	// общие; среди равных — в порядке создания.
+	for _, list := range rules {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].specificity() > list[j].specificity() })
+	}
+
+	t.mu.Lock()
+	t.rules = rules
+	t.mu.Unlock()
+	return nil
+}
+
+// Watch перечитывает правила раз в interval, пока не отменён ctx; так
+// правки с других экземпляров доходят до этого.
+func (t *Table) Watch(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := t.Load(ctx, db); err != nil {
+			log.Printf("eventroute: reload rules: %v", err)
+		}
+	}
+}
+
+// Rules — правила арендатора в порядке применения.
+func (t *Table) Rules(tenantID int) []Rule {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]Rule{}, t.rules[tenantID]...)
+}
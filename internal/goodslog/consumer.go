@@ -0,0 +1,353 @@
+// Package goodslog пишет события товаров из NATS в журнал goods_log в
+// ClickHouse, по которому строятся аналитика, asOf и история товара.
+//
+// События копятся в буфере и вставляются пачкой, когда набирается
+// BatchSize записей или проходит FlushInterval. Подписка идёт через очередь
+// queueGroup, поэтому при нескольких экземплярах сервиса каждое событие
+// записывает один из них.
+//
+// Метрики Pending, FlushDuration, InsertFailures и Dropped нужно
+// зарегистрировать в Prometheus вместе с остальными; Health отдаёт
+// состояние для /consume/healthz.
+package goodslog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"hezzl-test/internal/clock"
+	"hezzl-test/internal/tenant"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const queueGroup = "goods_log"
+
+// maxBatches — сколько пачек держит буфер, пока ClickHouse недоступен;
+// сверх этого самые старые записи отбрасываются.
+const maxBatches = 10
+
+var (
+	Pending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "goods_log_pending_records",
+		Help: "Goods log records buffered until the next insert into ClickHouse.",
+	})
+	FlushDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "goods_log_flush_duration_seconds",
+		Help:    "Time spent inserting the buffered goods log records.",
+		Buckets: prometheus.DefBuckets,
+	})
+	InsertFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "goods_log_insert_failures_total",
+		Help: "Batch inserts into goods_log that failed and were kept for a retry.",
+	})
+	// Dropped — события, не попавшие в журнал: invalid — не разобранные,
+	// overflow — вытесненные из переполненного буфера.
+	Dropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "goods_log_dropped_events_total",
+		Help: "Events that never reached goods_log.",
+	}, []string{"reason"})
+)
+
+var subjects = []string{"new_good_created", "good_updated", "good_deleted", "goods_reordered"}
+
+type Config struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	// Clock задаёт EventTime событий без occurred_at — опубликованных до
+	// того, как его стали добавлять; nil — системные часы.
+	Clock clock.Clock
+}
+
+// Record — строка goods_log.
+type Record struct {
+	ID          int
+	ProjectID   int
+	Name        string
+	Description string
+	Priority    int
+	Removed     bool
+	Diff        string
+	Editor      string
+	EventType   string
+	EventTime   time.Time
+}
+
+type event struct {
+	ID          int             `json:"id"`
+	ProjectID   int             `json:"project_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Priority    int             `json:"priority"`
+	Removed     bool            `json:"removed"`
+	Diff        json.RawMessage `json:"diff"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+// reordered — событие goods_reordered; в журнал каждый переставленный товар
+// пишется отдельной строкой только с приоритетом.
+type reordered struct {
+	ProjectID  int `json:"project_id"`
+	Priorities []struct {
+		ID       int `json:"id"`
+		Priority int `json:"priority"`
+	} `json:"priorities"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type Consumer struct {
+	clickhouse *sql.DB
+	cfg        Config
+	subs       []*nats.Subscription
+
+	mu        sync.Mutex
+	pending   []Record
+	lastFlush time.Time
+	lastError string
+
+	full chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewConsumer(clickhouse *sql.DB, cfg Config) *Consumer {
+	if cfg.Clock == nil {
+		cfg.Clock = clock.System{}
+	}
+	return &Consumer{
+		clickhouse: clickhouse,
+		cfg:        cfg,
+		full:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func (c *Consumer) Start(natsConn *nats.Conn) error {
+	for _, subject := range subjects {
+		sub, err := natsConn.QueueSubscribe(subject, queueGroup, c.handle)
+		if err != nil {
+			c.unsubscribe()
+			return err
+		}
+		c.subs = append(c.subs, sub)
+	}
+	go c.run()
+	return nil
+}
+
+// Stop отписывается и записывает то, что осталось в буфере.
+func (c *Consumer) Stop() {
+	c.unsubscribe()
+	close(c.stop)
+	<-c.done
+}
+
+func (c *Consumer) unsubscribe() {
+	for _, sub := range c.subs {
+		sub.Unsubscribe()
+	}
+	c.subs = nil
+}
+
+func (c *Consumer) handle(msg *nats.Msg) {
+	records, err := decode(msg, c.cfg.Clock)
+	if err != nil {
+		log.Printf("goods_log: skip %s event: %s", msg.Subject, msg.Data)
+		Dropped.WithLabelValues("invalid").Inc()
+		return
+	}
+
+	c.mu.Lock()
+	c.pending = append(c.pending, records...)
+	if dropped := len(c.pending) - maxBatches*c.cfg.BatchSize; dropped > 0 {
+		log.Printf("goods_log: buffer full, %d oldest events dropped", dropped)
+		Dropped.WithLabelValues("overflow").Add(float64(dropped))
+		c.pending = c.pending[dropped:]
+	}
+	n := len(c.pending)
+	Pending.Set(float64(n))
+	c.mu.Unlock()
+
+	if n >= c.cfg.BatchSize {
+		select {
+		case c.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// decode разбирает событие в строки журнала. EventTime — время события из
+// occurred_at, а если его нет — текущее время clk.
+func decode(msg *nats.Msg, clk clock.Clock) ([]Record, error) {
+	editor := msg.Header.Get(tenant.NATSUserHeader)
+
+	if msg.Subject == "goods_reordered" {
+		var e reordered
+		if err := json.Unmarshal(msg.Data, &e); err != nil {
+			return nil, err
+		}
+		if e.ProjectID == 0 {
+			return nil, errors.New("no project_id")
+		}
+		at := eventTime(e.OccurredAt, clk)
+		records := make([]Record, len(e.Priorities))
+		for i, p := range e.Priorities {
+			records[i] = Record{ID: p.ID, ProjectID: e.ProjectID, Priority: p.Priority, Editor: editor, EventType: msg.Subject, EventTime: at}
+		}
+		return records, nil
+	}
+
+	var e event
+	if err := json.Unmarshal(msg.Data, &e); err != nil {
+		return nil, err
+	}
+	if e.ID == 0 {
+		return nil, errors.New("no id")
+	}
+	record := Record{
+		ID:          e.ID,
+		ProjectID:   e.ProjectID,
+		Name:        e.Name,
+		Description: e.Description,
+		Priority:    e.Priority,
+		Removed:     e.Removed,
+		Editor:      editor,
+		EventType:   msg.Subject,
+		EventTime:   eventTime(e.OccurredAt, clk),
+	}
+	if len(e.Diff) > 0 && string(e.Diff) != "null" {
+		record.Diff = string(e.Diff)
+	}
+	return []Record{record}, nil
+}
+
+func eventTime(occurredAt time.Time, clk clock.Clock) time.Time {
+	if occurredAt.IsZero() {
+		return clk.Now().UTC()
+	}
+	return occurredAt.UTC()
+}
+
+func (c *Consumer) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-c.full:
+			c.flush()
+		case <-c.stop:
+			c.flush()
+			return
+		}
+	}
+}
+
+// flush вставляет буфер пачками по BatchSize. Пачка, которую не удалось
+// вставить, и всё после неё возвращаются в буфер до следующей попытки.
+func (c *Consumer) flush() {
+	c.mu.Lock()
+	records := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+	if len(records) == 0 {
+		c.flushed(nil)
+		return
+	}
+
+	start := time.Now()
+	defer func() { FlushDuration.Observe(time.Since(start).Seconds()) }()
+	for len(records) > 0 {
+		n := min(len(records), c.cfg.BatchSize)
+		if err := Insert(context.Background(), c.clickhouse, records[:n]); err != nil {
+			log.Printf("goods_log: insert %d events: %v", n, err)
+			InsertFailures.Inc()
+			c.mu.Lock()
+			c.pending = append(records, c.pending...)
+			Pending.Set(float64(len(c.pending)))
+			c.mu.Unlock()
+			c.flushed(err)
+			return
+		}
+		records = records[n:]
+	}
+	c.flushed(nil)
+}
+
+// flushed запоминает исход сброса буфера для Health.
+func (c *Consumer) flushed(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.lastError = err.Error()
+		return
+	}
+	c.lastError = ""
+	c.lastFlush = c.cfg.Clock.Now()
+	Pending.Set(float64(len(c.pending)))
+}
+
+// Health — состояние потребителя: сколько записей ждёт вставки, когда буфер
+// последний раз удалось записать целиком и чем закончилась последняя
+// неудачная попытка.
+type Health struct {
+	Healthy   bool      `json:"healthy"`
+	Pending   int       `json:"pending"`
+	LastFlush time.Time `json:"last_flush,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Health считает потребителя здоровым, пока последний сброс буфера удался.
+func (c *Consumer) Health() Health {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Health{
+		Healthy:   c.lastError == "",
+		Pending:   len(c.pending),
+		LastFlush: c.lastFlush,
+		LastError: c.lastError,
+	}
+}
+
+// Insert пишет записи одной транзакцией: драйвер ClickHouse отправляет их
+// на сервер одним блоком при Commit.
+func Insert(ctx context.Context, clickhouse *sql.DB, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := clickhouse.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO goods_log (Id, ProjectId, Name, Description, Priority, Removed, Diff, Editor, EventType, EventTime)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range records {
+		var removed uint8
+		if r.Removed {
+			removed = 1
+		}
+		_, err := stmt.ExecContext(ctx, int32(r.ID), int32(r.ProjectID), r.Name, r.Description,
+			int32(r.Priority), removed, r.Diff, r.Editor, r.EventType, r.EventTime)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,136 @@
+package goodslog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/clock"
+	"hezzl-test/internal/tenant"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecodeEventTime(t *testing.T) {
+	occurred := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := clock.NewManual(occurred.Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		subject string
+		data    string
+		want    time.Time
+	}{
+		{"occurred_at", "good_updated", `{"occurred_at":"2024-03-01T13:00:00+03:00","id":1,"project_id":2}`, occurred},
+		{"no occurred_at", "good_updated", `{"id":1,"project_id":2}`, now.Now()},
+		{"reordered", "goods_reordered", `{"occurred_at":"2024-03-01T10:00:00Z","project_id":2,"priorities":[{"id":1,"priority":3}]}`, occurred},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := nats.NewMsg(tt.subject)
+			msg.Data = []byte(tt.data)
+			msg.Header.Set(tenant.NATSUserHeader, "u1")
+
+			records, err := decode(msg, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("got %d records, want 1", len(records))
+			}
+			if got := records[0].EventTime; !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("EventTime = %v, want %v UTC", got, tt.want)
+			}
+			if records[0].Editor != "u1" {
+				t.Errorf("Editor = %q, want u1", records[0].Editor)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsEventWithoutID(t *testing.T) {
+	msg := nats.NewMsg("good_updated")
+	msg.Data = []byte(`{"project_id":2}`)
+	if _, err := decode(msg, clock.System{}); err == nil {
+		t.Fatal("want error for event without id")
+	}
+}
+
+// downConnector — ClickHouse, к которому нельзя подключиться.
+type downConnector struct{}
+
+func (downConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("clickhouse down")
+}
+
+func (downConnector) Driver() driver.Driver { return nil }
+
+func goodUpdated(id int) *nats.Msg {
+	msg := nats.NewMsg("good_updated")
+	msg.Data = []byte(fmt.Sprintf(`{"id":%d,"project_id":2}`, id))
+	return msg
+}
+
+func TestConsumerOverflowAndFailedFlush(t *testing.T) {
+	db := sql.OpenDB(downConnector{})
+	t.Cleanup(func() { db.Close() })
+	c := NewConsumer(db, Config{BatchSize: 2, FlushInterval: time.Hour, Clock: clock.NewManual(time.Now())})
+
+	overflow := testutil.ToFloat64(Dropped.WithLabelValues("overflow"))
+	invalid := testutil.ToFloat64(Dropped.WithLabelValues("invalid"))
+	failures := testutil.ToFloat64(InsertFailures)
+
+	for id := 1; id <= 2*maxBatches+3; id++ {
+		c.handle(goodUpdated(id))
+	}
+	bad := nats.NewMsg("good_updated")
+	bad.Data = []byte(`{"project_id":2}`)
+	c.handle(bad)
+
+	if got := testutil.ToFloat64(Dropped.WithLabelValues("overflow")) - overflow; got != 3 {
+		t.Errorf("overflow drops = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(Dropped.WithLabelValues("invalid")) - invalid; got != 1 {
+		t.Errorf("invalid drops = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(Pending); got != 2*maxBatches {
+		t.Errorf("pending gauge = %v, want %d", got, 2*maxBatches)
+	}
+	if h := c.Health(); !h.Healthy || h.Pending != 2*maxBatches {
+		t.Errorf("before flush Health = %+v", h)
+	}
+
+	c.flush()
+
+	if got := testutil.ToFloat64(InsertFailures) - failures; got != 1 {
+		t.Errorf("insert failures = %v, want 1", got)
+	}
+	h := c.Health()
+	if h.Healthy || h.LastError == "" {
+		t.Errorf("after failed flush Health = %+v, want unhealthy", h)
+	}
+	if h.Pending != 2*maxBatches {
+		t.Errorf("after failed flush pending = %d, want the records kept for a retry", h.Pending)
+	}
+}
+
+func TestConsumerHealthRecoversAfterFlush(t *testing.T) {
+	now := clock.NewManual(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC))
+	c := NewConsumer(nil, Config{BatchSize: 2, FlushInterval: time.Hour, Clock: now})
+	c.flushed(errors.New("clickhouse down"))
+	if c.Health().Healthy {
+		t.Fatal("want unhealthy after a failed flush")
+	}
+
+	c.flush()
+
+	h := c.Health()
+	if !h.Healthy || h.LastError != "" || !h.LastFlush.Equal(now.Now()) {
+		t.Errorf("Health = %+v, want healthy with LastFlush %v", h, now.Now())
+	}
+}
@@ -0,0 +1,142 @@
+// Package health периодически проверяет зависимости сервиса и отмечает
+// деградацию: зависимость отвечает, но медленнее порога.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Состояния зависимости.
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// Check — проверка одной зависимости. Ответ дольше Threshold считается
+// деградацией, ошибка — недоступностью.
+type Check struct {
+	Name      string
+	Threshold time.Duration
+	Ping      func(ctx context.Context) error
+}
+
+type Result struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	LatencyMS int64     `json:"latencyMs"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Monitor раз в interval выполняет все проверки и хранит последние
+// результаты. До первой проверки зависимости считаются исправными.
+type Monitor struct {
+	checks  []Check
+	timeout time.Duration
+
+	mu      sync.RWMutex
+	results []Result
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewMonitor(interval, timeout time.Duration, checks ...Check) *Monitor {
+	m := &Monitor{
+		checks:  checks,
+		timeout: timeout,
+		results: []Result{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go m.run(interval)
+	return m
+}
+
+func (m *Monitor) Close() error {
+	close(m.stop)
+	<-m.done
+	return nil
+}
+
+// Results — результаты последней проверки.
+func (m *Monitor) Results() []Result {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Result(nil), m.results...)
+}
+
+// Degraded сообщает, что хотя бы одна зависимость медленная или недоступна.
+func (m *Monitor) Degraded() bool {
+	return m.any(func(r Result) bool { return r.Status != StatusOK })
+}
+
+// Ready сообщает, что все зависимости отвечают, пусть и медленно.
+func (m *Monitor) Ready() bool {
+	return !m.any(func(r Result) bool { return r.Status == StatusDown })
+}
+
+func (m *Monitor) any(match func(Result) bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, r := range m.results {
+		if match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Monitor) run(interval time.Duration) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.checkAll()
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) checkAll() {
+	results := make([]Result, len(m.checks))
+	var wg sync.WaitGroup
+	for i, c := range m.checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			results[i] = m.check(c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	m.results = results
+	m.mu.Unlock()
+}
+
+func (m *Monitor) check(c Check) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Ping(ctx)
+	latency := time.Since(start)
+
+	r := Result{Name: c.Name, Status: StatusOK, LatencyMS: latency.Milliseconds(), CheckedAt: start.UTC()}
+	switch {
+	case err != nil:
+		r.Status, r.Error = StatusDown, err.Error()
+	case latency > c.Threshold:
+		r.Status = StatusDegraded
+	}
+	return r
+}
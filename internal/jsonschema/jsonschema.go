@@ -0,0 +1,296 @@
+// Package jsonschema проверяет JSON по схемам JSON Schema. Поддерживается
+// подмножество, которого хватает телам запросов сервиса: type (в том числе
+// списком, например ["integer", "null"]), properties, required,
+// additionalProperties (false или схема), items, enum, minimum/maximum,
+// minLength/maxLength, pattern, minItems/maxItems, not и format: date-time. Остальные
+// ключевые слова (title, description, readOnly, $schema) не проверяются.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Schema struct {
+	Type                 Types              `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Format               string             `json:"format"`
+	Pattern              string             `json:"pattern"`
+	Not                  *Schema            `json:"not"`
+
+	pattern *regexp.Regexp
+}
+
+// Types — допустимые типы значения: строка или список строк в схеме.
+type Types []string
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// Additional — значение additionalProperties: запрет лишних полей или
+// схема для них.
+type Additional struct {
+	Forbidden bool
+	Schema    *Schema
+}
+
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.Forbidden = !allowed
+		return nil
+	}
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// Parse разбирает схему.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile разбирает регулярные выражения pattern схемы и вложенных схем.
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	nested := []*Schema{s.Items, s.Not}
+	if s.AdditionalProperties != nil {
+		nested = append(nested, s.AdditionalProperties.Schema)
+	}
+	for _, prop := range s.Properties {
+		nested = append(nested, prop)
+	}
+	for _, n := range nested {
+		if n == nil {
+			continue
+		}
+		if err := n.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Errors — сообщения об ошибках по JSON Pointer (RFC 6901) значений;
+// пустой указатель — документ целиком.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	pointers := make([]string, 0, len(e))
+	for p := range e {
+		pointers = append(pointers, p)
+	}
+	sort.Strings(pointers)
+	parts := make([]string, len(pointers))
+	for i, p := range pointers {
+		parts[i] = p + ": " + e[p]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Fields отдаёт ошибки для details ответа 400.
+func (e Errors) Fields() map[string]string {
+	return e
+}
+
+// Validate проверяет документ data; все нарушения собираются в Errors.
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return Errors{"": "invalid JSON: " + err.Error()}
+	}
+	if dec.More() {
+		return Errors{"": "unexpected data after JSON value"}
+	}
+	errs := Errors{}
+	s.validate(v, "", errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *Schema) validate(v interface{}, ptr string, errs Errors) {
+	if len(s.Type) > 0 && !s.typeMatches(v) {
+		errs[ptr] = "must be " + strings.Join(s.Type, " or ")
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(v, s.Enum) {
+		errs[ptr] = "must be one of " + enumString(s.Enum)
+		return
+	}
+	if s.Not != nil {
+		// Ошибки схемы not означают, что значение ей не подходит, и
+		// наружу не попадают.
+		notErrs := Errors{}
+		s.Not.validate(v, ptr, notErrs)
+		if len(notErrs) == 0 {
+			errs[ptr] = "must not match " + s.Not.describe()
+			return
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs[ptr+"/"+escape(name)] = "is required"
+			}
+		}
+		for name, value := range v {
+			p := ptr + "/" + escape(name)
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(value, p, errs)
+			} else if a := s.AdditionalProperties; a != nil && a.Forbidden {
+				errs[p] = "is not allowed"
+			} else if a != nil && a.Schema != nil {
+				a.Schema.validate(value, p, errs)
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs[ptr] = fmt.Sprintf("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs[ptr] = fmt.Sprintf("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, ptr+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			errs[ptr] = fmt.Sprintf("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs[ptr] = fmt.Sprintf("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			errs[ptr] = "must match " + s.Pattern
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				errs[ptr] = "must be an RFC 3339 date-time"
+			}
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			errs[ptr] = fmt.Sprintf("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			errs[ptr] = fmt.Sprintf("must be at most %v", *s.Maximum)
+		}
+	}
+}
+
+func (s *Schema) typeMatches(v interface{}) bool {
+	for _, t := range s.Type {
+		switch v := v.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if t == "integer" {
+				if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+					return true
+				}
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// describe коротко называет схему в сообщении not.
+func (s *Schema) describe() string {
+	if s.Pattern != "" {
+		return s.Pattern
+	}
+	return "the not schema"
+}
+
+// inEnum сравнивает значения по их JSON: числа из схемы и из документа
+// разобраны по-разному (float64 и json.Number).
+func inEnum(v interface{}, enum []interface{}) bool {
+	got, _ := json.Marshal(v)
+	for _, e := range enum {
+		want, _ := json.Marshal(e)
+		if bytes.Equal(got, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumString(enum []interface{}) string {
+	data, _ := json.Marshal(enum)
+	return string(data)
+}
+
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,35 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatternAndNot(t *testing.T) {
+	s, err := Parse([]byte(`{"type": "object", "properties": {
+		"code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+		"description": {"type": "string", "not": {"pattern": "^enc:"}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		doc  string
+		want error
+	}{
+		{`{"code": "RUB", "description": "see enc:attached"}`, nil},
+		{`{"code": "rub"}`, Errors{"/code": "must match ^[A-Z]{3}$"}},
+		{`{"description": "enc:see attached"}`, Errors{"/description": "must not match ^enc:"}},
+	}
+	for _, tt := range tests {
+		if err := s.Validate([]byte(tt.doc)); !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("Validate(%s) = %v, want %v", tt.doc, err, tt.want)
+		}
+	}
+}
+
+func TestParseBadPattern(t *testing.T) {
+	if _, err := Parse([]byte(`{"properties": {"a": {"items": {"pattern": "("}}}}`)); err == nil {
+		t.Fatal("want error for an invalid pattern")
+	}
+}
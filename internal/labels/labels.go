@@ -0,0 +1,221 @@
+package labels
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]{0,61}[A-Za-z0-9])?$`)
+
+// Labels — структурированные метки товара вида env=prod, хранятся в JSONB.
+type Labels map[string]string
+
+func (l Labels) Validate() error {
+	for k, v := range l {
+		if !namePattern.MatchString(k) {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+		if v != "" && !namePattern.MatchString(v) {
+			return fmt.Errorf("invalid label value %q for key %q", v, k)
+		}
+	}
+	return nil
+}
+
+func (l Labels) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+func (l *Labels) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("labels: unsupported type %T", src)
+	}
+}
+
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector — набор требований в стиле селекторов Kubernetes, объединённых по И.
+type Selector []Requirement
+
+// Parse разбирает селектор вида
+//
+//	env=prod,season!=winter,tier in (a,b),tier notin (c),archived,!draft
+//
+// Оператор == эквивалентен =.
+func Parse(s string) (Selector, error) {
+	var selector Selector
+	for _, term := range splitTerms(s) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		req, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+func splitTerms(s string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, s[start:])
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	if strings.HasPrefix(term, "!") {
+		key := strings.TrimSpace(term[1:])
+		return requirement(key, DoesNotExist, nil)
+	}
+
+	for _, op := range []string{"!=", "==", "="} {
+		if i := strings.Index(term, op); i >= 0 {
+			key := strings.TrimSpace(term[:i])
+			value := strings.TrimSpace(term[i+len(op):])
+			operator := Equals
+			if op == "!=" {
+				operator = NotEquals
+			}
+			return requirement(key, operator, []string{value})
+		}
+	}
+
+	if fields := strings.Fields(term); len(fields) >= 2 && (fields[1] == "in" || fields[1] == "notin") {
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(term[len(fields[0]):]), fields[1]))
+		if !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+			return Requirement{}, fmt.Errorf("invalid selector %q: expected value list in parentheses", term)
+		}
+		var values []string
+		for _, v := range strings.Split(rest[1:len(rest)-1], ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+		return requirement(fields[0], Operator(fields[1]), values)
+	}
+
+	return requirement(term, Exists, nil)
+}
+
+func requirement(key string, op Operator, values []string) (Requirement, error) {
+	if !namePattern.MatchString(key) {
+		return Requirement{}, fmt.Errorf("invalid label key %q", key)
+	}
+	for _, v := range values {
+		if v != "" && !namePattern.MatchString(v) {
+			return Requirement{}, fmt.Errorf("invalid label value %q", v)
+		}
+	}
+	sort.Strings(values)
+	return Requirement{Key: key, Operator: op, Values: values}, nil
+}
+
+// String возвращает нормализованную запись селектора, пригодную для ключа кэша.
+func (s Selector) String() string {
+	terms := make([]string, 0, len(s))
+	for _, req := range s {
+		switch req.Operator {
+		case Equals, NotEquals:
+			terms = append(terms, req.Key+string(req.Operator)+req.Values[0])
+		case In, NotIn:
+			terms = append(terms, req.Key+" "+string(req.Operator)+" ("+strings.Join(req.Values, ",")+")")
+		case Exists:
+			terms = append(terms, req.Key)
+		case DoesNotExist:
+			terms = append(terms, "!"+req.Key)
+		}
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, ",")
+}
+
+// SQL переводит селектор в условие над JSONB-колонкой column. Параметры
+// нумеруются с $firstArg. Равенство выражается через @>, чтобы запрос
+// использовал GIN-индекс. Как и в Kubernetes, != и notin совпадают
+// с товарами, у которых метки нет вовсе.
+func (s Selector) SQL(column string, firstArg int) (string, []interface{}) {
+	if len(s) == 0 {
+		return "TRUE", nil
+	}
+
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", firstArg+len(args)-1)
+	}
+
+	for _, req := range s {
+		switch req.Operator {
+		case Equals:
+			conds = append(conds, fmt.Sprintf("%s @> %s::jsonb", column, arg(Labels{req.Key: req.Values[0]})))
+		case NotEquals:
+			conds = append(conds, fmt.Sprintf("NOT %s @> %s::jsonb", column, arg(Labels{req.Key: req.Values[0]})))
+		case In:
+			conds = append(conds, fmt.Sprintf("%s->>%s = ANY(%s)", column, arg(req.Key), arg(stringArray(req.Values))))
+		case NotIn:
+			key := arg(req.Key)
+			conds = append(conds, fmt.Sprintf("(NOT %s ? %s OR %s->>%s <> ALL(%s))", column, key, column, key, arg(stringArray(req.Values))))
+		case Exists:
+			conds = append(conds, fmt.Sprintf("%s ? %s", column, arg(req.Key)))
+		case DoesNotExist:
+			conds = append(conds, fmt.Sprintf("NOT %s ? %s", column, arg(req.Key)))
+		}
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+type stringArray []string
+
+// Value кодирует массив в текстовый литерал Postgres, чтобы пакет не зависел от драйвера.
+func (a stringArray) Value() (driver.Value, error) {
+	quoted := make([]string, len(a))
+	for i, v := range a {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
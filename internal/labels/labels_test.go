@@ -0,0 +1,132 @@
+package labels
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels Labels
+		valid  bool
+	}{
+		{"empty", nil, true},
+		{"simple", Labels{"env": "prod", "tier": "a-1"}, true},
+		{"prefixed key", Labels{"example.com/team": "catalog"}, true},
+		{"empty value", Labels{"draft": ""}, true},
+		{"63 characters", Labels{strings.Repeat("k", 63): "v"}, true},
+		{"64 characters", Labels{strings.Repeat("k", 64): "v"}, false},
+		{"empty key", Labels{"": "v"}, false},
+		{"key with space", Labels{"my key": "v"}, false},
+		{"key ends with dash", Labels{"env-": "v"}, false},
+		{"value with comma", Labels{"env": "a,b"}, false},
+		{"value with equals", Labels{"env": "a=b"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.labels.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Selector
+		str      string
+	}{
+		{"", nil, ""},
+		{"env=prod", Selector{{Key: "env", Operator: Equals, Values: []string{"prod"}}}, "env=prod"},
+		{"env==prod", Selector{{Key: "env", Operator: Equals, Values: []string{"prod"}}}, "env=prod"},
+		{" season != winter ", Selector{{Key: "season", Operator: NotEquals, Values: []string{"winter"}}}, "season!=winter"},
+		{"tier in (b, a)", Selector{{Key: "tier", Operator: In, Values: []string{"a", "b"}}}, "tier in (a,b)"},
+		{"tier notin (c)", Selector{{Key: "tier", Operator: NotIn, Values: []string{"c"}}}, "tier notin (c)"},
+		{"archived,!draft", Selector{{Key: "archived", Operator: Exists}, {Key: "draft", Operator: DoesNotExist}}, "!draft,archived"},
+		{"b=1,a=2", Selector{{Key: "b", Operator: Equals, Values: []string{"1"}}, {Key: "a", Operator: Equals, Values: []string{"2"}}}, "a=2,b=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+			if s := got.String(); s != tt.str {
+				t.Errorf("String() = %q, want %q", s, tt.str)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, selector := range []string{
+		"bad key=1",
+		"env=a b",
+		"tier in a,b",
+		"tier in (a,b",
+		"!",
+		"env=prod,=x",
+		"tier notin (ok,bad value)",
+	} {
+		if _, err := Parse(selector); err == nil {
+			t.Errorf("Parse(%q) succeeded", selector)
+		}
+	}
+}
+
+func TestSelectorSQL(t *testing.T) {
+	selector, err := Parse("env=prod,tier notin (a,b),!draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond, args := selector.SQL("labels", 3)
+	want := `labels @> $3::jsonb AND (NOT labels ? $4 OR labels->>$4 <> ALL($5)) AND NOT labels ? $6`
+	if cond != want {
+		t.Errorf("SQL = %s, want %s", cond, want)
+	}
+	if len(args) != 4 {
+		t.Fatalf("args = %v", args)
+	}
+	if v, _ := args[2].(stringArray).Value(); v != `{"a","b"}` {
+		t.Errorf("array arg = %v", v)
+	}
+
+	if cond, args := Selector(nil).SQL("labels", 1); cond != "TRUE" || args != nil {
+		t.Errorf("empty selector = %s, %v", cond, args)
+	}
+}
+
+func TestValueScan(t *testing.T) {
+	l := Labels{"env": "prod", "tier": "a"}
+	v, err := l.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []interface{}{v, []byte(v.(string))} {
+		var got Labels
+		if err := got.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, l) {
+			t.Errorf("Scan(%T) = %v, want %v", src, got, l)
+		}
+	}
+
+	// NULL в колонке и обратно.
+	if v, err := Labels(nil).Value(); v != nil || err != nil {
+		t.Errorf("nil labels Value() = %v, %v", v, err)
+	}
+	got := Labels{"stale": "x"}
+	if err := got.Scan(nil); err != nil || got != nil {
+		t.Errorf("Scan(nil) = %v, %v", got, err)
+	}
+	if err := got.Scan(42); err == nil {
+		t.Error("Scan(int) succeeded")
+	}
+}
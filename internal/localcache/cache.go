@@ -0,0 +1,222 @@
+// Package localcache — кэш в памяти процесса с TTL, реализующий deps.Cache,
+// для запуска без Redis в локальной разработке и небольших установках.
+// Данные не переживают рестарт и не делятся между экземплярами сервиса.
+package localcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"hezzl-test/internal/deps"
+)
+
+type item struct {
+	value   string
+	expires time.Time
+}
+
+func (it item) expired(now time.Time) bool {
+	return !it.expires.IsZero() && now.After(it.expires)
+}
+
+type Cache struct {
+	mu    sync.RWMutex
+	items map[string]item
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ deps.Cache = (*Cache)(nil)
+
+// New создаёт кэш, который раз в sweepInterval удаляет истёкшие ключи;
+// до удаления они всё равно не видны читателям.
+func New(sweepInterval time.Duration) *Cache {
+	c := &Cache{
+		items: make(map[string]item),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go c.sweep(sweepInterval)
+	return c
+}
+
+func (c *Cache) Get(ctx context.Context, key string) *redis.StringCmd {
+	c.mu.RLock()
+	it, ok := c.items[key]
+	c.mu.RUnlock()
+	if !ok || it.expired(time.Now()) {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(it.value, nil)
+}
+
+// MGet, как и в Redis, возвращает nil на месте отсутствующих ключей.
+func (c *Cache) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	now := time.Now()
+	vals := make([]interface{}, len(keys))
+	c.mu.RLock()
+	for i, key := range keys {
+		if it, ok := c.items[key]; ok && !it.expired(now) {
+			vals[i] = it.value
+		}
+	}
+	c.mu.RUnlock()
+	return redis.NewSliceResult(vals, nil)
+}
+
+func (c *Cache) GetDel(ctx context.Context, key string) *redis.StringCmd {
+	c.mu.Lock()
+	it, ok := c.items[key]
+	delete(c.items, key)
+	c.mu.Unlock()
+	if !ok || it.expired(time.Now()) {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(it.value, nil)
+}
+
+// IncrBy увеличивает целое значение ключа на value, сохраняя его TTL;
+// отсутствующий ключ считается нулём.
+func (c *Cache) IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, ok := c.items[key]
+	if !ok || it.expired(time.Now()) {
+		it = item{value: "0"}
+	}
+	n, err := strconv.ParseInt(it.value, 10, 64)
+	if err != nil {
+		return redis.NewIntResult(0, errors.New("ERR value is not an integer or out of range"))
+	}
+	n += value
+	it.value = strconv.FormatInt(n, 10)
+	c.items[key] = it
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	it := item{value: format(value)}
+	if expiration > 0 {
+		it.expires = time.Now().Add(expiration)
+	}
+	c.mu.Lock()
+	c.items[key] = it
+	c.mu.Unlock()
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *Cache) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	now := time.Now()
+	var n int64
+	c.mu.Lock()
+	for _, key := range keys {
+		if it, ok := c.items[key]; ok {
+			if !it.expired(now) {
+				n++
+			}
+			delete(c.items, key)
+		}
+	}
+	c.mu.Unlock()
+	return redis.NewIntResult(n, nil)
+}
+
+// Scan возвращает все подходящие ключи одной страницей с нулевым курсором,
+// поэтому cursor и count не используются. match поддерживает * и ?, как в Redis.
+func (c *Cache) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	re, err := globRegexp(match)
+	if err != nil {
+		return redis.NewScanCmdResult(nil, 0, err)
+	}
+
+	now := time.Now()
+	var keys []string
+	c.mu.RLock()
+	for key, it := range c.items {
+		if !it.expired(now) && re.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.RUnlock()
+	sort.Strings(keys)
+	return redis.NewScanCmdResult(keys, 0, nil)
+}
+
+// Close останавливает фоновую очистку.
+func (c *Cache) Close() error {
+	close(c.stop)
+	<-c.done
+	return nil
+}
+
+func (c *Cache) sweep(interval time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			for key, it := range c.items {
+				if it.expired(now) {
+					delete(c.items, key)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// format приводит значение к строке так же, как go-redis при записи.
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func globRegexp(match string) (*regexp.Regexp, error) {
+	if match == "" {
+		match = "*"
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range match {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	goodsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "goods_created_total",
+		Help: "Goods created, including imports and copies.",
+	}, []string{"project"})
+	goodsUpdated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "goods_updated_total",
+		Help: "Goods updated, including moves between projects.",
+	}, []string{"project"})
+	goodsRemoved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "goods_removed_total",
+		Help: "Goods removed, soft or hard.",
+	}, []string{"project"})
+	goodsReprioritized = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "goods_reprioritizations_total",
+		Help: "Reprioritize requests applied.",
+	})
+	cacheRebuilds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_rebuilds_total",
+		Help: "Cache entries rebuilt from Postgres, by cache and trigger.",
+	}, []string{"cache", "trigger"})
+	cacheInvalidated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_keys_invalidated_total",
+		Help: "Cache keys removed by pattern invalidation, by cache.",
+	}, []string{"cache"})
+	cacheInvalidationTruncated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_invalidations_truncated_total",
+		Help: "Pattern invalidations stopped at the scan limit, by cache.",
+	}, []string{"cache"})
+)
+
+// Business — счётчики бизнес-событий для продуктовых дашбордов; их нужно
+// зарегистрировать в Prometheus вместе с остальными метриками.
+func Business() []prometheus.Collector {
+	return []prometheus.Collector{goodsCreated, goodsUpdated, goodsRemoved, goodsReprioritized, cacheRebuilds, cacheInvalidated, cacheInvalidationTruncated}
+}
+
+func GoodsCreated(projectID, n int) {
+	goodsCreated.WithLabelValues(strconv.Itoa(projectID)).Add(float64(n))
+}
+
+func GoodsUpdated(projectID, n int) {
+	goodsUpdated.WithLabelValues(strconv.Itoa(projectID)).Add(float64(n))
+}
+
+func GoodsRemoved(projectID, n int) {
+	goodsRemoved.WithLabelValues(strconv.Itoa(projectID)).Add(float64(n))
+}
+
+func GoodsReprioritized() {
+	goodsReprioritized.Inc()
+}
+
+// CacheRebuilt отмечает пересборку записи кэша cache; trigger — "request"
+// для промаха при запросе или имя фоновой задачи.
+func CacheRebuilt(cache, trigger string) {
+	cacheRebuilds.WithLabelValues(cache, trigger).Inc()
+}
+
+// CacheKeysInvalidated отмечает n ключей кэша cache, удалённых по шаблону.
+func CacheKeysInvalidated(cache string, n int) {
+	cacheInvalidated.WithLabelValues(cache).Add(float64(n))
+}
+
+// CacheInvalidationTruncated отмечает удаление по шаблону, прерванное на
+// пределе числа SCAN.
+func CacheInvalidationTruncated(cache string) {
+	cacheInvalidationTruncated.WithLabelValues(cache).Inc()
+}
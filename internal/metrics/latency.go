@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TraceHeader — заголовок W3C Trace Context, который проставляет трассировщик
+// или прокси перед сервисом: 00-<trace-id>-<span-id>-<flags>.
+const TraceHeader = "traceparent"
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	goodsListQueryDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "goods_list_query_duration_seconds",
+		Help:    "Postgres goods list page query latency.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+// Latency — гистограммы задержек; их нужно зарегистрировать вместе с
+// остальными метриками.
+func Latency() []prometheus.Collector {
+	return []prometheus.Collector{requestDuration, goodsListQueryDuration}
+}
+
+type traceCtxKey struct{}
+
+// TraceID возвращает id трассы запроса или "", если трассировка выключена
+// или запрос пришёл без traceparent.
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceCtxKey{}).(string)
+	return id
+}
+
+// Requests измеряет задержку запросов по маршрутам. Пути, для которых known
+// возвращает false, попадают в маршрут "other", чтобы не плодить ряды. С
+// exemplars к наблюдениям прикладывается trace_id из traceparent, и из
+// медленного бакета в Grafana можно перейти к трассе запроса; exemplars
+// отдаются только в формате OpenMetrics.
+func Requests(known func(path string) bool, exemplars bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.URL.Path
+			if !known(route) {
+				route = "other"
+			}
+			traceID := ""
+			if exemplars {
+				traceID = parseTraceParent(r.Header.Get(TraceHeader))
+			}
+			if traceID != "" {
+				r = r.WithContext(context.WithValue(r.Context(), traceCtxKey{}, traceID))
+			}
+
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			observe(requestDuration.WithLabelValues(r.Method, route), time.Since(start), traceID)
+		})
+	}
+}
+
+// GoodsListQuery отмечает время запроса страницы списка товаров.
+func GoodsListQuery(ctx context.Context, d time.Duration) {
+	observe(goodsListQueryDuration, d, TraceID(ctx))
+}
+
+func observe(o prometheus.Observer, d time.Duration, traceID string) {
+	if e, ok := o.(prometheus.ExemplarObserver); ok && traceID != "" {
+		e.ObserveWithExemplar(d.Seconds(), prometheus.Labels{"trace_id": traceID})
+		return
+	}
+	o.Observe(d.Seconds())
+}
+
+// parseTraceParent выделяет trace-id; неверный или нулевой заголовок даёт "".
+func parseTraceParent(header string) string {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	for _, c := range parts[1] {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ""
+		}
+	}
+	return parts[1]
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	dbOpenDesc         = prometheus.NewDesc("db_connections_open", "Open connections, in use and idle.", []string{"db"}, nil)
+	dbInUseDesc        = prometheus.NewDesc("db_connections_in_use", "Connections currently in use.", []string{"db"}, nil)
+	dbIdleDesc         = prometheus.NewDesc("db_connections_idle", "Idle connections.", []string{"db"}, nil)
+	dbMaxOpenDesc      = prometheus.NewDesc("db_connections_max_open", "Maximum number of open connections, 0 is unlimited.", []string{"db"}, nil)
+	dbWaitCountDesc    = prometheus.NewDesc("db_connections_wait_total", "Connections waited for.", []string{"db"}, nil)
+	dbWaitDurationDesc = prometheus.NewDesc("db_connections_wait_seconds_total", "Time spent waiting for a connection.", []string{"db"}, nil)
+
+	redisHitsDesc     = prometheus.NewDesc("redis_pool_hits_total", "Free connections found in the pool.", nil, nil)
+	redisMissesDesc   = prometheus.NewDesc("redis_pool_misses_total", "Free connections not found in the pool.", nil, nil)
+	redisTimeoutsDesc = prometheus.NewDesc("redis_pool_timeouts_total", "Waits for a connection that timed out.", nil, nil)
+	redisTotalDesc    = prometheus.NewDesc("redis_pool_connections", "Connections in the pool.", nil, nil)
+	redisIdleDesc     = prometheus.NewDesc("redis_pool_connections_idle", "Idle connections in the pool.", nil, nil)
+
+	natsConnectedDesc  = prometheus.NewDesc("nats_connected", "1 if the NATS connection is established.", []string{"status"}, nil)
+	natsReconnectsDesc = prometheus.NewDesc("nats_reconnects_total", "NATS reconnects.", nil, nil)
+)
+
+// Pools отдаёт в Prometheus состояние пулов соединений Postgres, ClickHouse,
+// Redis и NATS; значения снимаются в момент сбора.
+type Pools struct {
+	dbs   map[string]*sql.DB
+	redis RedisPool
+	nats  *nats.Conn
+}
+
+// RedisPool — клиент Redis со статистикой пула: *redis.Client или
+// *redis.Ring, у которого она суммируется по узлам.
+type RedisPool interface {
+	PoolStats() *redis.PoolStats
+}
+
+func NewPools(dbs map[string]*sql.DB, redisClient RedisPool, natsConn *nats.Conn) *Pools {
+	return &Pools{dbs: dbs, redis: redisClient, nats: natsConn}
+}
+
+func (p *Pools) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		dbOpenDesc, dbInUseDesc, dbIdleDesc, dbMaxOpenDesc, dbWaitCountDesc, dbWaitDurationDesc,
+		redisHitsDesc, redisMissesDesc, redisTimeoutsDesc, redisTotalDesc, redisIdleDesc,
+		natsConnectedDesc, natsReconnectsDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (p *Pools) Collect(ch chan<- prometheus.Metric) {
+	for name, db := range p.dbs {
+		s := db.Stats()
+		ch <- prometheus.MustNewConstMetric(dbOpenDesc, prometheus.GaugeValue, float64(s.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(dbInUseDesc, prometheus.GaugeValue, float64(s.InUse), name)
+		ch <- prometheus.MustNewConstMetric(dbIdleDesc, prometheus.GaugeValue, float64(s.Idle), name)
+		ch <- prometheus.MustNewConstMetric(dbMaxOpenDesc, prometheus.GaugeValue, float64(s.MaxOpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(dbWaitCountDesc, prometheus.CounterValue, float64(s.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(dbWaitDurationDesc, prometheus.CounterValue, s.WaitDuration.Seconds(), name)
+	}
+
+	if p.redis != nil {
+		s := p.redis.PoolStats()
+		ch <- prometheus.MustNewConstMetric(redisHitsDesc, prometheus.CounterValue, float64(s.Hits))
+		ch <- prometheus.MustNewConstMetric(redisMissesDesc, prometheus.CounterValue, float64(s.Misses))
+		ch <- prometheus.MustNewConstMetric(redisTimeoutsDesc, prometheus.CounterValue, float64(s.Timeouts))
+		ch <- prometheus.MustNewConstMetric(redisTotalDesc, prometheus.GaugeValue, float64(s.TotalConns))
+		ch <- prometheus.MustNewConstMetric(redisIdleDesc, prometheus.GaugeValue, float64(s.IdleConns))
+	}
+
+	if p.nats != nil {
+		connected := 0.0
+		if p.nats.IsConnected() {
+			connected = 1
+		}
+		ch <- prometheus.MustNewConstMetric(natsConnectedDesc, prometheus.GaugeValue, connected, p.nats.Status().String())
+		ch <- prometheus.MustNewConstMetric(natsReconnectsDesc, prometheus.CounterValue, float64(p.nats.Stats().Reconnects))
+	}
+}
+
+// Watch раз в interval сравнивает среднее ожидание соединения из пулов
+// баз данных и Redis с threshold и пишет предупреждение с подсказками, если
+// пул не справляется. Работает до отмены ctx.
+func (p *Pools) Watch(ctx context.Context, interval, threshold time.Duration) {
+	type snapshot struct {
+		count    int64
+		duration time.Duration
+	}
+	last := make(map[string]snapshot, len(p.dbs))
+	var lastRedisTimeouts uint32
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for name, db := range p.dbs {
+			s := db.Stats()
+			prev := last[name]
+			last[name] = snapshot{count: s.WaitCount, duration: s.WaitDuration}
+
+			waits := s.WaitCount - prev.count
+			if waits <= 0 {
+				continue
+			}
+			avg := (s.WaitDuration - prev.duration) / time.Duration(waits)
+			if avg > threshold {
+				log.Printf("pool: %s: %d waits for a connection in the last %s, %s on average (in use %d of max %d); "+
+					"raise SetMaxOpenConns, look for slow queries and long-running transactions, or check for leaked rows/tx",
+					name, waits, interval, avg, s.InUse, s.MaxOpenConnections)
+			}
+		}
+
+		if p.redis != nil {
+			timeouts := p.redis.PoolStats().Timeouts
+			if timeouts > lastRedisTimeouts {
+				log.Printf("pool: redis: %d connection waits timed out in the last %s; raise PoolSize or PoolTimeout, or look for slow commands",
+					timeouts-lastRedisTimeouts, interval)
+			}
+			lastRedisTimeouts = timeouts
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"hezzl-test/internal/response"
+)
+
+// AdminTokenHeader — заголовок с токеном административного порта.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminToken пропускает только запросы с токеном token в X-Admin-Token.
+// Пустой token проверку выключает: тогда порт защищён только адресом, на
+// котором слушает.
+func AdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(token)) != 1 {
+				response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "errors.admin.unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
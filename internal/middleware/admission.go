@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"hezzl-test/internal/response"
+)
+
+// Priority описывает класс запросов для Admission: каждый запрос занимает
+// Weight единиц ёмкости и допускается, только пока занятое вместе с ним
+// не превышает Share от всей ёмкости.
+type Priority struct {
+	Weight int
+	Share  float64
+}
+
+// Admission — общий взвешенный бюджет одновременных запросов. Классы с
+// меньшей Share первыми упираются в свой потолок при нагрузке, оставляя
+// запас важным запросам: тяжёлая выгрузка ждёт и получает 503, а изменения
+// и чтение отдельных записей продолжают проходить.
+type Admission struct {
+	capacity int
+
+	mu      sync.Mutex
+	inUse   int
+	changed chan struct{}
+}
+
+func NewAdmission(capacity int) *Admission {
+	return &Admission{capacity: capacity, changed: make(chan struct{})}
+}
+
+// Middleware допускает запросы класса p, ожидая освобождения ёмкости не
+// дольше wait.
+func (a *Admission) Middleware(p Priority, wait time.Duration) func(http.Handler) http.Handler {
+	limit := int(float64(a.capacity) * p.Share)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !a.admit(r, p.Weight, limit, wait) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds()+1)))
+				response.Error(w, r, http.StatusServiceUnavailable, response.CodeUnavailable, "errors.server.busy")
+				return
+			}
+			defer a.release(p.Weight)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a *Admission) admit(r *http.Request, weight, limit int, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		a.mu.Lock()
+		if a.inUse+weight <= limit || a.inUse == 0 {
+			a.inUse += weight
+			a.mu.Unlock()
+			return true
+		}
+		changed := a.changed
+		a.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		case <-r.Context().Done():
+			return false
+		}
+	}
+}
+
+func (a *Admission) release(weight int) {
+	a.mu.Lock()
+	a.inUse -= weight
+	close(a.changed)
+	a.changed = make(chan struct{})
+	a.mu.Unlock()
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdmission(t *testing.T) {
+	a := NewAdmission(10)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	export := a.Middleware(Priority{Weight: 4, Share: 0.5}, 20*time.Millisecond)
+	write := a.Middleware(Priority{Weight: 1, Share: 1}, 20*time.Millisecond)
+	request := func() *http.Request { return httptest.NewRequest(http.MethodGet, "/goods/export", nil) }
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		export(blocking).ServeHTTP(w, request())
+		done <- w.Code
+	}()
+	<-started
+
+	// Вторая выгрузка не помещается в свою долю и получает 503.
+	w := httptest.NewRecorder()
+	export(ok).ServeHTTP(w, request())
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("second export: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	// Изменения проходят, пока есть общий запас.
+	w = httptest.NewRecorder()
+	write(ok).ServeHTTP(w, request())
+	if w.Code != http.StatusOK {
+		t.Fatalf("write under load: status %d", w.Code)
+	}
+
+	// Ожидающая выгрузка допускается, когда первая освобождает ёмкость.
+	waiting := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		a.Middleware(Priority{Weight: 4, Share: 0.5}, time.Second)(ok).ServeHTTP(w, request())
+		waiting <- w.Code
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("first export: %d", code)
+	}
+	if code := <-waiting; code != http.StatusOK {
+		t.Errorf("waiting export: %d, want it admitted after release", code)
+	}
+}
+
+func TestAdmissionOversized(t *testing.T) {
+	// Запрос тяжелее своей доли всё равно проходит, если ёмкость свободна.
+	a := NewAdmission(2)
+	w := httptest.NewRecorder()
+	a.Middleware(Priority{Weight: 5, Share: 0.5}, time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goods/export", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d on an idle server", w.Code)
+	}
+	if a.inUse != 0 {
+		t.Errorf("inUse = %d after the request, want 0", a.inUse)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"hezzl-test/internal/jsonschema"
+	"hezzl-test/internal/response"
+)
+
+// ValidateBody отклоняет с 400 тело запроса, не подходящее под схему;
+// details.fields ответа указывает JSON Pointer каждого нарушения. Тело
+// больше maxBytes отклоняется целиком. Пустое тело пропускается: его
+// разбирает обработчик. Проверенное тело передаётся дальше без изменений.
+func ValidateBody(s *jsonschema.Schema, maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+			if err != nil {
+				response.BadRequest(w, r, err)
+				return
+			}
+			if len(bytes.TrimSpace(body)) > 0 {
+				if err := s.Validate(body); err != nil {
+					response.BadRequest(w, r, err)
+					return
+				}
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hezzl-test/internal/jsonschema"
+)
+
+func TestValidateBody(t *testing.T) {
+	s, err := jsonschema.Parse([]byte(`{"type": "object", "additionalProperties": false,
+		"properties": {"name": {"type": "string"}, "priority": {"type": "integer", "minimum": 1}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		fields map[string]interface{}
+	}{
+		{name: "valid", body: `{"name":"Tea","priority":2}`, status: http.StatusOK},
+		{name: "empty", body: "  ", status: http.StatusOK},
+		{name: "wrong type", body: `{"name":1}`, status: http.StatusBadRequest, fields: map[string]interface{}{"/name": "must be string"}},
+		{name: "unknown field", body: `{"nmae":"Tea"}`, status: http.StatusBadRequest},
+		{name: "too large", body: `{"name":"` + strings.Repeat("x", 64) + `"}`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := ValidateBody(s, 48)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				got = string(body)
+			}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/good/create", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK {
+				// Обработчик получает тело без изменений.
+				if got != tt.body {
+					t.Errorf("handler body = %q, want %q", got, tt.body)
+				}
+				return
+			}
+			if got != "" {
+				t.Error("handler ran for a rejected body")
+			}
+			if tt.fields == nil {
+				return
+			}
+			var resp struct {
+				Details struct {
+					Fields map[string]interface{} `json:"fields"`
+				} `json:"details"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp.Details.Fields, tt.fields) {
+				t.Errorf("fields = %v, want %v", resp.Details.Fields, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"hezzl-test/internal/budget"
+	"hezzl-test/internal/deps"
+)
+
+// Ответы крупнее maxCachedBody не кэшируются.
+const maxCachedBody = 1 << 20
+
+type cachedResponse struct {
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	StoredAt    time.Time `json:"stored_at"`
+}
+
+// Cache кэширует в Redis успешные GET-ответы маршрута на ttl. Ключ строится
+// из principal (арендатор и пользователь запроса) и нормализованного URL —
+// пути и параметров, отсортированных по имени. Ответ сопровождается
+// Cache-Control с тем же ttl и Age с возрастом записи; запросы с
+// авторизацией помечаются private. Cache-Control: no-cache в запросе или
+// требование чтения своих записей (см. Consistency) заставляет пересчитать ответ.
+func Cache(redisClient deps.Cache, ttl time.Duration, principal func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := "http:" + principal(r) + ":" + r.URL.Path + "?" + r.URL.Query().Encode()
+			setCacheControl(w, r, ttl)
+
+			if r.Header.Get("Cache-Control") != "no-cache" && !Strong(r.Context()) {
+				ctx, cancel := budget.For(r.Context(), budget.Cache)
+				cached, ok := loadResponse(ctx, redisClient, key)
+				cancel()
+				if ok {
+					age := time.Since(cached.StoredAt)
+					if age < 0 {
+						age = 0
+					}
+					w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+					w.Header().Set("Content-Type", cached.ContentType)
+					w.Header().Set("Content-Length", strconv.Itoa(len(cached.Body)))
+					w.Header().Set("X-Cache", "HIT")
+					w.WriteHeader(cached.Status)
+					w.Write(cached.Body)
+					return
+				}
+			}
+
+			w.Header().Set("Age", "0")
+			w.Header().Set("X-Cache", "MISS")
+			rec := &recordWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status != http.StatusOK || rec.overflow {
+				return
+			}
+			data, err := json.Marshal(cachedResponse{
+				Status:      rec.status,
+				ContentType: w.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+				StoredAt:    time.Now(),
+			})
+			if err != nil {
+				return
+			}
+			ctx, cancel := budget.For(r.Context(), budget.Cache)
+			defer cancel()
+			if err := redisClient.Set(ctx, key, data, ttl).Err(); err != nil {
+				log.Printf("cache: store %s: %v", key, err)
+			}
+		})
+	}
+}
+
+func setCacheControl(w http.ResponseWriter, r *http.Request, ttl time.Duration) {
+	visibility := "public"
+	if r.Header.Get("Authorization") != "" {
+		visibility = "private"
+	}
+	w.Header().Set("Cache-Control", visibility+", max-age="+strconv.Itoa(int(ttl.Seconds())))
+	w.Header().Add("Vary", "Authorization")
+	w.Header().Add("Vary", "X-Tenant-ID")
+	w.Header().Add("Vary", "X-User-ID")
+}
+
+func loadResponse(ctx context.Context, redisClient deps.Cache, key string) (cachedResponse, bool) {
+	var cached cachedResponse
+	data, err := redisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		return cached, false
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return cached, false
+	}
+	return cached, true
+}
+
+// recordWriter пропускает ответ клиенту, копируя тело, пока оно не
+// превысит maxCachedBody. Неуспешные ответы помечаются no-store.
+type recordWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (rw *recordWriter) WriteHeader(status int) {
+	rw.status = status
+	if status != http.StatusOK {
+		rw.Header().Set("Cache-Control", "no-store")
+		rw.Header().Del("Age")
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordWriter) Write(p []byte) (int, error) {
+	if !rw.overflow {
+		if rw.body.Len()+len(p) > maxCachedBody {
+			rw.overflow = true
+			rw.body = bytes.Buffer{}
+		} else {
+			rw.body.Write(p)
+		}
+	}
+	return rw.ResponseWriter.Write(p)
+}
+
+func (rw *recordWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *recordWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/localcache"
+)
+
+func TestCache(t *testing.T) {
+	store := localcache.New(time.Minute)
+	defer store.Close()
+
+	calls := 0
+	h := Cache(store, time.Minute, func(r *http.Request) string { return r.Header.Get("X-User-ID") })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, `{"calls":`+strconv.Itoa(calls)+`}`)
+		}))
+	get := func(target string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/goods/list?limit=10&offset=0", "X-User-ID", "1")
+	if w.Header().Get("X-Cache") != "MISS" || w.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("first request: X-Cache = %q, Cache-Control = %q", w.Header().Get("X-Cache"), w.Header().Get("Cache-Control"))
+	}
+
+	// Порядок параметров не влияет на ключ.
+	w = get("/goods/list?offset=0&limit=10", "X-User-ID", "1")
+	if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != `{"calls":1}` || calls != 1 {
+		t.Fatalf("repeat: X-Cache = %q, body %s, calls = %d", w.Header().Get("X-Cache"), w.Body, calls)
+	}
+	if w.Header().Get("Content-Type") != "application/json" || w.Header().Get("Age") == "" {
+		t.Errorf("hit headers = %v", w.Header())
+	}
+
+	// Другой пользователь и no-cache идут в обработчик.
+	if w = get("/goods/list?limit=10&offset=0", "X-User-ID", "2"); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("other principal: X-Cache = %q", w.Header().Get("X-Cache"))
+	}
+	if w = get("/goods/list?limit=10&offset=0", "X-User-ID", "1", "Cache-Control", "no-cache"); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("no-cache: X-Cache = %q", w.Header().Get("X-Cache"))
+	}
+
+	if w = get("/goods/list", "Authorization", "Bearer token"); !strings.HasPrefix(w.Header().Get("Cache-Control"), "private") {
+		t.Errorf("authorized: Cache-Control = %q", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestCacheSkipsErrors(t *testing.T) {
+	store := localcache.New(time.Minute)
+	defer store.Close()
+
+	calls := 0
+	h := Cache(store, time.Minute, func(*http.Request) string { return "" })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			http.Error(w, "db down", http.StatusServiceUnavailable)
+		}))
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goods/list", nil))
+		if w.Header().Get("Cache-Control") != "no-store" || w.Header().Get("Age") != "" {
+			t.Errorf("error response headers = %v", w.Header())
+		}
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, error responses must not be cached", calls)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/good/create", nil))
+	if w.Header().Get("X-Cache") != "" {
+		t.Errorf("POST went through the cache: %v", w.Header())
+	}
+}
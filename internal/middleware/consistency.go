@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	ConsistencyHeader      = "X-Consistency"
+	ConsistencyTokenHeader = "X-Consistency-Token"
+)
+
+type strongCtxKey struct{}
+
+// Consistency обеспечивает чтение своих записей. Ответ на изменяющий запрос
+// несёт X-Consistency-Token с моментом записи; пока клиент возвращает его в
+// том же заголовке и с записи прошло меньше window, чтения идут мимо кэша.
+// X-Consistency: strong обходит кэш без токена.
+func Consistency(window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				w.Header().Set(ConsistencyTokenHeader, strconv.FormatInt(time.Now().UnixMilli(), 10))
+			}
+
+			strong := r.Header.Get(ConsistencyHeader) == "strong"
+			if token := r.Header.Get(ConsistencyTokenHeader); token != "" && !strong {
+				if ms, err := strconv.ParseInt(token, 10, 64); err == nil {
+					strong = time.Since(time.UnixMilli(ms)) < window
+				}
+			}
+			if strong {
+				r = r.WithContext(context.WithValue(r.Context(), strongCtxKey{}, true))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Strong сообщает, что запрос должен читать данные мимо кэша.
+func Strong(ctx context.Context) bool {
+	strong, _ := ctx.Value(strongCtxKey{}).(bool)
+	return strong
+}
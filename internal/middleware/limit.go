@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"hezzl-test/internal/response"
+)
+
+// Limit пропускает не больше inFlight запросов одновременно через все
+// обёрнутые им маршруты. Ещё до queue запросов ждут освобождения места не
+// дольше wait; остальные и не дождавшиеся сразу получают 503 с Retry-After.
+func Limit(inFlight, queue int, wait time.Duration) func(http.Handler) http.Handler {
+	slots := make(chan struct{}, inFlight)
+	waiting := make(chan struct{}, queue)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				if !acquire(r, slots, waiting, wait) {
+					w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds()+1)))
+					response.Error(w, r, http.StatusServiceUnavailable, response.CodeUnavailable, "errors.server.busy")
+					return
+				}
+			}
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func acquire(r *http.Request, slots, waiting chan struct{}, wait time.Duration) bool {
+	select {
+	case waiting <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-waiting }()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimit(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 4)
+	h := Limit(1, 1, time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	serve := func() <-chan int {
+		code := make(chan int, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goods/list", nil))
+			code <- w.Code
+		}()
+		return code
+	}
+
+	first := serve()
+	<-started
+	queued := serve()
+	time.Sleep(20 * time.Millisecond)
+
+	// Очередь занята вторым запросом; третий отклоняется сразу.
+	start := time.Now()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goods/list", nil))
+	if w.Code != http.StatusServiceUnavailable || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("request over the queue: status %d after %s", w.Code, time.Since(start))
+	}
+	if w.Header().Get("Retry-After") != "2" {
+		t.Errorf("Retry-After = %q", w.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("first: %d", code)
+	}
+	if code := <-queued; code != http.StatusOK {
+		t.Errorf("queued: %d, want it to get the freed slot", code)
+	}
+}
+
+func TestLimitWait(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	h := Limit(1, 1, 20*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/goods/list", nil))
+	time.Sleep(10 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goods/list", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d after wait, want 503", w.Code)
+	}
+
+	// Отменённый клиент не держит место в очереди до конца wait.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goods/list", nil).WithContext(ctx))
+	if w.Code != http.StatusServiceUnavailable || time.Since(start) > 15*time.Millisecond {
+		t.Errorf("cancelled request: status %d after %s", w.Code, time.Since(start))
+	}
+}
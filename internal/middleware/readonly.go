@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"hezzl-test/internal/response"
+)
+
+// ReadOnly отклоняет с 503 запросы к изменяющему маршруту, если экземпляр —
+// реплика только для чтения, чтобы балансировщик отправил их на основной.
+// При enabled == false маршрут работает как обычно.
+func ReadOnly(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response.Error(w, r, http.StatusServiceUnavailable, response.CodeUnavailable, "errors.server.readOnly")
+		})
+	}
+}
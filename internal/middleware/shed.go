@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"hezzl-test/internal/response"
+)
+
+// Shed отклоняет с 503 долю fraction запросов, пока degraded возвращает
+// true. Им оборачиваются маршруты, без которых можно обойтись, пока
+// зависимость медленная: так запас остаётся основным запросам.
+func Shed(degraded func() bool, fraction float64, retryAfter time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if degraded() && rand.Float64() < fraction {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+				response.Error(w, r, http.StatusServiceUnavailable, response.CodeUnavailable, "errors.server.degraded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
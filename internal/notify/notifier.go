@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"hezzl-test/internal/tenant"
+
+	"github.com/nats-io/nats.go"
+)
+
+var subjects = map[string]string{
+	"new_good_created":   "created",
+	"good_updated":       "updated",
+	"good_deleted":       "deleted",
+	"good_reprioritized": "reprioritized",
+}
+
+type Rule struct {
+	Channel string
+	Target  string
+}
+
+type event struct {
+	ID          int    `json:"id"`
+	ProjectID   int    `json:"project_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Priority    int    `json:"priority"`
+}
+
+// Notifier рассылает события товаров в Slack и Telegram по правилам
+// из таблицы notification_rules. Правило срабатывает, если событие
+// указано в его списке events или список пуст и уведомления проекта не
+// выключены в project_settings.
+type Notifier struct {
+	db            *sql.DB
+	client        *http.Client
+	telegramToken string
+	subs          []*nats.Subscription
+}
+
+// NewNotifier отправляет уведомления через transport — как правило,
+// egress.NewTransport, потому что адреса вебхуков задают пользователи.
+func NewNotifier(db *sql.DB, telegramToken string, transport http.RoundTripper) *Notifier {
+	return &Notifier{
+		db:            db,
+		client:        &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		telegramToken: telegramToken,
+	}
+}
+
+func (n *Notifier) Start(natsConn *nats.Conn) error {
+	for subject := range subjects {
+		sub, err := natsConn.Subscribe(subject, n.handle)
+		if err != nil {
+			n.Stop()
+			return err
+		}
+		n.subs = append(n.subs, sub)
+	}
+	return nil
+}
+
+func (n *Notifier) Stop() {
+	for _, sub := range n.subs {
+		sub.Unsubscribe()
+	}
+	n.subs = nil
+}
+
+func (n *Notifier) handle(msg *nats.Msg) {
+	var e event
+	if err := json.Unmarshal(msg.Data, &e); err != nil || e.ProjectID == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tenantID, _ := strconv.Atoi(msg.Header.Get(tenant.NATSHeader))
+	rules, err := n.rules(ctx, tenantID, e.ProjectID, msg.Subject)
+	if err != nil {
+		log.Printf("notifier: load rules for project %d: %v", e.ProjectID, err)
+		return
+	}
+
+	text := format(msg.Subject, e)
+	for _, rule := range rules {
+		if err := n.send(ctx, rule, text); err != nil {
+			log.Printf("notifier: %s %s: %v", rule.Channel, rule.Target, err)
+		}
+	}
+}
+
+func (n *Notifier) rules(ctx context.Context, tenantID, projectID int, subject string) ([]Rule, error) {
+	rows, err := n.db.QueryContext(ctx, `SELECT r.channel, r.target
+		FROM notification_rules r JOIN projects p ON p.id = r.project_id
+		LEFT JOIN project_settings s ON s.project_id = r.project_id
+		WHERE r.project_id = $1 AND p.tenant_id = $2 AND r.enabled AND COALESCE(s.webhooks_enabled, true)
+			AND (cardinality(r.events) = 0 OR $3 = ANY(r.events))`,
+		projectID, tenantID, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var rule Rule
+		if err := rows.Scan(&rule.Channel, &rule.Target); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (n *Notifier) send(ctx context.Context, rule Rule, text string) error {
+	switch rule.Channel {
+	case "slack":
+		return n.post(ctx, rule.Target, map[string]string{"text": text})
+	case "telegram":
+		if n.telegramToken == "" {
+			return fmt.Errorf("telegram bot token is not configured")
+		}
+		return n.post(ctx, "https://api.telegram.org/bot"+n.telegramToken+"/sendMessage",
+			map[string]string{"chat_id": rule.Target, "text": text})
+	default:
+		return fmt.Errorf("unknown channel %q", rule.Channel)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+	}
+	return nil
+}
+
+func format(subject string, e event) string {
+	action, ok := subjects[subject]
+	if !ok {
+		action = subject
+	}
+	text := fmt.Sprintf("Good #%d %q %s in project %d", e.ID, e.Name, action, e.ProjectID)
+	if subject == "good_reprioritized" || subject == "new_good_created" {
+		text += fmt.Sprintf(" (priority %d)", e.Priority)
+	}
+	return text
+}
@@ -0,0 +1,89 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Типы компактного протокола Thrift.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter кодирует структуры метаданных компактным протоколом
+// Thrift. Поля пишутся по возрастанию id, вложенные структуры — между
+// beginStruct и endStruct.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) varint(n int64) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(n<<1^n>>63)))
+}
+
+func (t *thriftWriter) i32(n int32) {
+	t.varint(int64(n))
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) i32Field(id int16, n int32) {
+	t.field(id, thriftI32)
+	t.i32(n)
+}
+
+func (t *thriftWriter) i64Field(id int16, n int64) {
+	t.field(id, thriftI64)
+	t.varint(n)
+}
+
+func (t *thriftWriter) binaryField(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.binary(s)
+}
+
+// structField открывает поле-структуру; её закрывает endStruct.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.beginStruct()
+}
+
+// listField пишет заголовок списка из size элементов типа elem; элементы
+// пишутся следом без заголовков полей.
+func (t *thriftWriter) listField(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xF0 | elem)
+		t.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+	}
+}
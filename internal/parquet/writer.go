@@ -0,0 +1,391 @@
+// Package parquet пишет плоские таблицы в формате Apache Parquet без
+// сжатия: каждая группа строк хранит по одной странице данных на колонку в
+// кодировке PLAIN, метаданные кодируются компактным протоколом Thrift.
+// Этого достаточно, чтобы файлы читали Spark, DuckDB, ClickHouse и Athena.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const magic = "PAR1"
+
+// Type — логический тип колонки.
+type Type int
+
+const (
+	Boolean Type = iota
+	Int32
+	Int64
+	// String — BYTE_ARRAY с аннотацией UTF8.
+	String
+	// JSON — BYTE_ARRAY с аннотацией JSON.
+	JSON
+	// Timestamp — INT64 с микросекундами от эпохи в UTC.
+	Timestamp
+)
+
+// Column описывает колонку; Optional допускает nil в значениях.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Физические типы, аннотации и прочие константы формата.
+const (
+	physBoolean   = 0
+	physInt32     = 1
+	physInt64     = 2
+	physByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+	convertedJSON            = 19
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageData = 0
+)
+
+func (t Type) physical() int32 {
+	switch t {
+	case Boolean:
+		return physBoolean
+	case Int32:
+		return physInt32
+	case Int64, Timestamp:
+		return physInt64
+	default:
+		return physByteArray
+	}
+}
+
+// Writer пишет строки группами по rowGroupSize. Строки группы держатся в
+// памяти до её записи.
+type Writer struct {
+	w            io.Writer
+	offset       int64
+	columns      []Column
+	rowGroupSize int
+
+	values    []bytes.Buffer
+	bits      [][]bool
+	defined   [][]bool
+	rows      int
+	totalRows int64
+	groups    []rowGroup
+	err       error
+}
+
+type rowGroup struct {
+	rows    int64
+	size    int64
+	columns []columnChunk
+}
+
+type columnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+func NewWriter(w io.Writer, columns []Column, rowGroupSize int) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	if rowGroupSize <= 0 {
+		return nil, errors.New("parquet: row group size must be positive")
+	}
+	pw := &Writer{
+		w:            w,
+		columns:      columns,
+		rowGroupSize: rowGroupSize,
+		values:       make([]bytes.Buffer, len(columns)),
+		bits:         make([][]bool, len(columns)),
+		defined:      make([][]bool, len(columns)),
+	}
+	pw.write([]byte(magic))
+	return pw, pw.err
+}
+
+// Write добавляет строку. Значения идут в порядке колонок: bool для
+// Boolean, int или int32 для Int32, int или int64 для Int64, string или
+// []byte для String и JSON, time.Time для Timestamp; nil — только для
+// Optional.
+func (pw *Writer) Write(row ...interface{}) error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("parquet: %d values for %d columns", len(row), len(pw.columns))
+	}
+	for i, c := range pw.columns {
+		if err := pw.append(i, c, row[i]); err != nil {
+			// Колонки до i уже получили значения строки, файл дальше не пишется.
+			pw.err = err
+			return err
+		}
+	}
+	pw.rows++
+	if pw.rows == pw.rowGroupSize {
+		pw.flush()
+	}
+	return pw.err
+}
+
+func (pw *Writer) append(i int, c Column, v interface{}) error {
+	if v == nil {
+		if !c.Optional {
+			return fmt.Errorf("parquet: column %s is required", c.Name)
+		}
+		pw.defined[i] = append(pw.defined[i], false)
+		return nil
+	}
+	if c.Optional {
+		pw.defined[i] = append(pw.defined[i], true)
+	}
+
+	buf := &pw.values[i]
+	var scratch [8]byte
+	switch c.Type {
+	case Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return typeError(c, v)
+		}
+		pw.bits[i] = append(pw.bits[i], b)
+	case Int32:
+		var n int64
+		switch x := v.(type) {
+		case int:
+			n = int64(x)
+		case int32:
+			n = int64(x)
+		default:
+			return typeError(c, v)
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return fmt.Errorf("parquet: column %s: %d overflows int32", c.Name, n)
+		}
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(int32(n)))
+		buf.Write(scratch[:4])
+	case Int64, Timestamp:
+		var n int64
+		switch x := v.(type) {
+		case int:
+			n = int64(x)
+		case int64:
+			n = x
+		case time.Time:
+			if c.Type != Timestamp {
+				return typeError(c, v)
+			}
+			n = x.UnixMicro()
+		default:
+			return typeError(c, v)
+		}
+		binary.LittleEndian.PutUint64(scratch[:], uint64(n))
+		buf.Write(scratch[:])
+	case String, JSON:
+		var data []byte
+		switch x := v.(type) {
+		case string:
+			data = []byte(x)
+		case []byte:
+			data = x
+		default:
+			return typeError(c, v)
+		}
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(len(data)))
+		buf.Write(scratch[:4])
+		buf.Write(data)
+	}
+	return nil
+}
+
+func typeError(c Column, v interface{}) error {
+	return fmt.Errorf("parquet: column %s: unsupported value %T", c.Name, v)
+}
+
+// Close записывает последнюю группу строк и метаданные файла. Нижележащий
+// io.Writer не закрывается.
+func (pw *Writer) Close() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if pw.rows > 0 {
+		pw.flush()
+	}
+	if pw.err != nil {
+		return pw.err
+	}
+
+	var footer thriftWriter
+	pw.fileMetaData(&footer)
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(footer.buf.Len()))
+	pw.write(footer.buf.Bytes())
+	pw.write(size[:])
+	pw.write([]byte(magic))
+	return pw.err
+}
+
+func (pw *Writer) write(p []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+// flush пишет накопленные строки группой: по странице на колонку.
+func (pw *Writer) flush() {
+	group := rowGroup{rows: int64(pw.rows), columns: make([]columnChunk, len(pw.columns))}
+	for i, c := range pw.columns {
+		var page bytes.Buffer
+		if c.Optional {
+			levels := encodeLevels(pw.defined[i])
+			var size [4]byte
+			binary.LittleEndian.PutUint32(size[:], uint32(len(levels)))
+			page.Write(size[:])
+			page.Write(levels)
+		}
+		if c.Type == Boolean {
+			page.Write(packBits(pw.bits[i]))
+		} else {
+			page.Write(pw.values[i].Bytes())
+		}
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32Field(1, pageData)
+		header.i32Field(2, int32(page.Len()))
+		header.i32Field(3, int32(page.Len()))
+		header.structField(5)
+		header.i32Field(1, int32(pw.rows))
+		header.i32Field(2, encodingPlain)
+		header.i32Field(3, encodingRLE)
+		header.i32Field(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		start := pw.offset
+		pw.write(header.buf.Bytes())
+		pw.write(page.Bytes())
+		group.columns[i] = columnChunk{offset: start, size: pw.offset - start, values: int64(pw.rows)}
+		group.size += pw.offset - start
+
+		pw.values[i].Reset()
+		pw.bits[i] = pw.bits[i][:0]
+		pw.defined[i] = pw.defined[i][:0]
+	}
+	pw.groups = append(pw.groups, group)
+	pw.totalRows += int64(pw.rows)
+	pw.rows = 0
+}
+
+func (pw *Writer) fileMetaData(t *thriftWriter) {
+	t.beginStruct()
+	t.i32Field(1, 1)
+
+	t.listField(2, thriftStruct, len(pw.columns)+1)
+	t.beginStruct()
+	t.binaryField(4, "schema")
+	t.i32Field(5, int32(len(pw.columns)))
+	t.endStruct()
+	for _, c := range pw.columns {
+		t.beginStruct()
+		t.i32Field(1, c.Type.physical())
+		repetition := int32(repetitionRequired)
+		if c.Optional {
+			repetition = repetitionOptional
+		}
+		t.i32Field(3, repetition)
+		t.binaryField(4, c.Name)
+		switch c.Type {
+		case String:
+			t.i32Field(6, convertedUTF8)
+		case JSON:
+			t.i32Field(6, convertedJSON)
+		case Timestamp:
+			t.i32Field(6, convertedTimestampMicros)
+		}
+		t.endStruct()
+	}
+
+	t.i64Field(3, pw.totalRows)
+
+	t.listField(4, thriftStruct, len(pw.groups))
+	for _, g := range pw.groups {
+		t.beginStruct()
+		t.listField(1, thriftStruct, len(g.columns))
+		for i, chunk := range g.columns {
+			c := pw.columns[i]
+			t.beginStruct()
+			t.i64Field(2, chunk.offset)
+			t.structField(3)
+			t.i32Field(1, c.Type.physical())
+			t.listField(2, thriftI32, 2)
+			t.i32(encodingPlain)
+			t.i32(encodingRLE)
+			t.listField(3, thriftBinary, 1)
+			t.binary(c.Name)
+			t.i32Field(4, 0)
+			t.i64Field(5, chunk.values)
+			t.i64Field(6, chunk.size)
+			t.i64Field(7, chunk.size)
+			t.i64Field(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64Field(2, g.size)
+		t.i64Field(3, g.rows)
+		t.endStruct()
+	}
+
+	t.binaryField(6, "hezzl-test")
+	t.endStruct()
+}
+
+// encodeLevels кодирует уровни определения шириной в бит гибридом
+// RLE/bit-packing, используя только RLE-серии.
+func encodeLevels(defined []bool) []byte {
+	var out []byte
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if defined[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+// packBits упаковывает булевы значения по биту, начиная с младшего.
+func packBits(values []bool) []byte {
+	out := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
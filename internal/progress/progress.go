@@ -0,0 +1,175 @@
+// Package progress хранит ход асинхронных заданий и раздаёт его
+// подписчикам, например потоку SSE для интерфейса загрузки.
+//
+// Задания живут в памяти экземпляра, который их выполняет, поэтому следить
+// за заданием можно только через этот экземпляр.
+package progress
+
+import (
+	"sync"
+	"time"
+
+	"hezzl-test/internal/clock"
+)
+
+// Типы событий задания.
+const (
+	EventProgress = "progress"
+	EventWarning  = "warning"
+	EventSummary  = "summary"
+)
+
+// Сколько предупреждений задание помнит для новых подписчиков; остальные
+// получают только те, кто уже подписан.
+const maxWarnings = 100
+
+// Сколько событий ждут медленного подписчика; при переполнении события
+// хода ему не доставляются, последнее можно получить заново подпиской.
+const subscriberBuffer = 64
+
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// Tracker выдаёт задания и находит их по id. Завершённые задания хранятся
+// ttl, чтобы подписчик успел забрать итог.
+type Tracker struct {
+	ttl   time.Duration
+	clock clock.Clock
+	ids   clock.IDGenerator
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func New(ttl time.Duration, c clock.Clock, ids clock.IDGenerator) *Tracker {
+	return &Tracker{ttl: ttl, clock: c, ids: ids, jobs: make(map[string]*Job)}
+}
+
+// Start заводит задание арендатора и попутно забывает завершённые больше
+// ttl назад.
+func (t *Tracker) Start(tenantID int) *Job {
+	job := &Job{ID: t.ids.NewID(), tenantID: tenantID, clock: t.clock, subs: make(map[chan Event]struct{})}
+
+	now := t.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, j := range t.jobs {
+		if finished := j.finishedAt(); !finished.IsZero() && now.Sub(finished) > t.ttl {
+			delete(t.jobs, id)
+		}
+	}
+	t.jobs[job.ID] = job
+	return job
+}
+
+// Get возвращает задание арендатора; чужие задания не находятся.
+func (t *Tracker) Get(tenantID int, id string) (*Job, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[id]
+	if !ok || job.tenantID != tenantID {
+		return nil, false
+	}
+	return job, true
+}
+
+type Job struct {
+	ID       string
+	tenantID int
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	progress *Event
+	warnings []Event
+	summary  *Event
+	finished time.Time
+	subs     map[chan Event]struct{}
+}
+
+// Progress сообщает ход задания; новые подписчики получают последний.
+func (j *Job) Progress(data interface{}) {
+	e := Event{Type: EventProgress, Data: data}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress = &e
+	j.send(e)
+}
+
+// Warn сообщает о проблеме, которая не прерывает задание.
+func (j *Job) Warn(data interface{}) {
+	e := Event{Type: EventWarning, Data: data}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.warnings) < maxWarnings {
+		j.warnings = append(j.warnings, e)
+	}
+	j.send(e)
+}
+
+// Finish завершает задание итогом data и закрывает каналы подписчиков.
+func (j *Job) Finish(data interface{}) {
+	e := Event{Type: EventSummary, Data: data}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.summary != nil {
+		return
+	}
+	j.summary = &e
+	j.finished = j.clock.Now()
+	for ch := range j.subs {
+		close(ch)
+	}
+	j.subs = nil
+}
+
+// Subscribe возвращает уже известные события — предупреждения и последний
+// ход — и канал следующих. Канал закрывается по завершении задания (у
+// завершённого — сразу), итог тогда отдаёт Summary. cancel отписывает и
+// должен быть вызван.
+func (j *Job) Subscribe() (history []Event, events <-chan Event, cancel func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	history = append(history, j.warnings...)
+	if j.progress != nil {
+		history = append(history, *j.progress)
+	}
+	ch := make(chan Event, subscriberBuffer)
+	if j.summary != nil {
+		close(ch)
+		return history, ch, func() {}
+	}
+	j.subs[ch] = struct{}{}
+	return history, ch, func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		delete(j.subs, ch)
+	}
+}
+
+// Summary — итог завершённого задания.
+func (j *Job) Summary() (Event, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.summary == nil {
+		return Event{}, false
+	}
+	return *j.summary, true
+}
+
+func (j *Job) finishedAt() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finished
+}
+
+func (j *Job) send(e Event) {
+	for ch := range j.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
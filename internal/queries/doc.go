@@ -0,0 +1,11 @@
+// Package queries — код запросов к Postgres, сгенерированный sqlc из
+// internal/queries/sql по схеме из migrations/postgres. Столбцы и типы
+// проверяются при генерации, поэтому списки Scan не расходятся со схемой.
+// После изменения запросов или миграций нужно перегенерировать код:
+// проверка sqlc в CI падает, если sqlc generate меняет дерево.
+//
+// Здесь только запросы с постоянным текстом. Список товаров собирается из
+// селектора меток, сортировки и фильтров на лету и остаётся в queryGoodsPage.
+package queries
+
+//go:generate sqlc generate -f ../../sqlc.yaml
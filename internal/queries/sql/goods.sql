@@ -0,0 +1,41 @@
+-- name: GetGood :one
+SELECT * FROM goods WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListGoodsByIDs :many
+SELECT * FROM goods WHERE id = ANY(sqlc.arg(ids)::int[]) AND tenant_id = sqlc.arg(tenant_id);
+
+-- name: LockGood :one
+SELECT * FROM goods WHERE id = $1 AND tenant_id = $2 FOR UPDATE;
+
+-- name: UpdateGood :exec
+UPDATE goods SET name = sqlc.arg(name), description = sqlc.arg(description), priority = sqlc.arg(priority),
+    removed = sqlc.arg(removed), removed_at = CASE WHEN sqlc.arg(removed) THEN COALESCE(removed_at, now()) END,
+    labels = COALESCE(sqlc.narg(labels)::jsonb, labels)
+WHERE id = sqlc.arg(id) AND project_id = sqlc.arg(project_id);
+
+-- name: CountTrash :one
+SELECT COUNT(*) FROM goods
+WHERE tenant_id = $1 AND project_id = $2 AND removed AND project_id NOT IN (SELECT id FROM projects WHERE removed);
+
+-- name: ListTrash :many
+SELECT sqlc.embed(goods), COALESCE(removed_at, created_at)::timestamp AS trashed_at
+FROM goods WHERE tenant_id = $1 AND project_id = $2 AND removed AND project_id NOT IN (SELECT id FROM projects WHERE removed)
+ORDER BY removed_at DESC NULLS LAST, id
+LIMIT $3 OFFSET $4;
+
+-- name: RestoreGoods :many
+UPDATE goods SET removed = false, removed_at = NULL, version = version + 1
+WHERE id = ANY(sqlc.arg(ids)::int[]) AND tenant_id = sqlc.arg(tenant_id) AND removed
+    AND project_id NOT IN (SELECT id FROM projects WHERE archived OR removed)
+RETURNING *;
+
+-- name: TrashObjectKeys :many
+SELECT a.object_key FROM good_attachments a JOIN goods g ON g.id = a.good_id
+WHERE g.id = ANY(sqlc.arg(ids)::int[]) AND g.tenant_id = sqlc.arg(tenant_id) AND g.removed
+    AND NOT (sqlc.arg(writable_only)::boolean AND g.project_id IN (SELECT id FROM projects WHERE archived OR removed));
+
+-- name: PurgeGoods :many
+DELETE FROM goods
+WHERE id = ANY(sqlc.arg(ids)::int[]) AND tenant_id = sqlc.arg(tenant_id) AND removed
+    AND NOT (sqlc.arg(writable_only)::boolean AND project_id IN (SELECT id FROM projects WHERE archived OR removed))
+RETURNING id, project_id, (version + 1)::bigint AS version;
@@ -0,0 +1,5 @@
+-- name: ListProjects :many
+SELECT id, name, created_at FROM projects WHERE tenant_id = $1 AND NOT removed ORDER BY id;
+
+-- name: CreateProject :exec
+INSERT INTO projects (tenant_id, name) VALUES ($1, $2);
@@ -0,0 +1,8 @@
+-- name: ListTenants :many
+SELECT id, name, created_at FROM tenants ORDER BY id;
+
+-- name: CreateTenant :one
+INSERT INTO tenants (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id, created_at;
+
+-- name: TenantIDs :many
+SELECT id FROM tenants ORDER BY id;
@@ -0,0 +1,43 @@
+package response
+
+import (
+	"context"
+	"net/http"
+)
+
+// CompatHeader переключает формат ответов: "legacy" отдаёт прежние формы
+// (списки — голым массивом, ошибки — текстом), "current" — новые конверты.
+const CompatHeader = "X-API-Compat"
+
+// Legacy реализуют ответы, у которых есть прежняя форма.
+type Legacy interface {
+	Legacy() interface{}
+}
+
+type compatCtxKey struct{}
+
+// Compat определяет формат ответов запроса по заголовку X-API-Compat; без
+// заголовка используется legacyDefault.
+func Compat(legacyDefault bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			legacy := legacyDefault
+			switch r.Header.Get(CompatHeader) {
+			case "legacy":
+				legacy = true
+			case "current":
+				legacy = false
+			}
+			w.Header().Add("Vary", CompatHeader)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), compatCtxKey{}, legacy)))
+		})
+	}
+}
+
+func IsLegacy(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	legacy, _ := r.Context().Value(compatCtxKey{}).(bool)
+	return legacy
+}
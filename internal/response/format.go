@@ -0,0 +1,251 @@
+package response
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatHeader задаёт для запроса именование полей и формат времени в
+// ответе, например "naming=camel; time=unix".
+const FormatHeader = "X-API-Format"
+
+const (
+	NamingSnake = "snake"
+	NamingCamel = "camel"
+
+	TimeRFC3339       = "rfc3339"
+	TimeRFC3339Millis = "rfc3339ms"
+	TimeUnix          = "unix"
+	TimeUnixMillis    = "unixms"
+)
+
+// FormatOptions описывает форму JSON-ответов. Сервис кодирует ответы в
+// snake_case с временем в RFC3339, остальные варианты получаются
+// преобразованием уже закодированного JSON.
+type FormatOptions struct {
+	Naming string
+	Time   string
+}
+
+var canonicalFormat = FormatOptions{Naming: NamingSnake, Time: TimeRFC3339}
+
+// timeFields — поля ответов со временем помимо *_at и *At.
+var timeFields = map[string]bool{
+	"bucket":      true,
+	"from":        true,
+	"to":          true,
+	"time":        true,
+	"last_start":  true,
+	"last_finish": true,
+	"next_run":    true,
+}
+
+// verbatimFields — поля-словари с ключами пользователя, которые не
+// переименовываются.
+var verbatimFields = map[string]bool{
+	"labels":  true,
+	"details": true,
+}
+
+type formatCtxKey struct{}
+
+// Format определяет форму ответов запроса по заголовку X-API-Format;
+// незаданные в нём параметры берутся из defaults.
+func Format(defaults FormatOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opts, err := parseFormat(r.Header.Get(FormatHeader), defaults)
+			w.Header().Add("Vary", FormatHeader)
+			if err != nil {
+				BadRequest(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), formatCtxKey{}, opts)))
+		})
+	}
+}
+
+func parseFormat(header string, opts FormatOptions) (FormatOptions, error) {
+	for _, part := range strings.FieldsFunc(header, func(c rune) bool { return c == ';' || c == ',' }) {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch value = strings.ToLower(strings.TrimSpace(value)); strings.ToLower(strings.TrimSpace(name)) {
+		case "naming":
+			if value != NamingSnake && value != NamingCamel {
+				return opts, fmt.Errorf("invalid %s naming %q", FormatHeader, value)
+			}
+			opts.Naming = value
+		case "time":
+			if value != TimeRFC3339 && value != TimeRFC3339Millis && value != TimeUnix && value != TimeUnixMillis {
+				return opts, fmt.Errorf("invalid %s time %q", FormatHeader, value)
+			}
+			opts.Time = value
+		default:
+			return opts, fmt.Errorf("invalid %s parameter %q", FormatHeader, name)
+		}
+	}
+	return opts, nil
+}
+
+func formatOf(r *http.Request) FormatOptions {
+	if r == nil {
+		return canonicalFormat
+	}
+	opts, ok := r.Context().Value(formatCtxKey{}).(FormatOptions)
+	if !ok {
+		return canonicalFormat
+	}
+	return opts
+}
+
+// CanonicalFormat сообщает, что ответ запроса кодируется без
+// преобразований; только такие ответы можно класть в общие кэши.
+func CanonicalFormat(r *http.Request) bool {
+	return formatOf(r) == canonicalFormat
+}
+
+// FormatKey различает формы ответов в ключах кэшей.
+func FormatKey(r *http.Request) string {
+	opts := formatOf(r)
+	return opts.Naming + "-" + opts.Time
+}
+
+// Reformat приводит закодированный JSON к форме, запрошенной r. Порядок
+// полей сохраняется; с параметром pretty результат выравнивается.
+func Reformat(r *http.Request, data []byte) ([]byte, error) {
+	opts := formatOf(r)
+	if opts == canonicalFormat {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out bytes.Buffer
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if err := opts.value(dec, &out, "", tok); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+	}
+	if r.URL.Query().Has("pretty") {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, out.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		return pretty.Bytes(), nil
+	}
+	return out.Bytes(), nil
+}
+
+// value переписывает значение поля key, первым токеном которого является tok.
+func (o FormatOptions) value(dec *json.Decoder, out *bytes.Buffer, key string, tok json.Token) error {
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			out.WriteByte('[')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				next, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := o.value(dec, out, key, next); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(']')
+		} else {
+			out.WriteByte('{')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				next, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				field, _ := next.(string)
+				name := field
+				if !verbatimFields[key] && o.Naming == NamingCamel {
+					name = camelCase(field)
+				}
+				writeJSON(out, name)
+				out.WriteByte(':')
+				if verbatimFields[key] {
+					field = ""
+				}
+				if next, err = dec.Token(); err != nil {
+					return err
+				}
+				if err := o.value(dec, out, field, next); err != nil {
+					return err
+				}
+			}
+			out.WriteByte('}')
+		}
+		_, err := dec.Token()
+		return err
+	case string:
+		if isTimeField(key) {
+			if ts, err := time.Parse(time.RFC3339Nano, t); err == nil {
+				switch o.Time {
+				case TimeRFC3339Millis:
+					t = ts.Format("2006-01-02T15:04:05.000Z07:00")
+				case TimeUnix:
+					out.WriteString(strconv.FormatInt(ts.Unix(), 10))
+					return nil
+				case TimeUnixMillis:
+					out.WriteString(strconv.FormatInt(ts.UnixMilli(), 10))
+					return nil
+				}
+			}
+		}
+		writeJSON(out, t)
+	case json.Number:
+		out.WriteString(t.String())
+	default:
+		writeJSON(out, t)
+	}
+	return nil
+}
+
+func isTimeField(key string) bool {
+	return strings.HasSuffix(key, "_at") || strings.HasSuffix(key, "At") || timeFields[key]
+}
+
+// camelCase переводит created_at в createdAt; имена без "_" не меняются.
+func camelCase(name string) string {
+	if !strings.Contains(name, "_") {
+		return name
+	}
+	var b strings.Builder
+	upper := false
+	for _, c := range name {
+		switch {
+		case c == '_':
+			upper = b.Len() > 0
+		case upper:
+			b.WriteString(strings.ToUpper(string(c)))
+			upper = false
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func writeJSON(out *bytes.Buffer, v interface{}) {
+	data, _ := json.Marshal(v)
+	out.Write(data)
+}
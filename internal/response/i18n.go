@@ -0,0 +1,132 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// messages — тексты ошибок по ключу message для языков, которые можно
+// запросить через Accept-Language. Ключ без перевода отдаётся как есть.
+var messages = map[string]map[string]string{
+	"en": {
+		"errors.internal":               "Internal server error.",
+		"errors.request.invalid":        "The request is invalid.",
+		"errors.notFound":               "The requested object was not found.",
+		"errors.conflict":               "The request conflicts with the current state.",
+		"errors.forbidden":              "The request is not allowed.",
+		"errors.route.notFound":         "The requested route does not exist.",
+		"errors.route.methodNotAllowed": "The method is not allowed for this route.",
+		"errors.tenant.invalid":         "The tenant of the request is invalid.",
+		"errors.tenant.exists":          "A tenant with this name already exists.",
+		"errors.user.required":          "The request must be made on behalf of a user.",
+		"errors.server.busy":            "The server is busy, please retry later.",
+		"errors.admin.unauthorized":     "A valid admin token is required.",
+		"errors.server.degraded":        "The service is degraded and is serving essential requests only, please retry later.",
+		"errors.server.readOnly":        "This instance is a read-only replica, send changes to the primary.",
+		"errors.dependency.unavailable": "A required service is temporarily unavailable.",
+		"errors.project.notFound":       "The project was not found.",
+		"errors.project.archived":       "The project is archived and cannot be changed.",
+		"errors.project.removed":        "The project is removed and cannot be changed.",
+		"errors.project.quotaExceeded":  "The project has reached its goods limit.",
+		"errors.good.notFound":          "The good was not found.",
+		"errors.good.duplicate":         "A good with a similar name already exists.",
+		"errors.good.priorityTaken":     "The priority is already taken by another good.",
+		"errors.category.notFound":      "The category was not found.",
+		"errors.category.cycle":         "A category cannot be moved under its own descendant.",
+		"errors.category.hasChildren":   "The category has subcategories.",
+		"errors.favorite.notFound":      "The good is not in favorites.",
+		"errors.subscription.notFound":  "The subscription was not found.",
+		"errors.import.tooLarge":        "The import file is too large.",
+		"errors.job.notTriggered":       "The job is unknown or already running.",
+		"errors.job.notFound":           "The job was not found or has expired.",
+		"errors.token.invalid":          "The API token is invalid, expired or revoked.",
+		"errors.token.forbidden":        "The API token does not allow this request.",
+		"errors.token.notFound":         "The API token was not found.",
+		"errors.eventRoute.notFound":    "The event routing rule was not found.",
+	},
+	"ru": {
+		"errors.internal":               "Внутренняя ошибка сервера.",
+		"errors.request.invalid":        "Некорректный запрос.",
+		"errors.notFound":               "Запрошенный объект не найден.",
+		"errors.conflict":               "Запрос противоречит текущему состоянию.",
+		"errors.forbidden":              "Запрос не разрешён.",
+		"errors.route.notFound":         "Такого маршрута нет.",
+		"errors.route.methodNotAllowed": "Метод не поддерживается этим маршрутом.",
+		"errors.tenant.invalid":         "Некорректный арендатор запроса.",
+		"errors.tenant.exists":          "Арендатор с таким именем уже существует.",
+		"errors.user.required":          "Запрос должен выполняться от имени пользователя.",
+		"errors.server.busy":            "Сервер перегружен, повторите запрос позже.",
+		"errors.admin.unauthorized":     "Нужен действительный токен администратора.",
+		"errors.server.degraded":        "Сервис работает в ограниченном режиме и выполняет только основные запросы, повторите позже.",
+		"errors.server.readOnly":        "Этот экземпляр — реплика только для чтения, отправляйте изменения на основной.",
+		"errors.dependency.unavailable": "Нужный сервис временно недоступен.",
+		"errors.project.notFound":       "Проект не найден.",
+		"errors.project.archived":       "Проект в архиве, изменять его нельзя.",
+		"errors.project.removed":        "Проект удалён, изменять его нельзя.",
+		"errors.project.quotaExceeded":  "В проекте достигнуто наибольшее число товаров.",
+		"errors.good.notFound":          "Товар не найден.",
+		"errors.good.duplicate":         "Товар с похожим именем уже существует.",
+		"errors.good.priorityTaken":     "Этот приоритет уже занят другим товаром.",
+		"errors.category.notFound":      "Категория не найдена.",
+		"errors.category.cycle":         "Категорию нельзя перенести в её же потомка.",
+		"errors.category.hasChildren":   "У категории есть подкатегории.",
+		"errors.favorite.notFound":      "Товара нет в избранном.",
+		"errors.subscription.notFound":  "Подписка не найдена.",
+		"errors.import.tooLarge":        "Файл импорта слишком большой.",
+		"errors.job.notTriggered":       "Задача не найдена или уже выполняется.",
+		"errors.job.notFound":           "Задание не найдено или устарело.",
+		"errors.token.invalid":          "Токен недействителен, истёк или отозван.",
+		"errors.token.forbidden":        "Токен не разрешает этот запрос.",
+		"errors.token.notFound":         "Токен не найден.",
+		"errors.eventRoute.notFound":    "Правило маршрутизации событий не найдено.",
+	},
+}
+
+// localize копирует ключ сообщения в key и подставляет в message текст на
+// языке из Accept-Language. Без Accept-Language или без подходящего языка
+// message остаётся ключом, как раньше.
+func localize(r *http.Request, body ErrorBody) ErrorBody {
+	if body.Key == "" {
+		body.Key = body.Message
+	}
+	if r == nil {
+		return body
+	}
+	lang := language(r.Header.Get("Accept-Language"))
+	if lang == "" {
+		return body
+	}
+	text, ok := messages[lang][body.Message]
+	if !ok {
+		return body
+	}
+	body.Message = text
+	return body
+}
+
+// language выбирает из Accept-Language поддерживаемый язык с наибольшим
+// весом q; региональные варианты (en-US) сводятся к основному языку.
+func language(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := messages[base]; !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
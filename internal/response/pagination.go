@@ -0,0 +1,71 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Pagination дублирует meta страницы в заголовках для клиентов, которые не
+// разбирают тело: X-Total-Count и Link (RFC 5988) со ссылками first, prev,
+// next и last на тот же маршрут с другими limit и offset. Вызывается до
+// записи тела.
+func Pagination(w http.ResponseWriter, r *http.Request, meta Meta) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(meta.Total))
+	if meta.Limit <= 0 {
+		return
+	}
+
+	last := 0
+	if meta.Total > 0 {
+		last = (meta.Total - 1) / meta.Limit * meta.Limit
+	}
+
+	links := []string{pageLink(r, meta.Limit, 0, "first")}
+	if meta.Offset > 0 {
+		prev := meta.Offset - meta.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageLink(r, meta.Limit, prev, "prev"))
+	}
+	if meta.Offset+meta.Limit < meta.Total {
+		links = append(links, pageLink(r, meta.Limit, meta.Offset+meta.Limit, "next"))
+	}
+	links = append(links, pageLink(r, meta.Limit, last, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func pageLink(r *http.Request, limit, offset int, rel string) string {
+	return "<" + pageURL(r, limit, offset) + `>; rel="` + rel + `"`
+}
+
+func pageURL(r *http.Request, limit, offset int) string {
+	query := r.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// PageLinks — ссылки на соседние страницы в теле списка; пустая ссылка
+// означает, что страницы нет.
+type PageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// PageLinksOf строит ссылки на соседние страницы того же маршрута, как
+// Pagination для заголовка Link.
+func PageLinksOf(r *http.Request, meta Meta) *PageLinks {
+	links := &PageLinks{}
+	if meta.Limit <= 0 {
+		return links
+	}
+	if meta.Offset > 0 {
+		links.Prev = pageURL(r, meta.Limit, max(meta.Offset-meta.Limit, 0))
+	}
+	if meta.Offset+meta.Limit < meta.Total {
+		links.Next = pageURL(r, meta.Limit, meta.Offset+meta.Limit)
+	}
+	return links
+}
@@ -0,0 +1,26 @@
+package response
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Буферы крупнее maxPooledBuffer в пул не возвращаются, чтобы одна большая
+// страница не удерживала память навсегда.
+const maxPooledBuffer = 256 << 10
+
+var buffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return buffers.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	buffers.Put(buf)
+}
@@ -0,0 +1,154 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"hezzl-test/internal/breaker"
+	"hezzl-test/internal/errs"
+)
+
+const (
+	CodeInternal     = 1
+	CodeBadRequest   = 2
+	CodeNotFound     = 3
+	CodeConflict     = 4
+	CodeTenant       = 5
+	CodeUnauthorized = 6
+	CodeUnavailable  = 7
+	CodeRateLimited  = 8
+)
+
+// ErrorBody — тело ответа с ошибкой. Code и Key стабильны для клиентов;
+// Message локализуется по Accept-Language (см. localize), а без него
+// совпадает с Key.
+type ErrorBody struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Key     string      `json:"key"`
+	Details interface{} `json:"details"`
+}
+
+type Meta struct {
+	Total   int `json:"total"`
+	Removed int `json:"removed"`
+	Limit   int `json:"limit"`
+	Offset  int `json:"offset"`
+}
+
+// JSON кодирует data целиком до записи заголовков, чтобы ошибка кодирования
+// превращалась в 500, а не в оборванный ответ с уже отправленным статусом.
+func JSON(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	if IsLegacy(r) {
+		switch v := data.(type) {
+		case ErrorBody:
+			http.Error(w, v.Message, statusCode)
+			return
+		case Legacy:
+			data = v.Legacy()
+		}
+	}
+	if v, ok := data.(ErrorBody); ok {
+		data = localize(r, v)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	enc := json.NewEncoder(buf)
+	if r != nil && r.URL.Query().Has("pretty") {
+		enc.SetIndent("", "  ")
+	}
+	err := enc.Encode(data)
+	body := buf.Bytes()
+	if err == nil {
+		body, err = Reformat(r, body)
+	}
+	if err != nil {
+		log.Printf("response: encode: %v", err)
+		buf.Reset()
+		statusCode = http.StatusInternalServerError
+		json.NewEncoder(buf).Encode(ErrorBody{Code: CodeInternal, Message: "errors.internal", Details: struct{}{}})
+		body = buf.Bytes()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("response: write: %v", err)
+	}
+}
+
+func Error(w http.ResponseWriter, r *http.Request, statusCode, code int, message string) {
+	JSON(w, r, statusCode, ErrorBody{Code: code, Message: message, Details: struct{}{}})
+}
+
+func BadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	if IsLegacy(r) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	JSON(w, r, http.StatusBadRequest, ErrorBody{
+		Code:    CodeBadRequest,
+		Message: "errors.request.invalid",
+		Details: badRequestDetails(err),
+	})
+}
+
+// badRequestDetails добавляет ошибки по полям, если их отдаёт err (например,
+// bind.Errors).
+func badRequestDetails(err error) map[string]interface{} {
+	details := map[string]interface{}{"error": err.Error()}
+	var fields interface{ Fields() map[string]string }
+	if errors.As(err, &fields) {
+		details["fields"] = fields.Fields()
+	}
+	return details
+}
+
+// InternalError отвечает 500, а при разомкнутом breaker зависимости — 503.
+func InternalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	if errors.Is(err, breaker.ErrOpen) {
+		Error(w, r, http.StatusServiceUnavailable, CodeUnavailable, "errors.dependency.unavailable")
+		return
+	}
+	Error(w, r, http.StatusInternalServerError, CodeInternal, "errors.internal")
+}
+
+// Fail отвечает на ошибку err: ошибки пакета errs — статусом и кодом
+// своего вида с их ключом, остальные — как InternalError.
+func Fail(w http.ResponseWriter, r *http.Request, err error) {
+	key := ""
+	var e *errs.Error
+	if errors.As(err, &e) {
+		key = e.Key
+	}
+	switch {
+	case errors.Is(err, errs.ErrValidation):
+		BadRequest(w, r, err)
+	case errors.Is(err, errs.ErrNotFound):
+		Error(w, r, http.StatusNotFound, CodeNotFound, keyOr(key, "errors.notFound"))
+	case errors.Is(err, errs.ErrConflict):
+		Error(w, r, http.StatusConflict, CodeConflict, keyOr(key, "errors.conflict"))
+	case errors.Is(err, errs.ErrForbidden):
+		Error(w, r, http.StatusForbidden, CodeUnauthorized, keyOr(key, "errors.forbidden"))
+	default:
+		InternalError(w, r, err)
+	}
+}
+
+func keyOr(key, def string) string {
+	if key == "" {
+		return def
+	}
+	return key
+}
+
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"hezzl-test/internal/errs"
+)
+
+func TestFail(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   int
+		key    string
+	}{
+		{name: "not found", err: errs.NotFound("errors.good.notFound"), status: http.StatusNotFound, code: CodeNotFound, key: "errors.good.notFound"},
+		{name: "wrapped conflict", err: fmt.Errorf("move: %w", errs.Conflict("errors.good.priorityTaken")), status: http.StatusConflict, code: CodeConflict, key: "errors.good.priorityTaken"},
+		{name: "forbidden", err: errs.Forbidden("errors.token.forbidden"), status: http.StatusForbidden, code: CodeUnauthorized, key: "errors.token.forbidden"},
+		{name: "validation", err: errs.Validation(errors.New("bad header")), status: http.StatusBadRequest, code: CodeBadRequest, key: "errors.request.invalid"},
+		{name: "bare kind", err: errs.ErrNotFound, status: http.StatusNotFound, code: CodeNotFound, key: "errors.notFound"},
+		{name: "other", err: errors.New("connection refused"), status: http.StatusInternalServerError, code: CodeInternal, key: "errors.internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Fail(w, httptest.NewRequest("GET", "/", nil), tt.err)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			var body ErrorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.code || body.Key != tt.key {
+				t.Fatalf("code, key = %d, %q; want %d, %q", body.Code, body.Key, tt.code, tt.key)
+			}
+		})
+	}
+}
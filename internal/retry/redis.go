@@ -0,0 +1,37 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook повторяет команды и конвейеры Redis по policy. Встроенные
+// повторы клиента при этом стоит выключить (Options.MaxRetries = -1).
+func RedisHook(policy Policy) redis.Hook {
+	return redisHook{policy: policy}
+}
+
+type redisHook struct {
+	policy Policy
+}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return h.policy.Do(ctx, func(ctx context.Context) error {
+			return next(ctx, cmd)
+		})
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return h.policy.Do(ctx, func(ctx context.Context) error {
+			return next(ctx, cmds)
+		})
+	}
+}
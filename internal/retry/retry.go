@@ -0,0 +1,105 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+)
+
+// Policy повторяет операцию с экспоненциальной задержкой и полным джиттером:
+// перед n-й повторной попыткой ждёт случайное время от 0 до
+// min(MaxDelay, BaseDelay*2^(n-1)).
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Retryable решает, стоит ли повторять ошибку; nil означает Transient.
+	Retryable func(error) bool
+}
+
+// Do выполняет fn до MaxAttempts раз, пока она возвращает повторяемую
+// ошибку. Отмена ctx прерывает ожидание и возвращает последнюю ошибку fn.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = Transient
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p Policy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// Transient отличает временные сбои инфраструктуры (обрыв соединения,
+// таймаут, перегрузка, конфликт сериализации) от ошибок самого запроса.
+// Отмена контекста и разомкнутый breaker временными не считаются: повтор
+// их не исправит.
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Class() == "08": // connection_exception
+			return true
+		case pqErr.Code == "40001", pqErr.Code == "40P01": // serialization_failure, deadlock_detected
+			return true
+		case pqErr.Code == "53300", pqErr.Code == "57P03": // too_many_connections, cannot_connect_now
+			return true
+		}
+		return false
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) && !errors.Is(err, redis.Nil) {
+		for _, prefix := range []string{"LOADING", "READONLY", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN"} {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if errors.Is(err, nats.ErrTimeout) || errors.Is(err, nats.ErrReconnectBufExceeded) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,141 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+
+	"hezzl-test/internal/breaker"
+)
+
+func TestDo(t *testing.T) {
+	errPermanent := errors.New("syntax error")
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{"first try", []error{nil}, 1, nil},
+		{"recovers", []error{io.EOF, io.EOF, nil}, 3, nil},
+		{"gives up", []error{io.EOF, io.EOF, io.EOF, nil}, 3, io.EOF},
+		{"permanent", []error{errPermanent, nil}, 1, errPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{MaxAttempts: 3, BaseDelay: time.Microsecond, MaxDelay: time.Millisecond}
+			attempts := 0
+			err := p.Do(context.Background(), func(context.Context) error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+			if err != tt.err || attempts != tt.attempts {
+				t.Errorf("err = %v after %d attempts, want %v after %d", err, attempts, tt.err, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestDoStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	attempts := 0
+	done := make(chan error)
+	go func() {
+		done <- p.Do(ctx, func(context.Context) error {
+			attempts++
+			return io.EOF
+		})
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err != io.EOF || attempts != 1 {
+			t.Errorf("err = %v after %d attempts, want the last error after 1", err, attempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do keeps waiting after ctx is cancelled")
+	}
+}
+
+func TestDoCustomRetryable(t *testing.T) {
+	p := Policy{MaxAttempts: 3, Retryable: func(err error) bool { return err.Error() == "again" }}
+	attempts := 0
+	p.Do(context.Background(), func(context.Context) error {
+		attempts++
+		return errors.New("again")
+	})
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	p := Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt, ceiling := range map[int]time.Duration{1: 10 * time.Millisecond, 3: 40 * time.Millisecond, 4: 50 * time.Millisecond, 40: 50 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if d := p.delay(attempt); d < 0 || d > ceiling {
+				t.Fatalf("delay(%d) = %s, want within [0, %s]", attempt, d, ceiling)
+			}
+		}
+	}
+	if d := (Policy{}).delay(1); d != 0 {
+		t.Errorf("zero policy delay = %s", d)
+	}
+}
+
+// serverError — ошибка, которую вернул сам Redis.
+type serverError string
+
+func (e serverError) Error() string { return string(e) }
+func (serverError) RedisError()     {}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{breaker.ErrOpen, false},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "53300"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{serverError("LOADING Redis is loading the dataset in memory"), true},
+		{serverError("READONLY You can't write against a read only replica."), true},
+		{serverError("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+		{redis.Nil, false},
+		{nats.ErrTimeout, true},
+		{nats.ErrReconnectBufExceeded, true},
+		{io.EOF, true},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{timeoutError{}, true},
+		{errors.New("syntax error"), false},
+	}
+	for _, tt := range tests {
+		if got := Transient(tt.err); got != tt.want {
+			t.Errorf("Transient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package ringcache раскладывает ключи кэша по нескольким независимым узлам
+// Redis консистентным хешированием на стороне клиента, без Redis Cluster.
+// Добавление или удаление узла перемещает лишь долю ключей, поэтому объём
+// кэша растёт вместе с числом узлов.
+package ringcache
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"hezzl-test/internal/deps"
+)
+
+// Cache — redis.Ring, у которого команды над несколькими ключами и SCAN
+// обходят все узлы: сам Ring отправляет их на узел первого ключа или на
+// случайный узел.
+type Cache struct {
+	*redis.Ring
+}
+
+var _ deps.Cache = (*Cache)(nil)
+
+// New создаёт кольцо над nodes — адресами узлов вида host:port. Узлы
+// именуются своими адресами, так что порядок в списке не влияет на
+// распределение ключей.
+func New(nodes []string, opt redis.RingOptions) *Cache {
+	opt.Addrs = make(map[string]string, len(nodes))
+	for _, addr := range nodes {
+		opt.Addrs[addr] = addr
+	}
+	return &Cache{Ring: redis.NewRing(&opt)}
+}
+
+// MGet читает ключи конвейером из GET по узлам; отсутствующим ключам, как
+// и в MGET, соответствует nil.
+func (c *Cache) MGet(ctx context.Context, keys ...string) *redis.SliceCmd {
+	cmds, err := c.Ring.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return redis.NewSliceResult(nil, err)
+	}
+
+	vals := make([]interface{}, len(cmds))
+	for i, cmd := range cmds {
+		if val, err := cmd.(*redis.StringCmd).Result(); err == nil {
+			vals[i] = val
+		}
+	}
+	return redis.NewSliceResult(vals, nil)
+}
+
+// Del удаляет ключи конвейером: Ring сам группирует команды по узлам.
+func (c *Cache) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	cmds, err := c.Ring.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return redis.NewIntResult(0, err)
+	}
+
+	var n int64
+	for _, cmd := range cmds {
+		n += cmd.(*redis.IntCmd).Val()
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+// Scan обходит все узлы до конца и возвращает найденные ключи одной
+// страницей с нулевым курсором; cursor игнорируется, count задаёт размер
+// страницы SCAN на каждом узле.
+func (c *Cache) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	err := c.Ring.ForEachShard(ctx, func(ctx context.Context, client *redis.Client) error {
+		iter := client.Scan(ctx, 0, match, count).Iterator()
+		var found []string
+		for iter.Next(ctx) {
+			found = append(found, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		mu.Lock()
+		keys = append(keys, found...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return redis.NewScanCmdResult(nil, 0, err)
+	}
+	sort.Strings(keys)
+	return redis.NewScanCmdResult(keys, 0, nil)
+}
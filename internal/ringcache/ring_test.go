@@ -0,0 +1,237 @@
+package ringcache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// node — узел Redis в памяти: понимает RESP2 и команды, которые шлют
+// кольцо и Cache (PING, GET, SET, DEL, SCAN). На остальные, в том числе
+// HELLO, отвечает ошибкой, и клиент остаётся на RESP2.
+type node struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func startNode(t *testing.T) *node {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &node{ln: ln, data: make(map[string]string)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go n.serve(conn)
+		}
+	}()
+	return n
+}
+
+func (n *node) addr() string { return n.ln.Addr().String() }
+
+func (n *node) keys() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	keys := make([]string, 0, len(n.data))
+	for key := range n.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (n *node) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		n.exec(w, args)
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (n *node) exec(w *bufio.Writer, args []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		w.WriteString("+PONG\r\n")
+	case "GET":
+		if v, ok := n.data[args[1]]; ok {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+		} else {
+			w.WriteString("$-1\r\n")
+		}
+	case "SET":
+		n.data[args[1]] = args[2]
+		w.WriteString("+OK\r\n")
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := n.data[key]; ok {
+				delete(n.data, key)
+				deleted++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", deleted)
+	case "SCAN":
+		// Один проход: все подходящие ключи и нулевой курсор.
+		match := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.EqualFold(args[i], "MATCH") {
+				match = args[i+1]
+			}
+		}
+		var keys []string
+		for key := range n.data {
+			if ok, _ := path.Match(match, key); ok {
+				keys = append(keys, key)
+			}
+		}
+		fmt.Fprintf(w, "*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+		for _, key := range keys {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(key), key)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func newRing(t *testing.T) (*Cache, []*node) {
+	t.Helper()
+	nodes := []*node{startNode(t), startNode(t), startNode(t)}
+	addrs := make([]string, len(nodes))
+	for i, n := range nodes {
+		addrs[i] = n.addr()
+	}
+	c := New(addrs, redis.RingOptions{DialTimeout: time.Second, DisableIndentity: true})
+	t.Cleanup(func() { c.Close() })
+	return c, nodes
+}
+
+func TestRingSpreadsKeys(t *testing.T) {
+	c, nodes := newRing(t)
+	ctx := context.Background()
+
+	var keys []string
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("good:%d", i)
+		keys = append(keys, key)
+		if err := c.Set(ctx, key, strconv.Itoa(i), 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total := 0
+	for i, n := range nodes {
+		if len(n.keys()) == 0 {
+			t.Errorf("node %d got no keys", i)
+		}
+		total += len(n.keys())
+	}
+	if total != len(keys) {
+		t.Fatalf("nodes hold %d keys, want %d", total, len(keys))
+	}
+
+	vals, err := c.MGet(ctx, "good:3", "missing", "good:29").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"3", nil, "29"}; !reflect.DeepEqual(vals, want) {
+		t.Errorf("MGet = %v, want %v", vals, want)
+	}
+
+	found, _, err := c.Scan(ctx, 0, "good:*", 10).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != len(keys) {
+		t.Errorf("Scan found %d keys across nodes, want %d", len(found), len(keys))
+	}
+
+	deleted, err := c.Del(ctx, append(keys, "missing")...).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != int64(len(keys)) {
+		t.Errorf("Del = %d, want %d", deleted, len(keys))
+	}
+	for i, n := range nodes {
+		if left := n.keys(); len(left) != 0 {
+			t.Errorf("node %d still holds %v", i, left)
+		}
+	}
+}
+
+func TestRingNodeOrder(t *testing.T) {
+	c, nodes := newRing(t)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		if err := c.Set(ctx, fmt.Sprintf("good:%d", i), "1", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Кольцо с теми же узлами в другом порядке ищет ключи там же.
+	reversed := New([]string{nodes[2].addr(), nodes[1].addr(), nodes[0].addr()}, redis.RingOptions{DisableIndentity: true})
+	defer reversed.Close()
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("good:%d", i)
+		if err := reversed.Get(ctx, key).Err(); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+}
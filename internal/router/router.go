@@ -0,0 +1,71 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+
+	"hezzl-test/internal/response"
+)
+
+const (
+	Mux = "mux"
+	Chi = "chi"
+)
+
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.Handler
+}
+
+// New строит маршрутизатор выбранной реализации по единому списку маршрутов.
+// Неизвестный путь отвечает 404, известный путь с другим методом — 405 с
+// заголовком Allow; оба ответа в стандартном формате ошибки.
+func New(kind string, routes []Route) (http.Handler, error) {
+	allowed := make(map[string][]string)
+	for _, rt := range routes {
+		allowed[rt.Path] = append(allowed[rt.Path], rt.Method)
+	}
+	for _, methods := range allowed {
+		sort.Strings(methods)
+	}
+	notAllowed := methodNotAllowed(allowed)
+
+	switch kind {
+	case Mux:
+		r := mux.NewRouter()
+		for _, rt := range routes {
+			r.Handle(rt.Path, rt.Handler).Methods(rt.Method)
+		}
+		r.NotFoundHandler = http.HandlerFunc(notFound)
+		r.MethodNotAllowedHandler = notAllowed
+		return r, nil
+	case Chi:
+		r := chi.NewRouter()
+		for _, rt := range routes {
+			r.Method(rt.Method, rt.Path, rt.Handler)
+		}
+		r.NotFound(notFound)
+		r.MethodNotAllowed(notAllowed)
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown router %q", kind)
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	response.Error(w, r, http.StatusNotFound, response.CodeNotFound, "errors.route.notFound")
+}
+
+func methodNotAllowed(allowed map[string][]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if methods, ok := allowed[r.URL.Path]; ok {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+		}
+		response.Error(w, r, http.StatusMethodNotAllowed, response.CodeBadRequest, "errors.route.methodNotAllowed")
+	}
+}
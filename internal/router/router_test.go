@@ -0,0 +1,108 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hezzl-test/internal/response"
+)
+
+// paths — маршруты сервиса, на которых сравниваются реализации.
+var paths = []string{
+	"GET /projects", "PATCH /project/archive", "POST /projects/merge",
+	"GET /goods/list", "GET /goods/search", "GET /analytics/goods/activity",
+	"GET /digest/subscriptions", "POST /digest/subscription", "DELETE /digest/subscription",
+	"GET /admin/tenants", "POST /admin/tenants", "GET /admin/jobs", "POST /admin/jobs/run", "GET /admin/workers",
+	"POST /good/create", "PATCH /good/update", "DELETE /good/delete",
+	"POST /good/attachments", "GET /good/attachments",
+	"GET /categories", "POST /category/create", "PATCH /category/update", "DELETE /category/delete",
+	"PATCH /good/category", "GET /good/related", "POST /good/favorite", "DELETE /good/favorite",
+	"GET /goods/favorites", "POST /goods/import", "POST /goods/transfer", "PATCH /goods/reprioritize",
+}
+
+var requests = []struct {
+	name   string
+	method string
+	path   string
+	status int
+	key    string
+}{
+	{"hit", http.MethodGet, "/goods/list", http.StatusOK, ""},
+	{"not_found", http.MethodGet, "/goods/unknown", http.StatusNotFound, "errors.route.notFound"},
+	{"not_allowed", http.MethodPut, "/good/favorite", http.StatusMethodNotAllowed, "errors.route.methodNotAllowed"},
+}
+
+func newRouter(tb testing.TB, kind string) http.Handler {
+	tb.Helper()
+	routes := make([]Route, len(paths))
+	for i, p := range paths {
+		method, path, _ := strings.Cut(p, " ")
+		routes[i] = Route{Method: method, Path: path, Handler: http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})}
+	}
+	h, err := New(kind, routes)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return h
+}
+
+func TestRouters(t *testing.T) {
+	for _, kind := range []string{Mux, Chi} {
+		h := newRouter(t, kind)
+		for _, req := range requests {
+			t.Run(kind+"/"+req.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(req.method, req.path, nil))
+				if w.Code != req.status {
+					t.Fatalf("status = %d, want %d", w.Code, req.status)
+				}
+				if req.key == "" {
+					return
+				}
+				var body response.ErrorBody
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Key != req.key {
+					t.Errorf("body %s, want error %s", w.Body, req.key)
+				}
+				if req.status == http.StatusMethodNotAllowed && w.Header().Get("Allow") != "DELETE, POST" {
+					t.Errorf("Allow = %q, want DELETE, POST", w.Header().Get("Allow"))
+				}
+			})
+		}
+	}
+}
+
+func TestNewUnknownKind(t *testing.T) {
+	if _, err := New("httprouter", nil); err == nil {
+		t.Fatal("want error for unknown router")
+	}
+}
+
+// discardWriter — http.ResponseWriter, который ничего не хранит.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// BenchmarkRouter сравнивает время и аллокации на запрос у реализаций при
+// попадании в маршрут, 404 и 405.
+func BenchmarkRouter(b *testing.B) {
+	for _, kind := range []string{Mux, Chi} {
+		h := newRouter(b, kind)
+		for _, req := range requests {
+			r := httptest.NewRequest(req.method, req.path, nil)
+			b.Run(kind+"/"+req.name, func(b *testing.B) {
+				b.ReportAllocs()
+				w := &discardWriter{header: http.Header{}}
+				for i := 0; i < b.N; i++ {
+					h.ServeHTTP(w, r)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"hezzl-test/internal/clock"
+)
+
+type Schedule interface {
+	Next(now time.Time) time.Time
+}
+
+// Every запускает задачу с фиксированным интервалом.
+type Every time.Duration
+
+func (e Every) Next(now time.Time) time.Time {
+	return now.Add(time.Duration(e))
+}
+
+// DailyAt запускает задачу раз в сутки в заданное время UTC.
+type DailyAt struct {
+	Hour   int
+	Minute int
+}
+
+func (d DailyAt) Next(now time.Time) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), d.Hour, d.Minute, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Enabled  bool
+	Run      func(ctx context.Context) error
+}
+
+type Status struct {
+	Name         string     `json:"name"`
+	Enabled      bool       `json:"enabled"`
+	Running      bool       `json:"running"`
+	Runs         int        `json:"runs"`
+	Skipped      int        `json:"skipped"`
+	LastStart    *time.Time `json:"last_start,omitempty"`
+	LastFinish   *time.Time `json:"last_finish,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	NextRun      *time.Time `json:"next_run,omitempty"`
+	Progress     *Progress  `json:"progress,omitempty"`
+}
+
+// Progress — сколько единиц работы выполнено из Total в текущем или
+// последнем запуске; задачи сообщают его через ReportProgress.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+type progressKey struct{}
+
+// ReportProgress обновляет прогресс задачи, запущенной с ctx. Вне
+// планировщика вызов ничего не делает.
+func ReportProgress(ctx context.Context, done, total int) {
+	if report, ok := ctx.Value(progressKey{}).(func(Progress)); ok {
+		report(Progress{Done: done, Total: total})
+	}
+}
+
+// ErrLocked — задачу сейчас выполняет другой экземпляр сервиса; такой
+// запуск учитывается в Skipped.
+var ErrLocked = errors.New("scheduler: job is locked by another instance")
+
+// Lock выполняет run задачи name, удерживая общую для всех экземпляров
+// сервиса блокировку, или возвращает ErrLocked, если её держит другой
+// экземпляр.
+type Lock func(ctx context.Context, name string, run func(ctx context.Context) error) error
+
+type entry struct {
+	job    Job
+	status Status
+}
+
+// Scheduler запускает зарегистрированные задачи по расписанию. Задача не
+// стартует повторно, пока не закончился предыдущий запуск: такой тик
+// учитывается в Skipped.
+type Scheduler struct {
+	clock   clock.Clock
+	lock    Lock
+	mu      sync.Mutex
+	entries map[string]*entry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New создаёт планировщик, который считает расписание и время запусков по
+// часам c; ожидание до запуска всё равно идёт по системному таймеру.
+func New(c clock.Clock) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{clock: c, entries: make(map[string]*entry), ctx: ctx, cancel: cancel}
+}
+
+// SetLock задаёт блокировку, под которой выполняется каждый запуск задач;
+// без неё задачи запускаются на каждом экземпляре сервиса.
+func (s *Scheduler) SetLock(lock Lock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lock = lock
+}
+
+func (s *Scheduler) Register(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[job.Name] = &entry{job: job, status: Status{Name: job.Name, Enabled: job.Enabled}}
+}
+
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.job.Enabled {
+			s.wg.Add(1)
+			go s.loop(e)
+		}
+	}
+}
+
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Trigger запускает задачу вне расписания, если она не выполняется сейчас.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("scheduler: unknown job %q", name)
+	}
+	if !s.run(e) {
+		return fmt.Errorf("scheduler: job %q is already running", name)
+	}
+	return nil
+}
+
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (s *Scheduler) loop(e *entry) {
+	defer s.wg.Done()
+	for {
+		now := s.clock.Now()
+		next := e.job.Schedule.Next(now)
+		s.mu.Lock()
+		e.status.NextRun = &next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(e)
+	}
+}
+
+func (s *Scheduler) run(e *entry) bool {
+	s.mu.Lock()
+	if e.status.Running {
+		e.status.Skipped++
+		s.mu.Unlock()
+		return false
+	}
+	start := s.clock.Now()
+	e.status.Running = true
+	e.status.LastStart = &start
+	e.status.Progress = nil
+	lock := s.lock
+	s.mu.Unlock()
+
+	ctx := context.WithValue(s.ctx, progressKey{}, func(p Progress) {
+		s.mu.Lock()
+		e.status.Progress = &p
+		s.mu.Unlock()
+	})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		var err error
+		if lock != nil {
+			err = lock(ctx, e.job.Name, e.job.Run)
+		} else {
+			err = e.job.Run(ctx)
+		}
+		if errors.Is(err, ErrLocked) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			e.status.Running = false
+			e.status.Skipped++
+			return
+		}
+		if err != nil {
+			log.Printf("scheduler: %s: %v", e.job.Name, err)
+		}
+
+		finish := s.clock.Now()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		e.status.Running = false
+		e.status.Runs++
+		e.status.LastFinish = &finish
+		e.status.LastDuration = finish.Sub(start).String()
+		e.status.LastError = ""
+		if err != nil {
+			e.status.LastError = err.Error()
+		}
+	}()
+	return true
+}
@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/clock"
+)
+
+func TestDailyAtNext(t *testing.T) {
+	at := DailyAt{Hour: 3, Minute: 30}
+	tests := []struct {
+		now, want time.Time
+	}{
+		{time.Date(2026, 3, 10, 1, 0, 0, 0, time.UTC), time.Date(2026, 3, 10, 3, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 10, 3, 30, 0, 0, time.UTC), time.Date(2026, 3, 11, 3, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 3, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 10, 5, 0, 0, 0, time.FixedZone("MSK", 3*3600)), time.Date(2026, 3, 10, 3, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := at.Next(tt.now); !got.Equal(tt.want) {
+			t.Errorf("Next(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestTriggerRecordsClockTimes(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	c := clock.NewManual(start)
+	s := New(c)
+
+	release := make(chan struct{})
+	s.Register(Job{Name: "job", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+		<-release
+		c.Advance(90 * time.Second)
+		ReportProgress(ctx, 1, 2)
+		return nil
+	}})
+
+	if err := s.Trigger("job"); err != nil {
+		t.Fatal(err)
+	}
+	// Пока задача выполняется, повторный запуск пропускается.
+	if err := s.Trigger("job"); err == nil {
+		t.Fatal("second Trigger succeeded while the job is running")
+	}
+	close(release)
+	s.Stop()
+
+	status := s.Status()[0]
+	if status.Runs != 1 || status.Skipped != 1 || status.Running {
+		t.Fatalf("status = %+v", status)
+	}
+	if !status.LastStart.Equal(start) || !status.LastFinish.Equal(start.Add(90*time.Second)) || status.LastDuration != "1m30s" {
+		t.Errorf("start %v, finish %v, duration %s", status.LastStart, status.LastFinish, status.LastDuration)
+	}
+	if status.Progress == nil || *status.Progress != (Progress{Done: 1, Total: 2}) {
+		t.Errorf("progress = %+v", status.Progress)
+	}
+}
+
+func TestNextRunFollowsClock(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	s := New(clock.NewManual(now))
+	s.Register(Job{Name: "job", Schedule: Every(time.Hour), Enabled: true, Run: func(context.Context) error { return nil }})
+	s.Start()
+	defer s.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if next := s.Status()[0].NextRun; next != nil {
+			if want := now.Add(time.Hour); !next.Equal(want) {
+				t.Fatalf("NextRun = %v, want %v", next, want)
+			}
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("NextRun is not set")
+}
+
+func TestTriggerUnknownJob(t *testing.T) {
+	s := New(clock.System{})
+	if err := s.Trigger("missing"); err == nil {
+		t.Fatal("want error for unknown job")
+	}
+}
+
+func TestLockedRunIsSkipped(t *testing.T) {
+	s := New(clock.NewManual(time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)))
+	held := map[string]bool{"busy": true}
+	s.SetLock(func(ctx context.Context, name string, run func(ctx context.Context) error) error {
+		if held[name] {
+			return ErrLocked
+		}
+		return run(ctx)
+	})
+
+	ran := map[string]bool{}
+	for _, name := range []string{"busy", "free"} {
+		name := name
+		s.Register(Job{Name: name, Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			ran[name] = true
+			return errors.New("failed")
+		}})
+		if err := s.Trigger(name); err != nil {
+			t.Fatal(err)
+		}
+		s.Stop()
+	}
+
+	statuses := s.Status()
+	busy, free := statuses[0], statuses[1]
+	if ran["busy"] || busy.Runs != 0 || busy.Skipped != 1 || busy.Running || busy.LastError != "" {
+		t.Errorf("locked job: ran %v, status %+v", ran["busy"], busy)
+	}
+	if !ran["free"] || free.Runs != 1 || free.Skipped != 0 || free.LastError != "failed" {
+		t.Errorf("free job: ran %v, status %+v", ran["free"], free)
+	}
+}
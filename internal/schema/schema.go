@@ -0,0 +1,193 @@
+// Package schema сверяет схему живой базы с той, что получается из
+// миграций: версию последней применённой миграции и набор столбцов таблиц.
+// Так расхождение обнаруживается при запуске, а не ошибками Scan посреди
+// запросов.
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema — столбцы по таблицам.
+type Schema map[string]map[string]bool
+
+var (
+	comment     = regexp.MustCompile(`--[^\n]*`)
+	space       = regexp.MustCompile(`\s+`)
+	createTable = regexp.MustCompile(`(?i)^CREATE TABLE (?:IF NOT EXISTS )?(\w+) ?\((.*)\)$`)
+	alterTable  = regexp.MustCompile(`(?i)^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?(\w+) (.*)$`)
+	dropTable   = regexp.MustCompile(`(?i)^DROP TABLE (?:IF EXISTS )?(.*)$`)
+	addColumn   = regexp.MustCompile(`(?i)^ADD COLUMN (?:IF NOT EXISTS )?(\w+)`)
+	dropColumn  = regexp.MustCompile(`(?i)^DROP COLUMN (?:IF EXISTS )?(\w+)`)
+	renameCol   = regexp.MustCompile(`(?i)^RENAME (?:COLUMN )?(\w+) TO (\w+)$`)
+	renameTable = regexp.MustCompile(`(?i)^RENAME TO (\w+)$`)
+	version     = regexp.MustCompile(`^(\d+)_`)
+)
+
+// Табличные ограничения в CREATE TABLE, а не столбцы.
+var constraints = map[string]bool{"primary": true, "unique": true, "constraint": true, "foreign": true, "check": true, "exclude": true}
+
+// FromMigrations применяет по порядку миграции *.sql из каталога dir и
+// возвращает получившуюся схему и номер последней миграции. Учитываются
+// CREATE TABLE, DROP TABLE и ALTER TABLE с ADD, DROP и RENAME столбцов;
+// остальные операторы на столбцы не влияют.
+func FromMigrations(fsys fs.FS, dir string) (Schema, int, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(files)
+
+	s := Schema{}
+	last := 0
+	for _, file := range files {
+		m := version.FindStringSubmatch(path.Base(file))
+		if m == nil {
+			return nil, 0, fmt.Errorf("schema: migration %s has no version prefix", file)
+		}
+		last, _ = strconv.Atoi(m[1])
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, 0, err
+		}
+		s.apply(string(data))
+	}
+	return s, last, nil
+}
+
+func (s Schema) apply(sqlText string) {
+	sqlText = comment.ReplaceAllString(sqlText, "")
+	for _, stmt := range strings.Split(sqlText, ";") {
+		stmt = strings.TrimSpace(space.ReplaceAllString(stmt, " "))
+		if m := createTable.FindStringSubmatch(stmt); m != nil {
+			table := strings.ToLower(m[1])
+			if s[table] == nil {
+				s[table] = map[string]bool{}
+			}
+			for _, def := range splitTop(m[2]) {
+				name := strings.ToLower(strings.Fields(def + " ")[0])
+				if !constraints[name] {
+					s[table][name] = true
+				}
+			}
+		} else if m := alterTable.FindStringSubmatch(stmt); m != nil {
+			s.alter(strings.ToLower(m[1]), m[2])
+		} else if m := dropTable.FindStringSubmatch(stmt); m != nil {
+			for _, table := range strings.Split(m[1], ",") {
+				delete(s, strings.ToLower(strings.Fields(table)[0]))
+			}
+		}
+	}
+}
+
+func (s Schema) alter(table, actions string) {
+	for _, action := range splitTop(actions) {
+		action = strings.TrimSpace(action)
+		if m := addColumn.FindStringSubmatch(action); m != nil && s[table] != nil {
+			s[table][strings.ToLower(m[1])] = true
+		} else if m := dropColumn.FindStringSubmatch(action); m != nil && s[table] != nil {
+			delete(s[table], strings.ToLower(m[1]))
+		} else if m := renameTable.FindStringSubmatch(action); m != nil {
+			s[strings.ToLower(m[1])] = s[table]
+			delete(s, table)
+			return
+		} else if m := renameCol.FindStringSubmatch(action); m != nil && s[table] != nil {
+			delete(s[table], strings.ToLower(m[1]))
+			s[table][strings.ToLower(m[2])] = true
+		}
+	}
+}
+
+// splitTop делит список по запятым вне скобок и кавычек.
+func splitTop(list string) []string {
+	var parts []string
+	depth, start, quoted := 0, 0, false
+	for i, c := range list {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(list[start:]))
+}
+
+// Live читает столбцы таблиц текущей схемы базы.
+func Live(ctx context.Context, db *sql.DB) (Schema, error) {
+	rows, err := db.QueryContext(ctx, `SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema()`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := Schema{}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		if s[table] == nil {
+			s[table] = map[string]bool{}
+		}
+		s[table][column] = true
+	}
+	return s, rows.Err()
+}
+
+// Diff перечисляет расхождения живой схемы live с ожидаемой expected:
+// недостающие таблицы и столбцы и лишние столбцы ожидаемых таблиц.
+// Посторонние таблицы не проверяются.
+func Diff(expected, live Schema) []string {
+	var diff []string
+	for table, columns := range expected {
+		liveColumns, ok := live[table]
+		if !ok {
+			diff = append(diff, "missing table "+table)
+			continue
+		}
+		for column := range columns {
+			if !liveColumns[column] {
+				diff = append(diff, "missing column "+table+"."+column)
+			}
+		}
+		for column := range liveColumns {
+			if !columns[column] {
+				diff = append(diff, "unexpected column "+table+"."+column)
+			}
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// Version читает номер последней применённой миграции из schema_version;
+// 0 — таблицы нет или она пуста.
+func Version(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+	var v int
+	err := db.QueryRowContext(ctx, `SELECT version FROM schema_version`).Scan(&v)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return v, err
+}
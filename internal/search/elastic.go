@@ -0,0 +1,244 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Document struct {
+	ID          int       `json:"id"`
+	TenantID    int       `json:"tenant_id"`
+	ProjectID   int       `json:"project_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Priority    int       `json:"priority"`
+	Removed     bool      `json:"removed"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Query struct {
+	TenantID        int
+	Text            string
+	ProjectID       int
+	ExcludeProjects []int
+	Limit           int
+	Offset          int
+}
+
+type Result struct {
+	Total int
+	Hits  []Document
+}
+
+// Elastic — минимальный клиент REST API Elasticsearch/OpenSearch,
+// которого хватает для зеркалирования товаров и полнотекстового поиска.
+type Elastic struct {
+	addr   string
+	index  string
+	client *http.Client
+}
+
+func NewElastic(addr, index string) *Elastic {
+	return &Elastic{
+		addr:   addr,
+		index:  index,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+var indexMapping = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"properties": map[string]interface{}{
+			"id":          map[string]string{"type": "integer"},
+			"tenant_id":   map[string]string{"type": "integer"},
+			"project_id":  map[string]string{"type": "integer"},
+			"name":        map[string]string{"type": "text"},
+			"description": map[string]string{"type": "text"},
+			"priority":    map[string]string{"type": "integer"},
+			"removed":     map[string]string{"type": "boolean"},
+			"created_at":  map[string]string{"type": "date"},
+		},
+	},
+}
+
+func (e *Elastic) EnsureIndex(ctx context.Context) error {
+	status, _, err := e.do(ctx, http.MethodHead, "/"+e.index, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+	return e.expect(e.do(ctx, http.MethodPut, "/"+e.index, indexMapping))
+}
+
+func (e *Elastic) DropIndex(ctx context.Context) error {
+	status, body, err := e.do(ctx, http.MethodDelete, "/"+e.index, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return e.expect(status, body, err)
+}
+
+func (e *Elastic) Index(ctx context.Context, doc Document) error {
+	return e.expect(e.do(ctx, http.MethodPut, "/"+e.index+"/_doc/"+strconv.Itoa(doc.ID), doc))
+}
+
+func (e *Elastic) Delete(ctx context.Context, id int) error {
+	status, body, err := e.do(ctx, http.MethodDelete, "/"+e.index+"/_doc/"+strconv.Itoa(id), nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return e.expect(status, body, err)
+}
+
+func (e *Elastic) Bulk(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, doc := range docs {
+		enc.Encode(map[string]interface{}{"index": map[string]interface{}{"_index": e.index, "_id": strconv.Itoa(doc.ID)}})
+		enc.Encode(doc)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.addr+"/_bulk", &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("elastic: bulk: %s: %s", resp.Status, body)
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	if result.Errors {
+		return fmt.Errorf("elastic: bulk: some documents were rejected")
+	}
+	return nil
+}
+
+func (e *Elastic) Refresh(ctx context.Context) error {
+	return e.expect(e.do(ctx, http.MethodPost, "/"+e.index+"/_refresh", nil))
+}
+
+// Search ищет по name и description с нечётким совпадением (fuzziness AUTO),
+// name весит втрое больше description; поиск ограничен арендатором,
+// удалённые товары исключаются.
+func (e *Elastic) Search(ctx context.Context, q Query) (Result, error) {
+	filter := []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"tenant_id": q.TenantID}},
+		map[string]interface{}{"term": map[string]interface{}{"removed": false}},
+	}
+	if q.ProjectID != 0 {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"project_id": q.ProjectID}})
+	}
+
+	excludeProjects := q.ExcludeProjects
+	if excludeProjects == nil {
+		excludeProjects = []int{}
+	}
+
+	body := map[string]interface{}{
+		"from": q.Offset,
+		"size": q.Limit,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":     q.Text,
+						"fields":    []string{"name^3", "description"},
+						"fuzziness": "AUTO",
+					},
+				},
+				"filter": filter,
+				"must_not": []interface{}{
+					map[string]interface{}{"terms": map[string]interface{}{"project_id": excludeProjects}},
+				},
+			},
+		},
+	}
+
+	status, data, err := e.do(ctx, http.MethodPost, "/"+e.index+"/_search", body)
+	if err := e.expect(status, data, err); err != nil {
+		return Result{}, err
+	}
+
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source Document `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Total: resp.Hits.Total.Value, Hits: make([]Document, 0, len(resp.Hits.Hits))}
+	for _, hit := range resp.Hits.Hits {
+		result.Hits = append(result.Hits, hit.Source)
+	}
+	return result, nil
+}
+
+func (e *Elastic) do(ctx context.Context, method, path string, payload interface{}) (int, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.addr+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+func (e *Elastic) expect(status int, body []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("elastic: unexpected status %d: %s", status, body)
+	}
+	return nil
+}
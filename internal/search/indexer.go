@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+
+	"hezzl-test/internal/tenant"
+
+	"github.com/nats-io/nats.go"
+)
+
+// sequenceHeader несёт версию товара, в которой опубликовано событие.
+const sequenceHeader = "Sequence"
+
+// Indexer зеркалирует товары в Elasticsearch по событиям из NATS.
+type Indexer struct {
+	elastic *Elastic
+	subs    []*nats.Subscription
+
+	mu   sync.Mutex
+	seen map[int]int64
+}
+
+func NewIndexer(elastic *Elastic) *Indexer {
+	return &Indexer{elastic: elastic, seen: make(map[int]int64)}
+}
+
+// stale сообщает, что событие товара id уже применено или устарело:
+// повторная доставка и запоздавшие события не перетирают индекс.
+// События без номера версии применяются всегда.
+func (i *Indexer) stale(id int, msg *nats.Msg) bool {
+	seq, err := strconv.ParseInt(msg.Header.Get(sequenceHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	last, ok := i.seen[id]
+	if ok && seq <= last {
+		return true
+	}
+	if ok && seq > last+1 {
+		log.Printf("indexer: good %d: events %d..%d missing", id, last+1, seq-1)
+	}
+	i.seen[id] = seq
+	return false
+}
+
+func (i *Indexer) Start(natsConn *nats.Conn) error {
+	handlers := map[string]nats.MsgHandler{
+		"new_good_created": i.upsert,
+		"good_updated":     i.upsert,
+		"good_deleted":     i.remove,
+	}
+	for subject, handler := range handlers {
+		sub, err := natsConn.Subscribe(subject, handler)
+		if err != nil {
+			i.Stop()
+			return err
+		}
+		i.subs = append(i.subs, sub)
+	}
+	return nil
+}
+
+func (i *Indexer) Stop() {
+	for _, sub := range i.subs {
+		sub.Unsubscribe()
+	}
+	i.subs = nil
+}
+
+func (i *Indexer) upsert(msg *nats.Msg) {
+	var doc Document
+	if err := json.Unmarshal(msg.Data, &doc); err != nil || doc.ID == 0 {
+		log.Printf("indexer: skip %s event: %s", msg.Subject, msg.Data)
+		return
+	}
+	if i.stale(doc.ID, msg) {
+		return
+	}
+	doc.TenantID, _ = strconv.Atoi(msg.Header.Get(tenant.NATSHeader))
+	if err := i.elastic.Index(context.Background(), doc); err != nil {
+		log.Printf("indexer: index good %d: %v", doc.ID, err)
+	}
+}
+
+func (i *Indexer) remove(msg *nats.Msg) {
+	var doc Document
+	if err := json.Unmarshal(msg.Data, &doc); err != nil || doc.ID == 0 {
+		log.Printf("indexer: skip %s event: %s", msg.Subject, msg.Data)
+		return
+	}
+	if i.stale(doc.ID, msg) {
+		return
+	}
+	if err := i.elastic.Delete(context.Background(), doc.ID); err != nil {
+		log.Printf("indexer: delete good %d: %v", doc.ID, err)
+	}
+}
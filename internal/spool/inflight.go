@@ -0,0 +1,90 @@
+package spool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"hezzl-test/internal/deps"
+)
+
+// Flusher подтверждает, что сервер получил всё опубликованное до вызова;
+// его реализует *nats.Conn.
+type Flusher interface {
+	FlushTimeout(timeout time.Duration) error
+}
+
+// InFlight публикует через pub и помнит сообщения, получение которых
+// сервер ещё не подтвердил: PublishMsg ядра NATS лишь кладёт сообщение в
+// буфер соединения. Confirm снимает с учёта всё, что опубликовано до
+// успешного сброса буфера; если сброс при остановке не удался, Unconfirmed
+// отдаёт остальное для Save. Повтор может задвоить сообщение, которое
+// сервер всё же получил: потребители отсеивают его по Sequence и
+// Nats-Msg-Id.
+type InFlight struct {
+	pub   deps.Publisher
+	flush Flusher
+
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func NewInFlight(pub deps.Publisher, flush Flusher) *InFlight {
+	return &InFlight{pub: pub, flush: flush}
+}
+
+func (f *InFlight) PublishMsg(msg *nats.Msg) error {
+	if err := f.pub.PublishMsg(msg); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.msgs = append(f.msgs, msg)
+	f.mu.Unlock()
+	return nil
+}
+
+// Confirm сбрасывает буфер соединения и снимает с учёта сообщения,
+// опубликованные до вызова. При ошибке они остаются неподтверждёнными.
+func (f *InFlight) Confirm(timeout time.Duration) error {
+	f.mu.Lock()
+	n := len(f.msgs)
+	f.mu.Unlock()
+
+	if err := f.flush.FlushTimeout(timeout); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs = append([]*nats.Msg(nil), f.msgs[n:]...)
+	return nil
+}
+
+// Run подтверждает опубликованное раз в interval, пока не отменён ctx, чтобы
+// список неподтверждённых не рос.
+func (f *InFlight) Run(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Ошибка не страшна: сообщения останутся в списке до
+			// следующего подтверждения или остановки.
+			f.Confirm(timeout)
+		}
+	}
+}
+
+// Unconfirmed забирает сообщения, получение которых не подтверждено, в
+// порядке публикации.
+func (f *InFlight) Unconfirmed() []*nats.Msg {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	msgs := f.msgs
+	f.msgs = nil
+	return msgs
+}
@@ -0,0 +1,72 @@
+package spool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type publisherFunc func(msg *nats.Msg) error
+
+func (f publisherFunc) PublishMsg(msg *nats.Msg) error { return f(msg) }
+
+type flusherFunc func(timeout time.Duration) error
+
+func (f flusherFunc) FlushTimeout(timeout time.Duration) error { return f(timeout) }
+
+func subjects(msgs []*nats.Msg) []string {
+	s := make([]string, len(msgs))
+	for i, msg := range msgs {
+		s[i] = msg.Subject
+	}
+	return s
+}
+
+func TestInFlight(t *testing.T) {
+	errDown := errors.New("nats is down")
+	var flushErr error
+	var f *InFlight
+	f = NewInFlight(
+		publisherFunc(func(msg *nats.Msg) error {
+			if msg.Subject == "rejected" {
+				return errDown
+			}
+			return nil
+		}),
+		flusherFunc(func(time.Duration) error {
+			// Опубликованное во время сброса не подтверждается им.
+			f.PublishMsg(nats.NewMsg("during_flush"))
+			return flushErr
+		}),
+	)
+
+	f.PublishMsg(nats.NewMsg("confirmed"))
+	if err := f.PublishMsg(nats.NewMsg("rejected")); err != errDown {
+		t.Fatalf("PublishMsg = %v, want %v", err, errDown)
+	}
+	if err := f.Confirm(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	flushErr = errDown
+	f.PublishMsg(nats.NewMsg("lost"))
+	if err := f.Confirm(time.Second); err != errDown {
+		t.Fatalf("Confirm = %v, want %v", err, errDown)
+	}
+
+	got := subjects(f.Unconfirmed())
+	want := []string{"during_flush", "lost", "during_flush"}
+	if len(got) != len(want) {
+		t.Fatalf("unconfirmed = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unconfirmed = %v, want %v", got, want)
+		}
+	}
+	if rest := f.Unconfirmed(); len(rest) != 0 {
+		t.Errorf("second Unconfirmed = %v", subjects(rest))
+	}
+}
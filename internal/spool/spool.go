@@ -0,0 +1,105 @@
+// Package spool откладывает в кэш сообщения NATS, которые не удалось
+// опубликовать перед остановкой, и публикует их при следующем запуске.
+//
+// Сообщения хранятся без срока жизни под ключами prefix+<номер>; номер
+// выдаёт счётчик в том же кэше, поэтому повтор идёт в порядке откладывания
+// даже между экземплярами. Ключ забирается GETDEL, так что одно сообщение
+// повторяет только один экземпляр. С бэкендом кэша в памяти отложенное
+// теряется вместе с процессом.
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+
+	"hezzl-test/internal/deps"
+)
+
+type Spool struct {
+	cache  deps.Cache
+	prefix string
+}
+
+func New(cache deps.Cache, prefix string) *Spool {
+	return &Spool{cache: cache, prefix: prefix}
+}
+
+type message struct {
+	Subject string      `json:"subject"`
+	Header  nats.Header `json:"header,omitempty"`
+	Data    []byte      `json:"data"`
+}
+
+func (s *Spool) seqKey() string {
+	return s.prefix + "seq"
+}
+
+// Save откладывает msg до Replay.
+func (s *Spool) Save(ctx context.Context, msg *nats.Msg) error {
+	data, err := json.Marshal(message{Subject: msg.Subject, Header: msg.Header, Data: msg.Data})
+	if err != nil {
+		return err
+	}
+	n, err := s.cache.IncrBy(ctx, s.seqKey(), 1).Result()
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, s.prefix+strconv.FormatInt(n, 10), data, 0).Err()
+}
+
+// Replay публикует отложенные сообщения через pub по порядку и возвращает
+// их число. Сообщение, которое не удалось опубликовать, возвращается в
+// кэш, и повтор прерывается: остальные дождутся следующего запуска.
+func (s *Spool) Replay(ctx context.Context, pub deps.Publisher) (int, error) {
+	iter := s.cache.Scan(ctx, 0, s.prefix+"*", 100).Iterator()
+	type entry struct {
+		key string
+		n   int64
+	}
+	var entries []entry
+	for iter.Next(ctx) {
+		key := iter.Val()
+		n, err := strconv.ParseInt(strings.TrimPrefix(key, s.prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{key: key, n: n})
+	}
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].n < entries[j].n })
+
+	replayed := 0
+	for _, e := range entries {
+		data, err := s.cache.GetDel(ctx, e.key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return replayed, err
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			return replayed, fmt.Errorf("spool: %s: %w", e.key, err)
+		}
+		msg := nats.NewMsg(m.Subject)
+		msg.Header = m.Header
+		msg.Data = m.Data
+		if err := pub.PublishMsg(msg); err != nil {
+			if rerr := s.cache.Set(ctx, e.key, data, 0).Err(); rerr != nil {
+				return replayed, fmt.Errorf("spool: %s lost: %v (restore: %v)", e.key, err, rerr)
+			}
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
@@ -0,0 +1,247 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: hezzl-test/internal/storage (interfaces: GoodsRepository,ProjectsRepository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mocks/storage.go -package=mocks hezzl-test/internal/storage GoodsRepository,ProjectsRepository
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	storage "hezzl-test/internal/storage"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockGoodsRepository is a mock of GoodsRepository interface.
+type MockGoodsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockGoodsRepositoryMockRecorder
+}
+
+// MockGoodsRepositoryMockRecorder is the mock recorder for MockGoodsRepository.
+type MockGoodsRepositoryMockRecorder struct {
+	mock *MockGoodsRepository
+}
+
+// NewMockGoodsRepository creates a new mock instance.
+func NewMockGoodsRepository(ctrl *gomock.Controller) *MockGoodsRepository {
+	mock := &MockGoodsRepository{ctrl: ctrl}
+	mock.recorder = &MockGoodsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGoodsRepository) EXPECT() *MockGoodsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockGoodsRepository) Create(arg0 context.Context, arg1 int, arg2 storage.NewGood) (storage.Written, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(storage.Written)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockGoodsRepositoryMockRecorder) Create(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockGoodsRepository)(nil).Create), arg0, arg1, arg2)
+}
+
+// Delete mocks base method.
+func (m *MockGoodsRepository) Delete(arg0 context.Context, arg1, arg2, arg3 int, arg4 bool) (storage.Deleted, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(storage.Deleted)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockGoodsRepositoryMockRecorder) Delete(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockGoodsRepository)(nil).Delete), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Favorites mocks base method.
+func (m *MockGoodsRepository) Favorites(arg0 context.Context, arg1 string, arg2 []int) (map[int]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Favorites", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[int]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Favorites indicates an expected call of Favorites.
+func (mr *MockGoodsRepositoryMockRecorder) Favorites(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Favorites", reflect.TypeOf((*MockGoodsRepository)(nil).Favorites), arg0, arg1, arg2)
+}
+
+// Get mocks base method.
+func (m *MockGoodsRepository) Get(arg0 context.Context, arg1, arg2 int) (storage.Good, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2)
+	ret0, _ := ret[0].(storage.Good)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockGoodsRepositoryMockRecorder) Get(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockGoodsRepository)(nil).Get), arg0, arg1, arg2)
+}
+
+// GetMany mocks base method.
+func (m *MockGoodsRepository) GetMany(arg0 context.Context, arg1 int, arg2 []int) ([]storage.Good, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMany", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]storage.Good)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMany indicates an expected call of GetMany.
+func (mr *MockGoodsRepositoryMockRecorder) GetMany(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMany", reflect.TypeOf((*MockGoodsRepository)(nil).GetMany), arg0, arg1, arg2)
+}
+
+// List mocks base method.
+func (m *MockGoodsRepository) List(arg0 context.Context, arg1 storage.GoodsQuery) (storage.Page, storage.GoodsRows, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].(storage.Page)
+	ret1, _ := ret[1].(storage.GoodsRows)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List.
+func (mr *MockGoodsRepositoryMockRecorder) List(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockGoodsRepository)(nil).List), arg0, arg1)
+}
+
+// ListAsOf mocks base method.
+func (m *MockGoodsRepository) ListAsOf(arg0 context.Context, arg1 int, arg2 time.Time, arg3, arg4 int) (storage.Page, []storage.Good, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAsOf", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(storage.Page)
+	ret1, _ := ret[1].([]storage.Good)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListAsOf indicates an expected call of ListAsOf.
+func (mr *MockGoodsRepositoryMockRecorder) ListAsOf(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAsOf", reflect.TypeOf((*MockGoodsRepository)(nil).ListAsOf), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Reorder mocks base method.
+func (m *MockGoodsRepository) Reorder(arg0 context.Context, arg1, arg2, arg3, arg4 int, arg5 bool) ([]storage.PriorityChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reorder", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].([]storage.PriorityChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reorder indicates an expected call of Reorder.
+func (mr *MockGoodsRepositoryMockRecorder) Reorder(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reorder", reflect.TypeOf((*MockGoodsRepository)(nil).Reorder), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// Update mocks base method.
+func (m *MockGoodsRepository) Update(arg0 context.Context, arg1, arg2, arg3 int, arg4 storage.GoodUpdate) (storage.Written, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(storage.Written)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockGoodsRepositoryMockRecorder) Update(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockGoodsRepository)(nil).Update), arg0, arg1, arg2, arg3, arg4)
+}
+
+// MockProjectsRepository is a mock of ProjectsRepository interface.
+type MockProjectsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockProjectsRepositoryMockRecorder
+}
+
+// MockProjectsRepositoryMockRecorder is the mock recorder for MockProjectsRepository.
+type MockProjectsRepositoryMockRecorder struct {
+	mock *MockProjectsRepository
+}
+
+// NewMockProjectsRepository creates a new mock instance.
+func NewMockProjectsRepository(ctrl *gomock.Controller) *MockProjectsRepository {
+	mock := &MockProjectsRepository{ctrl: ctrl}
+	mock.recorder = &MockProjectsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProjectsRepository) EXPECT() *MockProjectsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockProjectsRepository) Get(arg0 context.Context, arg1, arg2 int) (storage.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2)
+	ret0, _ := ret[0].(storage.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockProjectsRepositoryMockRecorder) Get(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockProjectsRepository)(nil).Get), arg0, arg1, arg2)
+}
+
+// GetMany mocks base method.
+func (m *MockProjectsRepository) GetMany(arg0 context.Context, arg1 int, arg2 []int) ([]storage.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMany", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]storage.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMany indicates an expected call of GetMany.
+func (mr *MockProjectsRepositoryMockRecorder) GetMany(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMany", reflect.TypeOf((*MockProjectsRepository)(nil).GetMany), arg0, arg1, arg2)
+}
+
+// List mocks base method.
+func (m *MockProjectsRepository) List(arg0 context.Context, arg1 int) ([]storage.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]storage.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockProjectsRepositoryMockRecorder) List(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockProjectsRepository)(nil).List), arg0, arg1)
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"hezzl-test/internal/crypt"
+	"hezzl-test/internal/queries"
+)
+
+// PostgresGoods читает и удаляет товары в Postgres; описания
+// расшифровываются ключами keys. Остальные методы GoodsRepository
+// добавляет к нему пакет main.
+type PostgresGoods struct {
+	db   *sql.DB
+	keys *crypt.Keyring
+}
+
+func NewPostgresGoods(db *sql.DB, keys *crypt.Keyring) *PostgresGoods {
+	return &PostgresGoods{db: db, keys: keys}
+}
+
+// good собирает Good из строки goods и расшифровывает описание.
+func (g *PostgresGoods) good(row queries.Good) (Good, error) {
+	good := Good{
+		ID:        int(row.ID),
+		ProjectID: int(row.ProjectID),
+		Name:      row.Name,
+		Priority:  int(row.Priority),
+		Removed:   row.Removed,
+		CreatedAt: row.CreatedAt,
+		Views:     row.Views,
+	}
+	if row.CategoryID.Valid {
+		categoryID := int(row.CategoryID.Int32)
+		good.CategoryID = &categoryID
+	}
+	if len(row.Labels) > 0 {
+		if err := json.Unmarshal(row.Labels, &good.Labels); err != nil {
+			return good, err
+		}
+	}
+	description, err := g.keys.Decrypt(row.Description)
+	if err != nil {
+		return good, fmt.Errorf("decrypt description: %w", err)
+	}
+	good.Description = description
+	return good, nil
+}
+
+func (g *PostgresGoods) Get(ctx context.Context, tenantID, id int) (Good, error) {
+	row, err := queries.New(g.db).GetGood(ctx, queries.GetGoodParams{ID: int32(id), TenantID: int32(tenantID)})
+	if err == sql.ErrNoRows {
+		return Good{}, ErrGoodNotFound
+	}
+	if err != nil {
+		return Good{}, err
+	}
+	return g.good(row)
+}
+
+func (g *PostgresGoods) GetMany(ctx context.Context, tenantID int, ids []int) ([]Good, error) {
+	rows, err := queries.New(g.db).ListGoodsByIDs(ctx, queries.ListGoodsByIDsParams{Ids: int32s(ids), TenantID: int32(tenantID)})
+	if err != nil {
+		return nil, err
+	}
+
+	var goods []Good
+	for _, row := range rows {
+		good, err := g.good(row)
+		if err != nil {
+			return nil, err
+		}
+		goods = append(goods, good)
+	}
+	return goods, nil
+}
+
+// int32s приводит id к типу параметров запросов sqlc.
+func int32s(ids []int) []int32 {
+	out := make([]int32, len(ids))
+	for i, id := range ids {
+		out[i] = int32(id)
+	}
+	return out
+}
+
+func (g *PostgresGoods) Delete(ctx context.Context, tenantID, projectID, id int, dryRun bool) (Deleted, error) {
+	var deleted Deleted
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return deleted, err
+	}
+	defer tx.Rollback()
+
+	if err := CheckWritable(ctx, tx, tenantID, projectID); err != nil {
+		return deleted, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT a.object_key FROM good_attachments a JOIN goods g ON g.id = a.good_id
+		WHERE g.id = $1 AND g.project_id = $2 AND g.tenant_id = $3`,
+		id, projectID, tenantID)
+	if err != nil {
+		return deleted, err
+	}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return deleted, err
+		}
+		deleted.ObjectKeys = append(deleted.ObjectKeys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return deleted, err
+	}
+
+	err = tx.QueryRowContext(ctx, "DELETE FROM goods WHERE id = $1 AND project_id = $2 AND tenant_id = $3 RETURNING version + 1",
+		id, projectID, tenantID).Scan(&deleted.Version)
+	if err == sql.ErrNoRows {
+		return deleted, ErrGoodNotFound
+	}
+	if err != nil {
+		return deleted, err
+	}
+
+	if dryRun {
+		return deleted, nil
+	}
+	return deleted, tx.Commit()
+}
+
+func (g *PostgresGoods) Favorites(ctx context.Context, user string, ids []int) (map[int]bool, error) {
+	rows, err := g.db.QueryContext(ctx, "SELECT good_id FROM good_favorites WHERE user_id = $1 AND good_id = ANY($2)",
+		user, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	favorites := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		favorites[id] = true
+	}
+	return favorites, rows.Err()
+}
+
+// Querier — *sql.DB, *sql.Tx или подготовленные запросы обработчиков.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// CheckWritable возвращает ErrProjectRemoved или ErrProjectArchived, если
+// товары проекта нельзя изменять. Несуществующий проект проверку проходит:
+// его отсутствие обнаружит сама мутация.
+func CheckWritable(ctx context.Context, q Querier, tenantID, projectID int) error {
+	var archived, removed bool
+	err := q.QueryRowContext(ctx, "SELECT archived, removed FROM projects WHERE id = $1 AND tenant_id = $2", projectID, tenantID).Scan(&archived, &removed)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if removed {
+		return ErrProjectRemoved
+	}
+	if archived {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// PostgresProjects хранит проекты в Postgres.
+type PostgresProjects struct {
+	db *sql.DB
+}
+
+func NewPostgresProjects(db *sql.DB) *PostgresProjects {
+	return &PostgresProjects{db: db}
+}
+
+func (p *PostgresProjects) List(ctx context.Context, tenantID int) ([]Project, error) {
+	rows, err := queries.New(p.db).ListProjects(ctx, int32(tenantID))
+	if err != nil {
+		return nil, err
+	}
+	projects := make([]Project, len(rows))
+	for i, row := range rows {
+		projects[i] = Project{ID: int(row.ID), Name: row.Name, CreatedAt: row.CreatedAt}
+	}
+	return projects, nil
+}
+
+func (p *PostgresProjects) Get(ctx context.Context, tenantID, id int) (Project, error) {
+	var project Project
+	err := p.db.QueryRowContext(ctx, "SELECT id, name, created_at, archived, removed FROM projects WHERE id = $1 AND tenant_id = $2", id, tenantID).
+		Scan(&project.ID, &project.Name, &project.CreatedAt, &project.Archived, &project.Removed)
+	if err == sql.ErrNoRows {
+		return project, ErrProjectNotFound
+	}
+	return project, err
+}
+
+func (p *PostgresProjects) GetMany(ctx context.Context, tenantID int, ids []int) ([]Project, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT id, name, created_at, archived, removed FROM projects WHERE id = ANY($1) AND tenant_id = $2",
+		pq.Array(ids), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []Project
+	for rows.Next() {
+		var project Project
+		if err := rows.Scan(&project.ID, &project.Name, &project.CreatedAt, &project.Archived, &project.Removed); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+var _ ProjectsRepository = (*PostgresProjects)(nil)
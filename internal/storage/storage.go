@@ -0,0 +1,198 @@
+// Package storage отделяет обработчики от database/sql: они получают
+// GoodsRepository и ProjectsRepository, а не *sql.DB, поэтому их можно
+// проверять с моками из storage/mocks. Реализации поверх Postgres —
+// PostgresGoods и PostgresProjects.
+//
+// Каждый метод репозитория сам открывает и фиксирует свою транзакцию:
+// создание, изменение и перестановка товара выполняются в ней вместе с
+// настройками проекта, счётчиком приоритетов и аудитом, поэтому
+// обработчику не нужен ни *sql.DB, ни *sql.Tx. Чтение, списки и удаление
+// реализует PostgresGoods; создание, изменение и перестановку — пакет main
+// поверх него, потому что стратегии приоритетов, настройки проектов и
+// аудит у этих операций общие с пакетной загрузкой, переносом товаров и
+// отложенными изменениями.
+//
+// Ошибки репозиториев — из пакета errs, например ErrGoodNotFound, и в ответ
+// их переводит response.Fail.
+package storage
+
+import (
+	"context"
+	"time"
+
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/labels"
+)
+
+//go:generate go run go.uber.org/mock/mockgen -destination=mocks/storage.go -package=mocks hezzl-test/internal/storage GoodsRepository,ProjectsRepository
+
+var (
+	ErrGoodNotFound    = errs.NotFound("errors.good.notFound")
+	ErrProjectNotFound = errs.NotFound("errors.project.notFound")
+	ErrProjectArchived = errs.Conflict("errors.project.archived")
+	ErrProjectRemoved  = errs.Conflict("errors.project.removed")
+)
+
+type Good struct {
+	ID          int
+	ProjectID   int
+	CategoryID  *int
+	Name        string
+	Description string
+	Priority    int
+	Removed     bool
+	Labels      labels.Labels
+	CreatedAt   time.Time
+	Views       int64
+	// Favorite — отметка избранного пользователя GoodsQuery.FavoritesOf;
+	// nil, если она не запрашивалась.
+	Favorite *bool
+}
+
+type Project struct {
+	ID        int
+	Name      string
+	CreatedAt time.Time
+	Archived  bool
+	Removed   bool
+}
+
+// Deleted — результат удаления товара.
+type Deleted struct {
+	// Version — версия товара для события good_deleted.
+	Version int64
+	// ObjectKeys — ключи вложений товара в хранилище объектов; их удаляет
+	// вызывающий после фиксации.
+	ObjectKeys []string
+}
+
+// GoodsQuery — фильтры и страница списка товаров арендатора.
+type GoodsQuery struct {
+	TenantID        int
+	Limit           int
+	Offset          int
+	IncludeArchived bool
+	Labels          labels.Selector
+	// CategoryID отбирает товары категории вместе с её потомками.
+	CategoryID int
+	// Sort — порядок страницы: по приоритету (пустой) или SortPopularity.
+	Sort string
+	// FavoritesOf заполняет Good.Favorite для пользователя.
+	FavoritesOf string
+}
+
+// SortPopularity упорядочивает список по просмотрам.
+const SortPopularity = "popularity"
+
+// Page — число товаров, подходящих под фильтры списка, без учёта страницы.
+type Page struct {
+	Total   int
+	Removed int
+}
+
+// GoodsRows — курсор по странице товаров; его закрывает вызывающий.
+type GoodsRows interface {
+	Next() bool
+	Good() (Good, error)
+	Err() error
+	Close() error
+}
+
+// NewGood — товар для Create.
+type NewGood struct {
+	ProjectID  int
+	CategoryID *int
+	// Name — название; пустое заменяется названием по умолчанию на языке
+	// проекта.
+	Name        string
+	Description string
+	// Priority — позиция товара в проекте; 0 ставит его в конец.
+	Priority int
+	Removed  bool
+	Labels   labels.Labels
+	// AllowDuplicate пропускает поиск товаров с похожими названиями.
+	AllowDuplicate bool
+}
+
+// GoodUpdate — новые значения полей товара для Update.
+type GoodUpdate struct {
+	Name        string
+	Description string
+	// Priority — новая позиция; 0 оставляет прежнюю.
+	Priority int
+	Removed  bool
+	// Labels — новые метки; nil оставляет прежние.
+	Labels labels.Labels
+}
+
+// Written — результат Create и Update.
+type Written struct {
+	Good Good
+	// Old — товар до изменения; у Create пустой.
+	Old Good
+	// Version — версия товара для события.
+	Version int64
+	// Encrypted — описания товаров проекта шифруются и не попадают в
+	// события.
+	Encrypted bool
+	// CacheTTL — время жизни кэша товаров из настроек проекта; 0 —
+	// значение по умолчанию.
+	CacheTTL time.Duration
+}
+
+// CacheTime — время жизни кэша товара: из настроек проекта или def.
+func (w Written) CacheTime(def time.Duration) time.Duration {
+	if w.CacheTTL <= 0 {
+		return def
+	}
+	return w.CacheTTL
+}
+
+// PriorityChange — изменение приоритета одного товара при перестановке;
+// в таком виде оно попадает в ответ и событие goods_reordered.
+type PriorityChange struct {
+	ID       int `json:"id"`
+	Priority int `json:"priority"`
+	Previous int `json:"previous"`
+}
+
+type GoodsRepository interface {
+	// Get возвращает товар арендатора с расшифрованным описанием или
+	// ErrGoodNotFound.
+	Get(ctx context.Context, tenantID, id int) (Good, error)
+	// GetMany возвращает найденные товары из ids в произвольном порядке.
+	GetMany(ctx context.Context, tenantID int, ids []int) ([]Good, error)
+	// Delete удаляет товар id проекта projectID. Товары архивного или
+	// удалённого проекта не удаляются (ErrProjectArchived,
+	// ErrProjectRemoved); с dryRun изменения откатываются.
+	Delete(ctx context.Context, tenantID, projectID, id int, dryRun bool) (Deleted, error)
+	// List считает товары по фильтрам q и открывает курсор по странице.
+	List(ctx context.Context, q GoodsQuery) (Page, GoodsRows, error)
+	// ListAsOf восстанавливает страницу товаров арендатора на момент at по
+	// журналу событий. Метки, категории и просмотры журнал не хранит.
+	ListAsOf(ctx context.Context, tenantID int, at time.Time, limit, offset int) (Page, []Good, error)
+	// Favorites возвращает те из товаров ids, что user добавил в избранное.
+	Favorites(ctx context.Context, user string, ids []int) (map[int]bool, error)
+	// Create добавляет товар в проект и ставит его на позицию по стратегии
+	// проекта. Товары с похожими названиями, занятая позиция при стратегии
+	// reject и превышение квоты проекта отклоняют создание.
+	Create(ctx context.Context, tenantID int, good NewGood) (Written, error)
+	// Update изменяет товар id проекта projectID или возвращает
+	// ErrGoodNotFound; изменение записывается в аудит.
+	Update(ctx context.Context, tenantID, projectID, id int, update GoodUpdate) (Written, error)
+	// Reorder ставит товар id проекта projectID на позицию priority и
+	// возвращает изменившиеся приоритеты товаров проекта; с dryRun
+	// изменения откатываются.
+	Reorder(ctx context.Context, tenantID, projectID, id, priority int, dryRun bool) ([]PriorityChange, error)
+}
+
+type ProjectsRepository interface {
+	// List возвращает неудалённые проекты арендатора по возрастанию id.
+	List(ctx context.Context, tenantID int) ([]Project, error)
+	// Get возвращает проект арендатора, в том числе удалённый, или
+	// ErrProjectNotFound.
+	Get(ctx context.Context, tenantID, id int) (Project, error)
+	// GetMany возвращает найденные проекты арендатора из ids, в том числе
+	// удалённые, в произвольном порядке.
+	GetMany(ctx context.Context, tenantID int, ids []int) ([]Project, error)
+}
@@ -0,0 +1,261 @@
+package tap
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/tenant"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	recordsKey = "tap:record:"
+	armedKey   = "tap:armed:"
+	redacted   = "[REDACTED]"
+)
+
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Ключи JSON, значения которых не записываются; сравнение без учёта
+// регистра и по вхождению подстроки.
+var secretFields = []string{"password", "secret", "token", "authorization", "accesskey", "apikey"}
+
+type Config struct {
+	// SampleRate — доля запросов от 0 до 1, записываемых без явного запроса.
+	SampleRate float64
+	// MaxBody байт тела запроса и ответа попадают в запись, остальное отбрасывается.
+	MaxBody int
+	// TTL — сколько хранится каждая запись.
+	TTL time.Duration
+}
+
+type Record struct {
+	RequestID      string      `json:"request_id"`
+	TenantID       int         `json:"tenant_id"`
+	Time           time.Time   `json:"time"`
+	Duration       string      `json:"duration"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"request_header"`
+	RequestBody    string      `json:"request_body"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"response_header"`
+	ResponseBody   string      `json:"response_body"`
+}
+
+// Tap записывает в кэш полные запросы и ответы для разбора инцидентов:
+// случайную долю трафика и запросы, чей X-Request-ID заранее отмечен через Arm.
+// Секреты в заголовках и JSON-телах вырезаются до записи.
+type Tap struct {
+	cache deps.Cache
+	cfg   Config
+}
+
+func New(cache deps.Cache, cfg Config) *Tap {
+	return &Tap{cache: cache, cfg: cfg}
+}
+
+// Arm отмечает запрос с данным X-Request-ID для записи на ttl.
+func (t *Tap) Arm(ctx context.Context, requestID string, ttl time.Duration) error {
+	return t.cache.Set(ctx, armedKey+requestID, 1, ttl).Err()
+}
+
+// Records возвращает до limit последних записей, новые первыми.
+func (t *Tap) Records(ctx context.Context, limit int) ([]Record, error) {
+	iter := t.cache.Scan(ctx, 0, recordsKey+"*", 100).Iterator()
+	records := []Record{}
+	for iter.Next(ctx) {
+		data, err := t.cache.Get(ctx, iter.Val()).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rec Record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Time.After(records[j].Time) })
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (t *Tap) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		if !t.sampled(r.Context(), requestID) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reqBody, _ := io.ReadAll(io.LimitReader(r.Body, int64(t.cfg.MaxBody)))
+		r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(reqBody), r.Body), Closer: r.Body}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, max: t.cfg.MaxBody}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		t.store(Record{
+			RequestID:      requestID,
+			TenantID:       tenant.FromContext(r.Context()),
+			Time:           start,
+			Duration:       time.Since(start).String(),
+			Method:         r.Method,
+			URL:            r.URL.String(),
+			RequestHeader:  redactHeader(r.Header),
+			RequestBody:    redactBody(reqBody),
+			Status:         rec.status,
+			ResponseHeader: redactHeader(w.Header()),
+			ResponseBody:   redactBody(rec.body.Bytes()),
+		})
+	})
+}
+
+func (t *Tap) sampled(ctx context.Context, requestID string) bool {
+	if t.cfg.SampleRate > 0 && mathrand.Float64() < t.cfg.SampleRate {
+		return true
+	}
+	return t.cache.Get(ctx, armedKey+requestID).Err() == nil
+}
+
+// store пишет запись в фоне: ответ клиенту уже отправлен.
+func (t *Tap) store(rec Record) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		key := fmt.Sprintf("%s%s:%d", recordsKey, rec.RequestID, rec.Time.UnixNano())
+		if err := t.cache.Set(ctx, key, data, t.cfg.TTL).Err(); err != nil {
+			log.Printf("tap: store %s: %v", rec.RequestID, err)
+		}
+	}()
+}
+
+func redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range secretHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, redacted)
+		}
+	}
+	return out
+}
+
+// redactBody вырезает секретные поля из JSON; тело другого формата
+// записывается как есть.
+func redactBody(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	data, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSecretField(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactValue(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
+
+func isSecretField(key string) bool {
+	key = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	for _, field := range secretFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recorder пропускает ответ клиенту и копирует первые max байт тела.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	max    int
+}
+
+func (rw *recorder) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recorder) Write(p []byte) (int, error) {
+	if free := rw.max - rw.body.Len(); free > 0 {
+		if len(p) < free {
+			free = len(p)
+		}
+		rw.body.Write(p[:free])
+	}
+	return rw.ResponseWriter.Write(p)
+}
+
+func (rw *recorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *recorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,112 @@
+package tap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/localcache"
+)
+
+func TestRedactHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("Cookie", "session=1")
+	h.Set("X-Api-Key", "key")
+	h.Set("Content-Type", "application/json")
+
+	out := redactHeader(h)
+	for _, name := range []string{"Authorization", "Cookie", "X-Api-Key"} {
+		if got := out.Get(name); got != redacted {
+			t.Errorf("%s = %q, want %s", name, got, redacted)
+		}
+	}
+	if out.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", out.Get("Content-Type"))
+	}
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Error("redactHeader changed the original header")
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"flat", `{"name":"tea","password":"p"}`, `{"name":"tea","password":"[REDACTED]"}`},
+		{"case and separators", `{"Access_Key":"a","api-key":"b","refreshToken":"c"}`,
+			`{"Access_Key":"[REDACTED]","api-key":"[REDACTED]","refreshToken":"[REDACTED]"}`},
+		{"nested", `{"user":{"secret":{"x":1}},"items":[{"token":"t","id":1}]}`,
+			`{"items":[{"id":1,"token":"[REDACTED]"}],"user":{"secret":"[REDACTED]"}}`},
+		{"not json", "password=p", "password=p"},
+		{"truncated json", `{"password":"p`, `{"password":"p`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody([]byte(tt.body)); got != tt.want {
+				t.Errorf("redactBody(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRecordsArmedRequest(t *testing.T) {
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	ctx := context.Background()
+	tap := New(cache, Config{MaxBody: 64, TTL: time.Minute})
+	if err := tap.Arm(ctx, "req-1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	h := tap.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Обработчик читает тело целиком, хотя в запись попадает только MaxBody.
+		body, _ := io.ReadAll(r.Body)
+		if len(body) != 100 {
+			t.Errorf("handler got %d bytes of body, want 100", len(body))
+		}
+		w.Header().Set("Set-Cookie", "session=2")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"token":"t","id":1}`)
+	}))
+
+	for _, id := range []string{"req-1", "req-2"} {
+		r := httptest.NewRequest(http.MethodPost, "/good/create", strings.NewReader(strings.Repeat("x", 100)))
+		r.Header.Set(RequestIDHeader, id)
+		r.Header.Set("Authorization", "Bearer abc")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Header().Get(RequestIDHeader) != id || w.Code != http.StatusCreated {
+			t.Fatalf("%s: status %d, request id %q", id, w.Code, w.Header().Get(RequestIDHeader))
+		}
+	}
+
+	// Запись сохраняется в фоне.
+	var records []Record
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		var err error
+		if records, err = tap.Records(ctx, 10); err != nil {
+			t.Fatal(err)
+		}
+		if len(records) > 0 {
+			break
+		}
+	}
+	if len(records) != 1 || records[0].RequestID != "req-1" {
+		t.Fatalf("records = %+v, want only the armed req-1", records)
+	}
+	rec := records[0]
+	if rec.RequestHeader.Get("Authorization") != redacted || rec.ResponseHeader.Get("Set-Cookie") != redacted {
+		t.Errorf("headers are not redacted: %v %v", rec.RequestHeader, rec.ResponseHeader)
+	}
+	if len(rec.RequestBody) != 64 {
+		t.Errorf("request body is %d bytes, want MaxBody 64", len(rec.RequestBody))
+	}
+	if rec.Status != http.StatusCreated || rec.ResponseBody != `{"id":1,"token":"[REDACTED]"}` {
+		t.Errorf("response = %d %s", rec.Status, rec.ResponseBody)
+	}
+}
@@ -0,0 +1,165 @@
+package tenant
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"hezzl-test/internal/response"
+)
+
+const (
+	Header     = "X-Tenant-ID"
+	UserHeader = "X-User-ID"
+	NATSHeader = "Tenant-Id"
+	// NATSUserHeader — пользователь, чей запрос породил событие.
+	NATSUserHeader = "User-Id"
+)
+
+type ctxKey struct{}
+
+type userCtxKey struct{}
+
+var (
+	errMissing = errors.New("tenant is not specified")
+	errInvalid = errors.New("invalid tenant")
+	errToken   = errors.New("invalid bearer token")
+	errHeader  = errors.New("tenant headers are not accepted with token authentication")
+)
+
+func WithTenant(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) int {
+	id, _ := ctx.Value(ctxKey{}).(int)
+	return id
+}
+
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userCtxKey{}, user)
+}
+
+// UserFromContext возвращает пользователя запроса (claim sub из JWT или
+// заголовок X-User-ID) либо пустую строку для анонимного запроса.
+func UserFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userCtxKey{}).(string)
+	return user
+}
+
+// Middleware определяет арендатора запроса. Если secret задан, арендатор и
+// пользователь берутся только из claims tenant_id и sub проверенного JWT
+// (HS256, подписанного secret): запрос без токена отклоняется, а заголовки
+// X-Tenant-ID и X-User-ID не принимаются, чтобы клиент не мог назваться
+// чужим арендатором. Без secret (локальный запуск) арендатор берётся из
+// X-Tenant-ID, а если его нет — fallback; fallback == 0 означает, что
+// арендатор обязателен.
+//
+// Запрос, арендатора которого уже определил внешний слой (токен проекта),
+// проходит без изменений.
+func Middleware(secret []byte, fallback int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if FromContext(r.Context()) != 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, user, err := resolve(r, secret, fallback)
+			if err != nil {
+				status := http.StatusBadRequest
+				if err == errToken || err == errMissing && len(secret) > 0 {
+					status = http.StatusUnauthorized
+				}
+				response.Error(w, r, status, response.CodeTenant, "errors.tenant.invalid")
+				return
+			}
+			ctx := WithTenant(r.Context(), id)
+			if user != "" {
+				ctx = WithUser(ctx, user)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func resolve(r *http.Request, secret []byte, fallback int) (int, string, error) {
+	if len(secret) > 0 {
+		if r.Header.Get(Header) != "" || r.Header.Get(UserHeader) != "" {
+			return 0, "", errHeader
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return 0, "", errMissing
+		}
+		c, err := parseClaims(token, secret)
+		if err != nil {
+			return 0, "", err
+		}
+		return c.TenantID, c.Subject, nil
+	}
+
+	user := r.Header.Get(UserHeader)
+	if v := r.Header.Get(Header); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return 0, "", errInvalid
+		}
+		return id, user, nil
+	}
+	if fallback != 0 {
+		return fallback, user, nil
+	}
+	return 0, "", errMissing
+}
+
+type claims struct {
+	TenantID int    `json:"tenant_id"`
+	Subject  string `json:"sub"`
+	Exp      int64  `json:"exp"`
+}
+
+func parseClaims(token string, secret []byte) (claims, error) {
+	var c claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return c, errToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return c, errToken
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return c, errToken
+	}
+
+	if err := decodeSegment(parts[1], &c); err != nil || c.TenantID <= 0 {
+		return c, errToken
+	}
+	if c.Exp != 0 && time.Now().Unix() > c.Exp {
+		return c, errToken
+	}
+	return c, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,115 @@
+package tenant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var secret = []byte("test secret")
+
+// sign собирает JWT из заголовка и claims, подписанный HS256 ключом key.
+func sign(header, payload string, key []byte) string {
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+const hs256 = `{"alg":"HS256","typ":"JWT"}`
+
+func TestMiddlewareJWT(t *testing.T) {
+	valid := sign(hs256, `{"tenant_id":7,"sub":"u1"}`, secret)
+	expired := sign(hs256, `{"tenant_id":7,"exp":`+strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)+`}`, secret)
+	future := sign(hs256, `{"tenant_id":7,"exp":`+strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)+`}`, secret)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+		tenant  int
+		user    string
+	}{
+		{name: "valid", headers: map[string]string{"Authorization": "Bearer " + valid}, status: http.StatusOK, tenant: 7, user: "u1"},
+		{name: "not expired", headers: map[string]string{"Authorization": "Bearer " + future}, status: http.StatusOK, tenant: 7},
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "not bearer", headers: map[string]string{"Authorization": "Basic " + valid}, status: http.StatusUnauthorized},
+		{name: "expired", headers: map[string]string{"Authorization": "Bearer " + expired}, status: http.StatusUnauthorized},
+		{name: "wrong secret", headers: map[string]string{"Authorization": "Bearer " + sign(hs256, `{"tenant_id":7}`, []byte("other"))}, status: http.StatusUnauthorized},
+		{name: "alg none", headers: map[string]string{"Authorization": "Bearer " + sign(`{"alg":"none"}`, `{"tenant_id":7}`, secret)}, status: http.StatusUnauthorized},
+		{name: "no tenant claim", headers: map[string]string{"Authorization": "Bearer " + sign(hs256, `{"sub":"u1"}`, secret)}, status: http.StatusUnauthorized},
+		{name: "two segments", headers: map[string]string{"Authorization": "Bearer a.b"}, status: http.StatusUnauthorized},
+		// С токеном заголовки арендатора не принимаются, даже вместе с ним.
+		{name: "tenant header", headers: map[string]string{"Authorization": "Bearer " + valid, Header: "8"}, status: http.StatusBadRequest},
+		{name: "user header", headers: map[string]string{"Authorization": "Bearer " + valid, UserHeader: "u2"}, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, tenant, user := run(Middleware(secret, 0), tt.headers)
+			if status != tt.status || tenant != tt.tenant || user != tt.user {
+				t.Errorf("got %d, tenant %d, user %q; want %d, tenant %d, user %q", status, tenant, user, tt.status, tt.tenant, tt.user)
+			}
+		})
+	}
+}
+
+func TestMiddlewareHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback int
+		headers  map[string]string
+		status   int
+		tenant   int
+	}{
+		{name: "header", headers: map[string]string{Header: "7", UserHeader: "u1"}, status: http.StatusOK, tenant: 7},
+		{name: "fallback", fallback: 1, status: http.StatusOK, tenant: 1},
+		{name: "missing", status: http.StatusBadRequest},
+		{name: "not a number", headers: map[string]string{Header: "seven"}, status: http.StatusBadRequest},
+		{name: "zero", headers: map[string]string{Header: "0"}, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, tenant, _ := run(Middleware(nil, tt.fallback), tt.headers)
+			if status != tt.status || tenant != tt.tenant {
+				t.Errorf("got %d, tenant %d; want %d, tenant %d", status, tenant, tt.status, tt.tenant)
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeepsResolvedTenant(t *testing.T) {
+	h := Middleware(secret, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := FromContext(r.Context()); got != 9 {
+			t.Errorf("tenant = %d, want 9", got)
+		}
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(WithTenant(r.Context(), 9))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+}
+
+// run пропускает запрос с заголовками через middleware и возвращает статус,
+// арендатора и пользователя, которых увидел обработчик.
+func run(mw func(http.Handler) http.Handler, headers map[string]string) (int, int, string) {
+	var tenant int
+	var user string
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, user = FromContext(r.Context()), UserFromContext(r.Context())
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, tenant, user
+}
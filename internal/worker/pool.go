@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrStopped = errors.New("worker pool is stopped")
+
+type task struct {
+	name     string
+	ctx      context.Context
+	fn       func(ctx context.Context) error
+	queuedAt time.Time
+}
+
+type Stats struct {
+	Workers   int    `json:"workers"`
+	QueueSize int    `json:"queue_size"`
+	Queued    int    `json:"queued"`
+	Submitted int    `json:"submitted"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Blocked   int    `json:"blocked"`
+	Rejected  int    `json:"rejected"`
+	MaxWait   string `json:"max_wait"`
+}
+
+// Pool выполняет побочные эффекты запросов (публикации, записи в кэш) на
+// фиксированном числе горутин. Очередь ограничена: при её заполнении Submit
+// ждёт свободного места, и такие ожидания учитываются в Stats.Blocked.
+type Pool struct {
+	tasks   chan task
+	timeout time.Duration
+	wg      sync.WaitGroup
+
+	// closing удерживается на чтение на время отправки в tasks, чтобы Stop
+	// не закрыл канал посреди Submit.
+	closing sync.RWMutex
+	stopped bool
+
+	mu      sync.Mutex
+	stats   Stats
+	maxWait time.Duration
+}
+
+// New создаёт пул из workers горутин с очередью на queueSize задач; каждая
+// задача выполняется не дольше timeout.
+func New(workers, queueSize int, timeout time.Duration) *Pool {
+	p := &Pool{
+		tasks:   make(chan task, queueSize),
+		timeout: timeout,
+		stats:   Stats{Workers: workers, QueueSize: queueSize},
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit ставит fn в очередь. Задача получает контекст ctx без отмены, чтобы
+// завершение запроса не прерывало уже принятый эффект; сам ctx ограничивает
+// только ожидание места в очереди.
+func (p *Pool) Submit(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	p.closing.RLock()
+	defer p.closing.RUnlock()
+	if p.stopped {
+		return ErrStopped
+	}
+
+	p.mu.Lock()
+	p.stats.Submitted++
+	p.mu.Unlock()
+
+	t := task{name: name, ctx: context.WithoutCancel(ctx), fn: fn, queuedAt: time.Now()}
+	select {
+	case p.tasks <- t:
+		return nil
+	default:
+	}
+
+	p.mu.Lock()
+	p.stats.Blocked++
+	p.mu.Unlock()
+
+	select {
+	case p.tasks <- t:
+		return nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Stop перестаёт принимать задачи и ждёт выполнения уже поставленных.
+func (p *Pool) Stop() {
+	p.closing.Lock()
+	if p.stopped {
+		p.closing.Unlock()
+		return
+	}
+	p.stopped = true
+	close(p.tasks)
+	p.closing.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Queued = len(p.tasks)
+	stats.MaxWait = p.maxWait.String()
+	return stats
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for t := range p.tasks {
+		wait := time.Since(t.queuedAt)
+
+		ctx, cancel := context.WithTimeout(t.ctx, p.timeout)
+		err := t.fn(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("worker: %s: %v", t.name, err)
+		}
+
+		p.mu.Lock()
+		p.stats.Completed++
+		if err != nil {
+			p.stats.Failed++
+		}
+		if wait > p.maxWait {
+			p.maxWait = wait
+		}
+		p.mu.Unlock()
+	}
+}
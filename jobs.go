@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/digest"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/metrics"
+	"hezzl-test/internal/objectstore"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/scheduler"
+	"hezzl-test/internal/search"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"log"
+	"net/http"
+	"time"
+)
+
+var errJobNotTriggered = errs.Conflict("errors.job.notTriggered")
+
+func registerJobs(s *scheduler.Scheduler, db, clickhouse *sql.DB, goods storage.GoodsRepository, redisClient deps.Cache, budgets *cachebudget.Budget, cacheTTL time.Duration, natsConn deps.Publisher, elastic *search.Elastic, s3 *objectstore.S3, smtp digest.SMTPConfig) {
+	s.SetLock(advisoryLock(db))
+
+	s.Register(scheduler.Job{
+		Name:     "cache_warmup",
+		Schedule: scheduler.Every(cacheWarmupInterval),
+		Enabled:  cacheWarmupEnabled,
+		Run: func(ctx context.Context) error {
+			return warmGoodsCache(ctx, db, goods, redisClient, cacheTTL)
+		},
+	})
+	s.Register(scheduler.Job{
+		Name:     "priority_compaction",
+		Schedule: scheduler.DailyAt{Hour: priorityCompactionHour},
+		Enabled:  priorityCompactionEnabled,
+		Run: func(ctx context.Context) error {
+			return compactPriorities(ctx, db, redisClient, natsConn)
+		},
+	})
+	s.Register(scheduler.Job{
+		Name:     "retention_purge",
+		Schedule: scheduler.Every(retentionPurgeInterval),
+		Enabled:  retentionPurgeEnabled,
+		Run: func(ctx context.Context) error {
+			return purgeRemovedGoods(ctx, db, redisClient, s3, natsConn)
+		},
+	})
+
+	s.Register(scheduler.Job{
+		Name:     "related_goods",
+		Schedule: scheduler.Every(relatedGoodsInterval),
+		Enabled:  relatedGoodsEnabled,
+		Run: func(ctx context.Context) error {
+			return computeGoodRelations(ctx, db, clickhouse)
+		},
+	})
+
+	s.Register(scheduler.Job{
+		Name:     "views_flush",
+		Schedule: scheduler.Every(viewsFlushInterval),
+		Enabled:  viewsFlushEnabled,
+		Run: func(ctx context.Context) error {
+			return flushViews(ctx, db, redisClient)
+		},
+	})
+
+	s.Register(scheduler.Job{
+		Name:     "reencrypt",
+		Schedule: scheduler.DailyAt{Hour: reencryptHour},
+		Enabled:  reencryptEnabled,
+		Run: func(ctx context.Context) error {
+			return reencryptDescriptions(ctx, db)
+		},
+	})
+
+	s.Register(scheduler.Job{
+		Name:     "scheduled_updates",
+		Schedule: scheduler.Every(scheduledUpdatesInterval),
+		Enabled:  scheduledUpdatesEnabled,
+		Run: func(ctx context.Context) error {
+			return applyScheduledUpdates(ctx, db, budgets, cacheTTL, natsConn)
+		},
+	})
+
+	registerRebuildJobs(s, db, clickhouse, goods, redisClient, cacheTTL, elastic)
+
+	d := digest.New(db, clickhouse, smtp)
+	s.Register(scheduler.Job{
+		Name:     "digest",
+		Schedule: scheduler.DailyAt{Hour: digestHour},
+		Enabled:  digestEnabled,
+		Run: func(ctx context.Context) error {
+			return d.Run(ctx, clk.Now().Add(-24*time.Hour))
+		},
+	})
+}
+
+// advisoryLock — блокировка запусков задач в Postgres: запуск держит
+// pg_try_advisory_xact_lock(hashtext(name)) в отдельной транзакции, пока
+// выполняется задача, поэтому при нескольких экземплярах сервиса задачу
+// выполняет только один из них.
+func advisoryLock(db *sql.DB) scheduler.Lock {
+	return func(ctx context.Context, name string, run func(ctx context.Context) error) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var locked bool
+		if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock(hashtext($1))", name).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			log.Printf("%s: running on another instance, skipped", name)
+			return scheduler.ErrLocked
+		}
+		return run(ctx)
+	}
+}
+
+func warmGoodsCache(ctx context.Context, db *sql.DB, goods storage.GoodsRepository, redisClient deps.Cache, cacheTTL time.Duration) error {
+	tenants, err := tenantIDs(db)
+	if err != nil {
+		return err
+	}
+
+	for _, tenantID := range tenants {
+		if err := warmTenantGoods(ctx, goods, redisClient, cacheTTL, tenantID, "cache_warmup"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warmTenantGoods кэширует первую страницу списка товаров арендатора;
+// trigger попадает в метрику пересборок кэша.
+func warmTenantGoods(ctx context.Context, goods storage.GoodsRepository, redisClient deps.Cache, cacheTTL time.Duration, tenantID int, trigger string) error {
+	query := storage.GoodsQuery{TenantID: tenantID, Limit: defaultLimit}
+	list, err := loadGoodsPage(ctx, goods, query)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	if err := redisClient.Set(ctx, goodsListCacheKey(query), data, cacheTTL).Err(); err != nil {
+		return err
+	}
+	metrics.CacheRebuilt("goods_list", trigger)
+	return nil
+}
+
+// compactPriorities перенумеровывает приоритеты каждого проекта подряд,
+// начиная с 1, сохраняя текущий порядок и убирая дыры после удалений, и
+// опускает счётчики проектов до нового последнего приоритета. Счётчики
+// блокируются до конца транзакции, чтобы новый товар не получил приоритет
+// по старому счётчику. Проекты со стратегией gap пропускаются: промежутки
+// у них нужны. Перенумерованные товары получают новую версию; после
+// фиксации по каждому проекту сбрасываются кэши и публикуется
+// goods_reordered, как после /goods/reprioritize.
+func compactPriorities(ctx context.Context, db *sql.DB, redisClient deps.Cache, natsConn deps.Publisher) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT project_id FROM project_priority_counters
+		WHERE project_id NOT IN (SELECT project_id FROM project_settings WHERE priority_strategy = 'gap')
+		ORDER BY project_id FOR UPDATE`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `UPDATE goods g SET priority = s.rn, version = g.version + 1
+		FROM (SELECT id, priority, ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY priority, id) AS rn FROM goods
+			WHERE project_id NOT IN (SELECT project_id FROM project_settings WHERE priority_strategy = 'gap')) s
+		WHERE g.id = s.id AND g.priority <> s.rn
+		RETURNING g.tenant_id, g.project_id, g.id, g.priority, s.priority`)
+	if err != nil {
+		return err
+	}
+	var projects []compactedProject
+	index := make(map[int]int)
+	n := 0
+	for rows.Next() {
+		var tenantID, projectID int
+		var change PriorityChange
+		if err := rows.Scan(&tenantID, &projectID, &change.ID, &change.Priority, &change.Previous); err != nil {
+			rows.Close()
+			return err
+		}
+		i, ok := index[projectID]
+		if !ok {
+			i = len(projects)
+			index[projectID] = i
+			projects = append(projects, compactedProject{tenantID: tenantID, GoodsReordered: GoodsReordered{ProjectID: projectID}})
+		}
+		projects[i].Priorities = append(projects[i].Priorities, change)
+		n++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE project_priority_counters c
+		SET last_priority = COALESCE((SELECT MAX(priority) FROM goods WHERE project_id = c.project_id), 0)
+		WHERE c.project_id NOT IN (SELECT project_id FROM project_settings WHERE priority_strategy = 'gap')`)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, p := range projects {
+		if err := p.invalidate(ctx, redisClient); err != nil {
+			log.Printf("priority_compaction: invalidate cache of project %d: %v", p.ProjectID, err)
+		}
+		data, err := json.Marshal(p.GoodsReordered)
+		if err != nil {
+			return err
+		}
+		if err := publish(tenant.WithTenant(ctx, p.tenantID), natsConn, "goods_reordered", data); err != nil {
+			log.Printf("priority_compaction: publish project %d: %v", p.ProjectID, err)
+		}
+	}
+
+	log.Printf("priority_compaction: %d goods renumbered in %d projects", n, len(projects))
+	return nil
+}
+
+// compactedProject — изменения приоритетов одного проекта при сжатии.
+type compactedProject struct {
+	tenantID int
+	GoodsReordered
+}
+
+// invalidate сбрасывает карточки перенумерованных товаров и списки
+// товаров арендатора.
+func (p compactedProject) invalidate(ctx context.Context, redisClient deps.Cache) error {
+	keys := make([]string, len(p.Priorities))
+	for i, change := range p.Priorities {
+		keys[i] = goodCacheKey(p.tenantID, change.ID)
+	}
+	if err := redisClient.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	return invalidateGoodsLists(ctx, redisClient, p.tenantID)
+}
+
+// purgeRemovedGoods окончательно удаляет товары, пролежавшие в корзине
+// дольше removedRetention, так же, как /goods/trash/purge: с аудитом,
+// событиями good_deleted, сбросом кэша и удалением объектов вложений.
+// Товары каждого арендатора удаляются своей транзакцией.
+func purgeRemovedGoods(ctx context.Context, db *sql.DB, redisClient deps.Cache, s3 *objectstore.S3, natsConn deps.Publisher) error {
+	rows, err := db.QueryContext(ctx, "SELECT tenant_id, id FROM goods WHERE removed AND removed_at < $1 ORDER BY tenant_id, id",
+		clk.Now().Add(-removedRetention))
+	if err != nil {
+		return err
+	}
+	expired := make(map[int][]int)
+	for rows.Next() {
+		var tenantID, id int
+		if err := rows.Scan(&tenantID, &id); err != nil {
+			rows.Close()
+			return err
+		}
+		expired[tenantID] = append(expired[tenantID], id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	n := 0
+	for tenantID, ids := range expired {
+		purged, err := purgeTenantGoods(tenant.WithTenant(ctx, tenantID), db, redisClient, s3, natsConn, tenantID, ids)
+		if err != nil {
+			return err
+		}
+		n += purged
+	}
+	log.Printf("retention_purge: %d goods purged", n)
+	return nil
+}
+
+func purgeTenantGoods(ctx context.Context, db *sql.DB, redisClient deps.Cache, s3 *objectstore.S3, natsConn deps.Publisher, tenantID int, ids []int) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	purged, objectKeys, err := purgeGoods(ctx, tx, tenantID, ids, false)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+
+	deleteAttachmentObjects(ctx, s3, objectKeys)
+	if err := dropPurgedGoods(ctx, redisClient, tenantID, purged); err != nil {
+		log.Printf("retention_purge: invalidate cache of tenant %d: %v", tenantID, err)
+	}
+	for _, good := range purged {
+		data, err := json.Marshal(map[string]int{"id": good.ID, "project_id": good.ProjectID})
+		if err != nil {
+			return 0, err
+		}
+		if err := publishGood(ctx, natsConn, "good_deleted", good.ID, good.Version, data); err != nil {
+			log.Printf("retention_purge: publish good %d: %v", good.ID, err)
+		}
+	}
+	return len(purged), nil
+}
+
+func listJobsHandler(s *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, http.StatusOK, s.Status())
+	}
+}
+
+func triggerJobHandler(s *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if err := s.Trigger(name); err != nil {
+			response.Fail(w, r, errJobNotTriggered)
+			return
+		}
+		response.JSON(w, r, http.StatusAccepted, map[string]string{"job": name})
+	}
+}
+
+func workerStatsHandler(effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, http.StatusOK, effects.Stats())
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/progress"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+var errJobNotFound = errs.NotFound("errors.job.notFound")
+
+// Стадии импорта в событиях progress: чтение строк файла и запись
+// прочитанного в базу.
+const (
+	importPhaseReading   = "reading"
+	importPhaseInserting = "inserting"
+)
+
+type importProgress struct {
+	Phase    string `json:"phase"`
+	Rows     int    `json:"rows"`
+	Rejected int    `json:"rejected"`
+}
+
+// importSummary — итог задания импорта: результат загрузки или ошибка,
+// из-за которой не загружено ни одной строки.
+type importSummary struct {
+	Result *bulk.Result `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// trackedSource сообщает заданию job ход чтения строк и отклонённые при
+// разборе строки.
+type trackedSource struct {
+	bulk.Source
+	job      *progress.Job
+	rows     int
+	rejected int
+}
+
+func (s *trackedSource) Next() (bulk.Row, error) {
+	row, err := s.Source.Next()
+	switch e := err.(type) {
+	case nil:
+		s.rows++
+	case *bulk.RowError:
+		s.rejected++
+		s.job.Warn(bulk.Rejection{Line: e.Line, Reason: e.Reason})
+	default:
+		if err == io.EOF {
+			s.job.Progress(importProgress{Phase: importPhaseInserting, Rows: s.rows, Rejected: s.rejected})
+		}
+		return row, err
+	}
+	if (s.rows+s.rejected)%importProgressRows == 0 {
+		s.job.Progress(importProgress{Phase: importPhaseReading, Rows: s.rows, Rejected: s.rejected})
+	}
+	return row, err
+}
+
+// jobEventsHandler отдаёт ход задания id (импорта по ссылке или выгрузки
+// проекта) потоком Server-Sent Events: сначала уже известные события,
+// затем новые по мере выполнения и последним — summary, после которого
+// поток закрывается.
+func jobEventsHandler(jobs *progress.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := jobs.Get(tenant.FromContext(r.Context()), r.URL.Query().Get("id"))
+		if !ok {
+			response.Fail(w, r, errJobNotFound)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			response.InternalError(w, r, fmt.Errorf("streaming is not supported"))
+			return
+		}
+
+		history, events, cancel := job.Subscribe()
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		for _, e := range history {
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(importEventsKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case e, ok := <-events:
+				if !ok {
+					if summary, done := job.Summary(); done {
+						writeEvent(w, summary)
+					}
+					flusher.Flush()
+					return
+				}
+				if err := writeEvent(w, e); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w io.Writer, e progress.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		log.Printf("jobs: encode %s event: %v", e.Type, err)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
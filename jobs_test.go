@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/mock/gomock"
+
+	"hezzl-test/internal/clock"
+	"hezzl-test/internal/deps/mocks"
+	"hezzl-test/internal/localcache"
+	"hezzl-test/internal/scheduler"
+	"hezzl-test/internal/tenant"
+)
+
+// publishedEvents собирает сообщения, опубликованные через mock-издателя.
+func publishedEvents(t *testing.T) (*mocks.MockPublisher, *[]*nats.Msg) {
+	ctrl := gomock.NewController(t)
+	publisher := mocks.NewMockPublisher(ctrl)
+	var msgs []*nats.Msg
+	publisher.EXPECT().PublishMsg(gomock.Any()).DoAndReturn(func(msg *nats.Msg) error {
+		msgs = append(msgs, msg)
+		return nil
+	}).AnyTimes()
+	return publisher, &msgs
+}
+
+// occurredAt возвращает время события из тела сообщения.
+func occurredAt(t *testing.T, msg *nats.Msg) time.Time {
+	t.Helper()
+	var body struct {
+		OccurredAt time.Time `json:"occurred_at"`
+	}
+	if err := json.Unmarshal(msg.Data, &body); err != nil {
+		t.Fatalf("decode %s: %v", msg.Data, err)
+	}
+	return body.OccurredAt
+}
+
+func TestPurgeRemovedGoods(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	useClock(t, clock.NewManual(now))
+
+	db, fake := newFakeDB(t,
+		fakeQuery{
+			match:   "SELECT tenant_id, id FROM goods WHERE removed AND removed_at < $1",
+			args:    argEquals(0, now.Add(-removedRetention)),
+			columns: []string{"tenant_id", "id"},
+			rows:    [][]driver.Value{{int64(testTenantID), int64(11)}},
+		},
+		fakeQuery{match: "SELECT a.object_key", columns: []string{"object_key"}},
+		fakeQuery{
+			match:   "DELETE FROM goods",
+			columns: []string{"id", "project_id", "version"},
+			rows:    [][]driver.Value{{int64(11), int64(3), int64(5)}},
+		},
+		fakeQuery{match: "INSERT INTO audit_log", args: argEquals(1, "purge")},
+	)
+
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	ctx := context.Background()
+	cache.Set(ctx, goodCacheKey(testTenantID, 11), "{}", 0)
+	cache.Set(ctx, "goods:list:7:page", "[]", 0)
+
+	publisher, msgs := publishedEvents(t)
+	if err := purgeRemovedGoods(ctx, db, cache, nil, publisher); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fake.txLog(); !reflect.DeepEqual(got, []string{"BEGIN", "COMMIT"}) {
+		t.Errorf("transactions = %v", got)
+	}
+	for _, key := range []string{goodCacheKey(testTenantID, 11), "goods:list:7:page"} {
+		if _, err := cache.Get(ctx, key).Result(); err == nil {
+			t.Errorf("%s is still cached", key)
+		}
+	}
+	if len(*msgs) != 1 {
+		t.Fatalf("published %d events, want 1", len(*msgs))
+	}
+	msg := (*msgs)[0]
+	if msg.Subject != "good_deleted" || msg.Header.Get(sequenceHeader) != "5" {
+		t.Errorf("event %s, sequence %s", msg.Subject, msg.Header.Get(sequenceHeader))
+	}
+	if got := occurredAt(t, msg); !got.Equal(now) {
+		t.Errorf("occurred_at = %v, want %v", got, now)
+	}
+}
+
+func TestRetentionDaysLeft(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	useClock(t, clock.NewManual(now))
+
+	tests := []struct {
+		removedAt time.Time
+		want      int
+	}{
+		{now, 30},
+		{now.Add(-removedRetention + 36*time.Hour), 2},
+		{now.Add(-removedRetention), 0},
+		{now.Add(-removedRetention - time.Hour), 0},
+	}
+	for _, tt := range tests {
+		if got := retentionDaysLeft(tt.removedAt); got != tt.want {
+			t.Errorf("retentionDaysLeft(%v) = %d, want %d", tt.removedAt, got, tt.want)
+		}
+	}
+}
+
+func TestCompactPriorities(t *testing.T) {
+	db, fake := newFakeDB(t,
+		fakeQuery{match: "FROM project_priority_counters"},
+		fakeQuery{
+			match:   "version = g.version + 1",
+			columns: []string{"tenant_id", "project_id", "id", "priority", "priority"},
+			rows: [][]driver.Value{
+				{int64(testTenantID), int64(3), int64(11), int64(1), int64(2)},
+				{int64(testTenantID), int64(3), int64(12), int64(2), int64(5)},
+				{int64(8), int64(4), int64(21), int64(1), int64(3)},
+			},
+		},
+		fakeQuery{match: "UPDATE project_priority_counters c"},
+	)
+
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	ctx := context.Background()
+	for _, key := range []string{goodCacheKey(testTenantID, 11), goodCacheKey(8, 21), "goods:list:7:page", "goods:list:8:page"} {
+		cache.Set(ctx, key, "{}", 0)
+	}
+	cache.Set(ctx, goodCacheKey(testTenantID, 13), "{}", 0)
+
+	publisher, msgs := publishedEvents(t)
+	if err := compactPriorities(ctx, db, cache, publisher); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fake.txLog(); !reflect.DeepEqual(got, []string{"BEGIN", "COMMIT"}) {
+		t.Errorf("transactions = %v", got)
+	}
+	for _, key := range []string{goodCacheKey(testTenantID, 11), goodCacheKey(8, 21), "goods:list:7:page", "goods:list:8:page"} {
+		if _, err := cache.Get(ctx, key).Result(); err == nil {
+			t.Errorf("%s is still cached", key)
+		}
+	}
+	if _, err := cache.Get(ctx, goodCacheKey(testTenantID, 13)).Result(); err != nil {
+		t.Errorf("untouched good is dropped from cache: %v", err)
+	}
+
+	want := []GoodsReordered{
+		{ProjectID: 3, Priorities: []PriorityChange{{ID: 11, Priority: 1, Previous: 2}, {ID: 12, Priority: 2, Previous: 5}}},
+		{ProjectID: 4, Priorities: []PriorityChange{{ID: 21, Priority: 1, Previous: 3}}},
+	}
+	if len(*msgs) != len(want) {
+		t.Fatalf("published %d events, want %d", len(*msgs), len(want))
+	}
+	for i, msg := range *msgs {
+		var event GoodsReordered
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Subject != "goods_reordered" || !reflect.DeepEqual(event, want[i]) {
+			t.Errorf("event %d: %s %+v, want %+v", i, msg.Subject, event, want[i])
+		}
+	}
+	if got := (*msgs)[1].Header.Get(tenant.NATSHeader); got != "8" {
+		t.Errorf("tenant header = %s, want 8", got)
+	}
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	for _, locked := range []bool{true, false} {
+		db, fake := newFakeDB(t, fakeQuery{
+			match:   "pg_try_advisory_xact_lock(hashtext($1))",
+			args:    argEquals(0, "priority_compaction"),
+			columns: []string{"locked"},
+			rows:    [][]driver.Value{{locked}},
+		})
+
+		ran := false
+		err := advisoryLock(db)(context.Background(), "priority_compaction", func(ctx context.Context) error {
+			ran = true
+			return nil
+		})
+		if ran != locked {
+			t.Errorf("lock acquired %v: job ran %v", locked, ran)
+		}
+		if !locked && !errors.Is(err, scheduler.ErrLocked) {
+			t.Errorf("lock held elsewhere: err = %v, want ErrLocked", err)
+		}
+		if got := fake.txLog(); !reflect.DeepEqual(got, []string{"BEGIN", "ROLLBACK"}) {
+			t.Errorf("transactions = %v", got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"hezzl-test/internal/response"
+	"net/http"
+)
+
+// GoodLinks — ссылки товара на связанные ресурсы API.
+type GoodLinks struct {
+	Self            string `json:"self"`
+	Project         string `json:"project"`
+	History         string `json:"history"`
+	Attachments     string `json:"attachments"`
+	PriorityHistory string `json:"priority_history"`
+}
+
+// ProjectLinks — ссылки проекта на связанные ресурсы API.
+type ProjectLinks struct {
+	Self  string `json:"self"`
+	Goods string `json:"goods"`
+}
+
+// link заполняет ссылки товара, если они включены hypermediaLinks.
+func (g *Goods) link() {
+	if !hypermediaLinks {
+		return
+	}
+	g.Links = &GoodLinks{
+		Self:            fmt.Sprintf("/good?id=%d", g.ID),
+		Project:         fmt.Sprintf("/project/settings?id=%d", g.ProjectID),
+		History:         fmt.Sprintf("/admin/audit?entity=good&entityId=%d", g.ID),
+		Attachments:     fmt.Sprintf("/good/attachments?id=%d", g.ID),
+		PriorityHistory: fmt.Sprintf("/analytics/good/priority-history?id=%d", g.ID),
+	}
+}
+
+func linkGoods(goods []Goods) {
+	for i := range goods {
+		goods[i].link()
+	}
+}
+
+func (p *Projects) link() {
+	if !hypermediaLinks {
+		return
+	}
+	p.Links = &ProjectLinks{
+		Self:  fmt.Sprintf("/project/settings?id=%d", p.ID),
+		Goods: fmt.Sprintf("/goods/list?projectId=%d", p.ID),
+	}
+}
+
+// pageLinks — ссылки на соседние страницы списка или nil, если ссылки
+// выключены.
+func pageLinks(r *http.Request, meta response.Meta) *response.PageLinks {
+	if !hypermediaLinks {
+		return nil
+	}
+	return response.PageLinksOf(r, meta)
+}
+
+// link заполняет ссылки страницы и её товаров.
+func (l *GoodsList) link(r *http.Request) {
+	l.Links = pageLinks(r, l.Meta)
+	linkGoods(l.Goods)
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/mock/gomock"
+
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/clock"
+	"hezzl-test/internal/deps"
+	depsmocks "hezzl-test/internal/deps/mocks"
+	"hezzl-test/internal/localcache"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/storage/mocks"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+)
+
+const testTenantID = 7
+
+// serve выполняет запрос арендатора testTenantID и возвращает ответ.
+func serve(t testing.TB, h http.Handler, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serveBody(t, h, method, target, "")
+}
+
+// serveBody — serve с телом запроса.
+func serveBody(t testing.TB, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(tenant.WithTenant(r.Context(), testTenantID))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// useClock подменяет clk на время теста.
+func useClock(t *testing.T, c clock.Clock) {
+	old := clk
+	clk = c
+	t.Cleanup(func() { clk = old })
+}
+
+// useIDs подменяет ids на время теста.
+func useIDs(t *testing.T, g clock.IDGenerator) {
+	old := ids
+	ids = g
+	t.Cleanup(func() { ids = old })
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+}
+
+func TestListProjectsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	projects := mocks.NewMockProjectsRepository(ctrl)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	projects.EXPECT().List(gomock.Any(), testTenantID).Return([]storage.Project{
+		{ID: 1, Name: "First", CreatedAt: created},
+		{ID: 2, Name: "Second", CreatedAt: created},
+	}, nil)
+
+	w := serve(t, listProjectsHandler(projects), "GET", "/projects")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var list []Projects
+	decodeBody(t, w, &list)
+	if len(list) != 2 || list[0].ID != 1 || list[1].Name != "Second" || !list[0].CreatedAt.Equal(created) {
+		t.Fatalf("projects = %+v", list)
+	}
+}
+
+func TestListProjectsHandlerEmpty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	projects := mocks.NewMockProjectsRepository(ctrl)
+	projects.EXPECT().List(gomock.Any(), testTenantID).Return(nil, nil)
+
+	w := serve(t, listProjectsHandler(projects), "GET", "/projects")
+	if w.Code != http.StatusOK || w.Body.String() != "[]\n" {
+		t.Fatalf("status = %d, body %q", w.Code, w.Body)
+	}
+}
+
+func TestRemoveGoodHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		deleted storage.Deleted
+		err     error
+		status  int
+		key     string
+	}{
+		{name: "no project", target: "/good/delete?id=5", status: http.StatusBadRequest},
+		{name: "not found", target: "/good/delete?id=5&projectId=3", err: storage.ErrGoodNotFound, status: http.StatusNotFound, key: "errors.good.notFound"},
+		{name: "archived", target: "/good/delete?id=5&projectId=3", err: storage.ErrProjectArchived, status: http.StatusConflict, key: "errors.project.archived"},
+		{name: "database down", target: "/good/delete?id=5&projectId=3", err: errors.New("connection refused"), status: http.StatusInternalServerError, key: "errors.internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			goods := mocks.NewMockGoodsRepository(ctrl)
+			if tt.status != http.StatusBadRequest {
+				goods.EXPECT().Delete(gomock.Any(), testTenantID, 3, 5, false).Return(tt.deleted, tt.err)
+			}
+
+			w := serve(t, removeGoodHandler(goods, nil, nil, nil), "DELETE", tt.target)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.key != "" {
+				var body response.ErrorBody
+				decodeBody(t, w, &body)
+				if body.Key != tt.key {
+					t.Fatalf("key = %q, want %q", body.Key, tt.key)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveGoodHandlerDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	goods := mocks.NewMockGoodsRepository(ctrl)
+	goods.EXPECT().Delete(gomock.Any(), testTenantID, 3, 5, true).Return(storage.Deleted{Version: 2}, nil)
+
+	w := serve(t, removeGoodHandler(goods, nil, nil, nil), "DELETE", "/good/delete?id=5&projectId=3&dryRun=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var dry DryRun
+	decodeBody(t, w, &dry)
+	if !dry.DryRun || dry.Count != 1 || dry.IDs[0] != 5 {
+		t.Fatalf("dry run = %+v", dry)
+	}
+}
+
+// goodsPage — storage.GoodsRows по готовому срезу товаров.
+type goodsPage struct {
+	goods  []storage.Good
+	next   int
+	closed bool
+}
+
+func (p *goodsPage) Next() bool {
+	p.next++
+	return p.next <= len(p.goods)
+}
+
+func (p *goodsPage) Good() (storage.Good, error) { return p.goods[p.next-1], nil }
+func (p *goodsPage) Err() error                  { return nil }
+func (p *goodsPage) Close() error                { p.closed = true; return nil }
+
+// recordEvents подставляет публикатор, который запоминает события по
+// темам, и пул побочных эффектов; события видны после остановки пула.
+func recordEvents(t *testing.T) (*depsmocks.MockPublisher, *worker.Pool, func() map[string][]*nats.Msg) {
+	freshBreaker(t)
+	publisher := depsmocks.NewMockPublisher(gomock.NewController(t))
+	var mu sync.Mutex
+	events := make(map[string][]*nats.Msg)
+	publisher.EXPECT().PublishMsg(gomock.Any()).DoAndReturn(func(msg *nats.Msg) error {
+		mu.Lock()
+		defer mu.Unlock()
+		events[msg.Subject] = append(events[msg.Subject], msg)
+		return nil
+	}).AnyTimes()
+	effects := worker.New(1, 10, time.Second)
+	t.Cleanup(effects.Stop)
+	return publisher, effects, func() map[string][]*nats.Msg {
+		effects.Stop()
+		mu.Lock()
+		defer mu.Unlock()
+		return events
+	}
+}
+
+func errorKey(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body response.ErrorBody
+	decodeBody(t, w, &body)
+	return body.Key
+}
+
+func TestCreateGoodHandler(t *testing.T) {
+	created := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	good := storage.Good{ID: 11, ProjectID: 3, Name: "Tea", Description: "secret", Priority: 4, CreatedAt: created}
+	goods := mocks.NewMockGoodsRepository(gomock.NewController(t))
+	goods.EXPECT().Create(gomock.Any(), testTenantID, storage.NewGood{ProjectID: 3, Name: "Tea", Description: "secret", AllowDuplicate: true}).
+		Return(storage.Written{Good: good, Version: 1, Encrypted: true}, nil)
+	publisher, effects, events := recordEvents(t)
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+
+	h := createGoodHandler(goods, cachebudget.New(cache, 1<<20), time.Minute, publisher, effects)
+	w := serveBody(t, h, "POST", "/good/create?allowDuplicate=true", `{"project_id":3,"name":"Tea","description":"secret"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var got Goods
+	decodeBody(t, w, &got)
+	if got.ID != 11 || got.Priority != 4 || got.Description != "secret" {
+		t.Fatalf("good = %+v", got)
+	}
+
+	msgs := events()["new_good_created"]
+	if len(msgs) != 1 {
+		t.Fatalf("new_good_created published %d times", len(msgs))
+	}
+	msg := msgs[0]
+	var event Goods
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != 11 || event.Description != "" {
+		t.Errorf("event = %+v, want no description of an encrypted project", event)
+	}
+	if _, err := cache.Get(context.Background(), goodCacheKey(testTenantID, 11)).Result(); err != nil {
+		t.Errorf("good is not cached: %v", err)
+	}
+}
+
+func TestCreateGoodHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+		key    string
+	}{
+		{name: "negative priority", body: `{"project_id":3,"priority":-1}`, status: http.StatusBadRequest},
+		{name: "duplicate", body: `{"project_id":3,"name":"Tea"}`, err: &duplicatesError{Candidates: []DuplicateCandidate{{ID: 2, Name: "Tea", Similarity: 1}}}, status: http.StatusConflict, key: "errors.good.duplicate"},
+		{name: "no project", body: `{"project_id":3}`, err: errProjectNotFound, status: http.StatusNotFound, key: "errors.project.notFound"},
+		{name: "priority taken", body: `{"project_id":3,"priority":2}`, err: errPriorityTaken, status: http.StatusConflict, key: "errors.good.priorityTaken"},
+		{name: "archived", body: `{"project_id":3}`, err: storage.ErrProjectArchived, status: http.StatusConflict, key: "errors.project.archived"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goods := mocks.NewMockGoodsRepository(gomock.NewController(t))
+			if tt.err != nil {
+				goods.EXPECT().Create(gomock.Any(), testTenantID, gomock.Any()).Return(storage.Written{}, tt.err)
+			}
+
+			w := serveBody(t, createGoodHandler(goods, nil, time.Minute, nil, nil), "POST", "/good/create", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.key != "" {
+				var body response.ErrorBody
+				decodeBody(t, w, &body)
+				if body.Key != tt.key {
+					t.Fatalf("key = %q, want %q", body.Key, tt.key)
+				}
+				if details, _ := body.Details.(map[string]interface{}); tt.name == "duplicate" && details["candidates"] == nil {
+					t.Errorf("details = %v, want candidates", body.Details)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateGoodHandler(t *testing.T) {
+	old := storage.Good{ID: 5, ProjectID: 3, Name: "Tea", Priority: 2}
+	updated := storage.Good{ID: 5, ProjectID: 3, Name: "Green tea", Priority: 1, Removed: true}
+	goods := mocks.NewMockGoodsRepository(gomock.NewController(t))
+	goods.EXPECT().Update(gomock.Any(), testTenantID, 3, 5, storage.GoodUpdate{Name: "Green tea", Priority: 1, Removed: true}).
+		Return(storage.Written{Good: updated, Old: old, Version: 4}, nil)
+	publisher, effects, events := recordEvents(t)
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+
+	// id и project_id тела не заменяют параметры запроса.
+	h := updateGoodHandler(goods, cachebudget.New(cache, 1<<20), time.Minute, publisher, effects)
+	w := serveBody(t, h, "PATCH", "/good/update?id=5&projectId=3", `{"id":9,"project_id":9,"name":"Green tea","priority":1,"removed":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	msgs := events()["good_updated"]
+	if len(msgs) != 1 {
+		t.Fatalf("good_updated published %d times", len(msgs))
+	}
+	msg := msgs[0]
+	if got := msg.Header.Get(sequenceHeader); got != "4" {
+		t.Errorf("sequence = %s, want 4", got)
+	}
+	var event GoodUpdated
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"name", "priority", "removed"} {
+		if _, ok := event.Diff[field]; !ok {
+			t.Errorf("diff %v has no %s", event.Diff, field)
+		}
+	}
+}
+
+func TestUpdateGoodHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		err    error
+		status int
+		key    string
+	}{
+		{name: "no project", target: "/good/update?id=5", status: http.StatusBadRequest},
+		{name: "not found", target: "/good/update?id=5&projectId=3", err: storage.ErrGoodNotFound, status: http.StatusNotFound, key: "errors.good.notFound"},
+		{name: "removed project", target: "/good/update?id=5&projectId=3", err: storage.ErrProjectRemoved, status: http.StatusConflict, key: "errors.project.removed"},
+		{name: "database down", target: "/good/update?id=5&projectId=3", err: errors.New("connection refused"), status: http.StatusInternalServerError, key: "errors.internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goods := mocks.NewMockGoodsRepository(gomock.NewController(t))
+			if tt.err != nil {
+				goods.EXPECT().Update(gomock.Any(), testTenantID, 3, 5, gomock.Any()).Return(storage.Written{}, tt.err)
+			}
+
+			w := serveBody(t, updateGoodHandler(goods, nil, time.Minute, nil, nil), "PATCH", tt.target, `{"name":"Tea"}`)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.key != "" && errorKey(t, w) != tt.key {
+				t.Fatalf("key = %q, want %q", errorKey(t, w), tt.key)
+			}
+		})
+	}
+}
+
+func TestReprioritizeGoodHandler(t *testing.T) {
+	changes := []PriorityChange{{ID: 5, Priority: 1, Previous: 3}, {ID: 6, Priority: 2, Previous: 1}}
+	tests := []struct {
+		name      string
+		target    string
+		body      string
+		dryRun    bool
+		err       error
+		status    int
+		published bool
+	}{
+		{name: "no id", target: "/goods/reprioritize?projectId=3", body: `{"newPriority":1}`, status: http.StatusBadRequest},
+		{name: "bad priority", target: "/goods/reprioritize?id=5&projectId=3", body: `{"newPriority":0}`, status: http.StatusBadRequest},
+		{name: "moved", target: "/goods/reprioritize?id=5&projectId=3", body: `{"newPriority":1}`, status: http.StatusOK, published: true},
+		{name: "dry run", target: "/goods/reprioritize?id=5&projectId=3&dryRun=true", body: `{"newPriority":1}`, dryRun: true, status: http.StatusOK},
+		{name: "taken", target: "/goods/reprioritize?id=5&projectId=3", body: `{"newPriority":1}`, err: errPriorityTaken, status: http.StatusConflict},
+		{name: "not found", target: "/goods/reprioritize?id=5&projectId=3", body: `{"newPriority":1}`, err: storage.ErrGoodNotFound, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goods := mocks.NewMockGoodsRepository(gomock.NewController(t))
+			if tt.status != http.StatusBadRequest {
+				goods.EXPECT().Reorder(gomock.Any(), testTenantID, 3, 5, 1, tt.dryRun).Return(changes, tt.err)
+			}
+			publisher, effects, events := recordEvents(t)
+
+			w := serveBody(t, reprioritizeGoodHandler(goods, publisher, effects), "PATCH", tt.target, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			msgs := events()["goods_reordered"]
+			if (len(msgs) == 1) != tt.published {
+				t.Fatalf("goods_reordered published %d times, want published %v", len(msgs), tt.published)
+			}
+			if tt.published {
+				var event GoodsReordered
+				if err := json.Unmarshal(msgs[0].Data, &event); err != nil {
+					t.Fatal(err)
+				}
+				if event.ProjectID != 3 || !reflect.DeepEqual(event.Priorities, changes) {
+					t.Errorf("event = %+v", event)
+				}
+			}
+		})
+	}
+}
+
+func TestListGoodsHandler(t *testing.T) {
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	publisher, _, _ := recordEvents(t)
+
+	page := &goodsPage{goods: []storage.Good{{ID: 1, ProjectID: 3, Name: "Tea", Priority: 1}, {ID: 2, ProjectID: 3, Name: "Coffee", Priority: 2}}}
+	goods := mocks.NewMockGoodsRepository(gomock.NewController(t))
+	// Второй запрос той же страницы отдаётся из кэша.
+	goods.EXPECT().List(gomock.Any(), storage.GoodsQuery{TenantID: testTenantID, Limit: 2}).
+		Return(storage.Page{Total: 5, Removed: 1}, page, nil)
+
+	h := listGoodsHandler(goods, nil, cache, time.Minute, publisher)
+	for i := 0; i < 2; i++ {
+		w := serve(t, h, "GET", "/goods/list?limit=2")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body)
+		}
+		var list GoodsList
+		decodeBody(t, w, &list)
+		if list.Meta != (response.Meta{Total: 5, Removed: 1, Limit: 2}) || len(list.Goods) != 2 || list.Goods[1].Name != "Coffee" {
+			t.Fatalf("request %d: list = %+v", i, list)
+		}
+	}
+	if !page.closed {
+		t.Error("rows are not closed")
+	}
+}
+
+func TestListGoodsHandlerExpands(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	goods := mocks.NewMockGoodsRepository(ctrl)
+	projects := mocks.NewMockProjectsRepository(ctrl)
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// asOf читает снимок из журнала; проекты и избранное дописываются к нему.
+	goods.EXPECT().ListAsOf(gomock.Any(), testTenantID, at, 10, 0).
+		Return(storage.Page{Total: 1}, []storage.Good{{ID: 1, ProjectID: 3, Name: "Tea"}}, nil)
+	projects.EXPECT().GetMany(gomock.Any(), testTenantID, []int{3}).Return([]storage.Project{{ID: 3, Name: "Drinks"}}, nil)
+
+	w := serve(t, listGoodsHandler(goods, projects, nil, time.Minute, nil), "GET", "/goods/list?asOf=2026-03-01T00:00:00Z&include=project")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var list GoodsList
+	decodeBody(t, w, &list)
+	if len(list.Goods) != 1 || list.Goods[0].Project == nil || list.Goods[0].Project.Name != "Drinks" {
+		t.Fatalf("list = %+v", list)
+	}
+
+	w = serve(t, listGoodsHandler(goods, projects, nil, time.Minute, nil), "GET", "/goods/list?asOf=2026-03-01T00:00:00Z&sort=popularity")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("asOf with sort: status = %d", w.Code)
+	}
+}
+
+// benchmarkHandler выполняет запросы к h параллельно, по GOMAXPROCS горутин
+// (-cpu задаёт их число), и проверяет статус каждого ответа.
+func benchmarkHandler(b *testing.B, h http.Handler, method, target, body string, status int) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if w := serveBody(b, h, method, target, body); w.Code != status {
+				b.Errorf("status = %d, want %d, body %s", w.Code, status, w.Body)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkGoodsHandlers измеряет list, create и reprioritize без стенда:
+// репозитории отвечают сразу, так что видна цена самих обработчиков —
+// разбора запроса, кэша, кодирования ответа и публикации событий.
+//
+//	go test -run - -bench GoodsHandlers -cpu 1,8,32
+func BenchmarkGoodsHandlers(b *testing.B) {
+	stored := storedGoods(10)
+	goods := mocks.NewMockGoodsRepository(gomock.NewController(b))
+	goods.EXPECT().List(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, storage.GoodsQuery) (storage.Page, storage.GoodsRows, error) {
+			return storage.Page{Total: 100}, &goodsPage{goods: stored}, nil
+		}).AnyTimes()
+	goods.EXPECT().Create(gomock.Any(), testTenantID, gomock.Any()).
+		Return(storage.Written{Good: stored[0], Version: 1}, nil).AnyTimes()
+	goods.EXPECT().Reorder(gomock.Any(), testTenantID, 3, 1, 5, false).
+		Return([]PriorityChange{{ID: 1, Priority: 5, Previous: 1}}, nil).AnyTimes()
+
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	budgets := cachebudget.New(cache, 1<<20)
+	effects := worker.New(4, 1024, time.Second)
+	defer effects.Stop()
+	var publisher deps.Publisher = deps.NopPublisher{}
+
+	b.Run("list/cached", func(b *testing.B) {
+		benchmarkHandler(b, listGoodsHandler(goods, nil, cache, time.Minute, publisher), "GET", "/goods/list?limit=10", "", http.StatusOK)
+	})
+	// Страница живёт в кэше наносекунду, так что каждый запрос читает
+	// репозиторий и пишет ответ построчно.
+	b.Run("list/uncached", func(b *testing.B) {
+		benchmarkHandler(b, listGoodsHandler(goods, nil, cache, time.Nanosecond, publisher), "GET", "/goods/list?limit=10&offset=10", "", http.StatusOK)
+	})
+	b.Run("create", func(b *testing.B) {
+		h := createGoodHandler(goods, budgets, time.Minute, publisher, effects)
+		benchmarkHandler(b, h, "POST", "/good/create?allowDuplicate=true", `{"project_id":3,"name":"Tea","description":"green"}`, http.StatusCreated)
+	})
+	b.Run("reprioritize", func(b *testing.B) {
+		h := reprioritizeGoodHandler(goods, publisher, effects)
+		benchmarkHandler(b, h, "PATCH", "/goods/reprioritize?id=1&projectId=3", `{"newPriority":5}`, http.StatusOK)
+	})
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS goods_log
+(
+    Id          Int32,
+    ProjectId   Int32,
+    Name        String,
+    Description String,
+    Priority    Int32,
+    Removed     UInt8,
+    EventType   LowCardinality(String),
+    EventTime   DateTime
+)
+ENGINE = MergeTree
+PARTITION BY toYYYYMM(EventTime)
+ORDER BY (ProjectId, EventTime, Id);
@@ -0,0 +1,3 @@
+-- JSON вида {"field": {"old": ..., "new": ...}} из события good_updated;
+-- пустая строка для остальных событий.
+ALTER TABLE goods_log ADD COLUMN IF NOT EXISTS Diff String DEFAULT '' AFTER Removed;
@@ -0,0 +1,32 @@
+-- Автор изменения из заголовка User-Id события; пусто, если неизвестен.
+ALTER TABLE goods_log ADD COLUMN IF NOT EXISTS Editor String DEFAULT '' AFTER Diff;
+
+-- Почасовые агрегаты журнала по проектам: число событий каждого типа и
+-- состояние uniq по авторам. Их заполняет материализованное представление
+-- при каждой вставке в goods_log, и аналитика не сканирует сырые события.
+CREATE TABLE IF NOT EXISTS goods_log_hourly
+(
+    ProjectId Int32,
+    Hour      DateTime,
+    EventType LowCardinality(String),
+    Events    SimpleAggregateFunction(sum, UInt64),
+    Editors   AggregateFunction(uniq, Nullable(String))
+)
+ENGINE = AggregatingMergeTree
+PARTITION BY toYYYYMM(Hour)
+ORDER BY (ProjectId, Hour, EventType);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS goods_log_hourly_mv TO goods_log_hourly AS
+SELECT ProjectId,
+       toStartOfHour(EventTime)      AS Hour,
+       EventType,
+       count()                       AS Events,
+       uniqState(nullIf(Editor, '')) AS Editors
+FROM goods_log
+GROUP BY ProjectId, Hour, EventType;
+
+-- Перенос событий, записанных до появления представления.
+INSERT INTO goods_log_hourly
+SELECT ProjectId, toStartOfHour(EventTime) AS Hour, EventType, count(), uniqState(nullIf(Editor, ''))
+FROM goods_log
+GROUP BY ProjectId, Hour, EventType;
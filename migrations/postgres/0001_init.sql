@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS projects
+(
+    id         SERIAL PRIMARY KEY,
+    name       TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS goods
+(
+    id          SERIAL PRIMARY KEY,
+    project_id  INT       NOT NULL REFERENCES projects (id),
+    name        TEXT      NOT NULL,
+    description TEXT      NOT NULL DEFAULT '',
+    priority    INT       NOT NULL DEFAULT 0,
+    removed     BOOLEAN   NOT NULL DEFAULT false,
+    created_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS goods_project_id_idx ON goods (project_id);
+CREATE INDEX IF NOT EXISTS goods_name_idx ON goods (name);
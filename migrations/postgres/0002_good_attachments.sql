@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS good_attachments
+(
+    id           SERIAL PRIMARY KEY,
+    good_id      INT       NOT NULL REFERENCES goods (id) ON DELETE CASCADE,
+    object_key   TEXT      NOT NULL UNIQUE,
+    file_name    TEXT      NOT NULL,
+    content_type TEXT      NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS good_attachments_good_id_idx ON good_attachments (good_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS notification_rules
+(
+    id         SERIAL PRIMARY KEY,
+    project_id INT       NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    channel    TEXT      NOT NULL CHECK (channel IN ('slack', 'telegram')),
+    target     TEXT      NOT NULL,
+    events     TEXT[]    NOT NULL DEFAULT '{}',
+    enabled    BOOLEAN   NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notification_rules_project_id_idx ON notification_rules (project_id) WHERE enabled;
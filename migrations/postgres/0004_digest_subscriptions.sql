@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS digest_subscriptions
+(
+    id         SERIAL PRIMARY KEY,
+    project_id INT       NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    email      TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (project_id, email)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS removed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS goods_removed_at_idx ON goods (removed_at) WHERE removed;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tenants
+(
+    id         SERIAL PRIMARY KEY,
+    name       TEXT      NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO tenants (id, name) VALUES (1, 'default') ON CONFLICT DO NOTHING;
+SELECT setval('tenants_id_seq', GREATEST((SELECT MAX(id) FROM tenants), 1));
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS tenant_id INT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS tenant_id INT NOT NULL DEFAULT 1 REFERENCES tenants (id);
+
+CREATE INDEX IF NOT EXISTS projects_tenant_id_idx ON projects (tenant_id);
+CREATE INDEX IF NOT EXISTS goods_tenant_id_priority_idx ON goods (tenant_id, priority);
@@ -0,0 +1,15 @@
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS removed BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS removed_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    tenant_id  INT       NOT NULL REFERENCES tenants (id),
+    action     TEXT      NOT NULL,
+    entity     TEXT      NOT NULL,
+    entity_id  INT       NOT NULL,
+    payload    JSONB     NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (tenant_id, entity, entity_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS good_favorites
+(
+    user_id    TEXT      NOT NULL,
+    good_id    INT       NOT NULL REFERENCES goods (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, good_id)
+);
+
+CREATE INDEX IF NOT EXISTS good_favorites_good_id_idx ON good_favorites (good_id);
@@ -0,0 +1,3 @@
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS goods_labels_idx ON goods USING GIN (labels);
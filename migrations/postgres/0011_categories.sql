@@ -0,0 +1,18 @@
+CREATE EXTENSION IF NOT EXISTS ltree;
+
+CREATE TABLE IF NOT EXISTS categories
+(
+    id         SERIAL PRIMARY KEY,
+    tenant_id  INT       NOT NULL REFERENCES tenants (id),
+    parent_id  INT REFERENCES categories (id),
+    name       TEXT      NOT NULL,
+    path       LTREE     NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS categories_path_idx ON categories USING GIST (path);
+CREATE INDEX IF NOT EXISTS categories_tenant_id_idx ON categories (tenant_id);
+
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS category_id INT REFERENCES categories (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS goods_category_id_idx ON goods (category_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS good_relations
+(
+    good_id     INT       NOT NULL REFERENCES goods (id) ON DELETE CASCADE,
+    related_id  INT       NOT NULL REFERENCES goods (id) ON DELETE CASCADE,
+    score       REAL      NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (good_id, related_id)
+);
+
+CREATE INDEX IF NOT EXISTS good_relations_score_idx ON good_relations (good_id, score DESC);
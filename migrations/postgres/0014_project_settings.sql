@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS project_settings
+(
+    project_id        INT       PRIMARY KEY REFERENCES projects (id) ON DELETE CASCADE,
+    priority_strategy TEXT      NOT NULL DEFAULT 'shift' CHECK (priority_strategy IN ('shift', 'swap', 'reject')),
+    updated_at        TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE project_settings ADD COLUMN IF NOT EXISTS cache_ttl INT CHECK (cache_ttl > 0);
+ALTER TABLE project_settings ADD COLUMN IF NOT EXISTS default_locale TEXT NOT NULL DEFAULT 'ru';
+ALTER TABLE project_settings ADD COLUMN IF NOT EXISTS webhooks_enabled BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,3 @@
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS views BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS goods_popularity_idx ON goods (tenant_id, views DESC, priority);
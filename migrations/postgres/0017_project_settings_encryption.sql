@@ -0,0 +1 @@
+ALTER TABLE project_settings ADD COLUMN IF NOT EXISTS encrypt_description BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,14 @@
+-- Последний выданный приоритет проекта: новые товары получают следующие
+-- значения через UPDATE ... RETURNING вместо MAX(priority) по товарам.
+CREATE TABLE IF NOT EXISTS project_priority_counters
+(
+    project_id    INT PRIMARY KEY REFERENCES projects (id) ON DELETE CASCADE,
+    last_priority INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO project_priority_counters (project_id, last_priority)
+SELECT p.id, COALESCE(MAX(g.priority), 0)
+FROM projects p
+         LEFT JOIN goods g ON g.project_id = p.id
+GROUP BY p.id
+ON CONFLICT (project_id) DO NOTHING;
@@ -0,0 +1,16 @@
+-- Токены доступа к одному проекту для подрядчиков. Хранится только
+-- SHA-256 токена; capability — read или write.
+CREATE TABLE IF NOT EXISTS api_tokens
+(
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INT       NOT NULL REFERENCES tenants (id),
+    project_id  INT       NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    capability  TEXT      NOT NULL,
+    description TEXT      NOT NULL DEFAULT '',
+    token_hash  TEXT      NOT NULL UNIQUE,
+    created_at  TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMP NOT NULL,
+    revoked_at  TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_tenant_idx ON api_tokens (tenant_id, expires_at);
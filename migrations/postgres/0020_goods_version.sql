@@ -0,0 +1,3 @@
+-- Номер версии товара: растёт на единицу с каждым изменением, о котором
+-- публикуется событие, и передаётся в нём как порядковый номер.
+ALTER TABLE goods ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
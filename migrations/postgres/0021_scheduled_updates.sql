@@ -0,0 +1,20 @@
+-- Отложенные изменения товаров. Поля повторяют тело /good/update, labels
+-- NULL оставляет метки как есть. Изменение применяется планировщиком не
+-- раньше apply_at; applied_at отмечает обработку, error — причину отказа.
+CREATE TABLE IF NOT EXISTS scheduled_updates
+(
+    id          SERIAL PRIMARY KEY,
+    tenant_id   INT       NOT NULL REFERENCES tenants (id),
+    good_id     INT       NOT NULL REFERENCES goods (id) ON DELETE CASCADE,
+    name        TEXT      NOT NULL,
+    description TEXT      NOT NULL DEFAULT '',
+    priority    INT       NOT NULL,
+    removed     BOOLEAN   NOT NULL DEFAULT false,
+    labels      JSONB,
+    apply_at    TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT now(),
+    applied_at  TIMESTAMP,
+    error       TEXT
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_updates_pending_idx ON scheduled_updates (apply_at) WHERE applied_at IS NULL;
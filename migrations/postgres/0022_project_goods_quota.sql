@@ -0,0 +1,3 @@
+-- Наибольшее число товаров проекта (не считая корзины); NULL — без
+-- ограничения. Задаётся только администратором.
+ALTER TABLE project_settings ADD COLUMN IF NOT EXISTS max_goods INT CHECK (max_goods >= 0);
@@ -0,0 +1,6 @@
+-- Стратегия gap: приоритеты проекта идут с шагом priority_gap (NULL —
+-- шаг по умолчанию), и вставка между соседями не сдвигает остальные товары.
+ALTER TABLE project_settings DROP CONSTRAINT IF EXISTS project_settings_priority_strategy_check;
+ALTER TABLE project_settings ADD CONSTRAINT project_settings_priority_strategy_check
+    CHECK (priority_strategy IN ('shift', 'swap', 'reject', 'gap'));
+ALTER TABLE project_settings ADD COLUMN IF NOT EXISTS priority_gap INT CHECK (priority_gap > 0);
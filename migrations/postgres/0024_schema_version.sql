@@ -0,0 +1,8 @@
+-- Номер последней применённой миграции; сервис сверяет его при запуске.
+-- Каждая следующая миграция должна обновлять version.
+CREATE TABLE IF NOT EXISTS schema_version (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    version INT NOT NULL
+);
+INSERT INTO schema_version (version) VALUES (24)
+    ON CONFLICT (id) DO UPDATE SET version = EXCLUDED.version;
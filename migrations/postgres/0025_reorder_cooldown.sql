@@ -0,0 +1,5 @@
+-- Время последнего полного переупорядочивания проекта: следующее
+-- разрешено не раньше чем через reorderCooldown.
+ALTER TABLE project_priority_counters ADD COLUMN IF NOT EXISTS reordered_at TIMESTAMP;
+
+UPDATE schema_version SET version = 25;
@@ -0,0 +1,17 @@
+-- Правила маршрутизации исходящих событий арендатора: событие subject
+-- (пустой — любое) проекта project_id (NULL — любого) отбрасывается (drop),
+-- уходит в тему target вместо своей (route) или дополнительно к ней (copy).
+CREATE TABLE IF NOT EXISTS event_routes
+(
+    id         SERIAL PRIMARY KEY,
+    tenant_id  INT       NOT NULL REFERENCES tenants (id),
+    subject    TEXT      NOT NULL DEFAULT '',
+    project_id INT REFERENCES projects (id) ON DELETE CASCADE,
+    action     TEXT      NOT NULL CHECK (action IN ('drop', 'route', 'copy')),
+    target     TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS event_routes_tenant_idx ON event_routes (tenant_id);
+
+UPDATE schema_version SET version = 26;
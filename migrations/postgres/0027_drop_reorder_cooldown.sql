@@ -0,0 +1,5 @@
+-- Полного переупорядочивания товаров проекта больше нет: перестановка
+-- затрагивает один товар, и время последнего переупорядочивания не нужно.
+ALTER TABLE project_priority_counters DROP COLUMN IF EXISTS reordered_at;
+
+UPDATE schema_version SET version = 27;
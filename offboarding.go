@@ -0,0 +1,380 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hezzl-test/internal/labels"
+	"hezzl-test/internal/objectstore"
+	"hezzl-test/internal/progress"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// projectBundleVersion — версия формата архива проекта; меняется при
+// несовместимой правке файлов в нём.
+const projectBundleVersion = 1
+
+// Файлы архива проекта. Списки записаны построчно (NDJSON), чтобы их можно
+// было читать, не загружая целиком.
+const (
+	bundleManifestFile    = "manifest.json"
+	bundleProjectFile     = "project.json"
+	bundleGoodsFile       = "goods.ndjson"
+	bundleHistoryFile     = "history.ndjson"
+	bundleAuditFile       = "audit.ndjson"
+	bundleAttachmentsFile = "attachments.ndjson"
+)
+
+// BundleManifest описывает архив: когда и для какого проекта он собран и
+// сколько записей в каждом файле.
+type BundleManifest struct {
+	Version   int            `json:"version"`
+	TenantID  int            `json:"tenant_id"`
+	ProjectID int            `json:"project_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Files     map[string]int `json:"files"`
+}
+
+type BundleProject struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Archived  bool            `json:"archived"`
+	Removed   bool            `json:"removed"`
+	CreatedAt time.Time       `json:"created_at"`
+	Settings  ProjectSettings `json:"settings"`
+}
+
+type BundleGood struct {
+	ID          int           `json:"id"`
+	CategoryID  *int          `json:"category_id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Priority    int           `json:"priority"`
+	Removed     bool          `json:"removed"`
+	RemovedAt   *time.Time    `json:"removed_at"`
+	Labels      labels.Labels `json:"labels"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Views       int64         `json:"views"`
+	Version     int64         `json:"version"`
+}
+
+// BundleEvent — событие товара из журнала goods_log.
+type BundleEvent struct {
+	GoodID      int             `json:"good_id"`
+	Event       string          `json:"event"`
+	Time        time.Time       `json:"time"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Priority    int             `json:"priority"`
+	Removed     bool            `json:"removed"`
+	Diff        json.RawMessage `json:"diff,omitempty"`
+	Editor      string          `json:"editor,omitempty"`
+}
+
+// BundleAttachment — вложение без содержимого: ключ в хранилище и ссылка
+// на скачивание, действующая projectExportLinkTime.
+type BundleAttachment struct {
+	ID          int       `json:"id"`
+	GoodID      int       `json:"good_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Key         string    `json:"key"`
+	URL         string    `json:"url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type bundleProgress struct {
+	File string `json:"file"`
+	Rows int    `json:"rows"`
+}
+
+// bundleSummary — итог выгрузки: ключ архива в S3 и ссылка на него или
+// ошибка.
+type bundleSummary struct {
+	Key   string         `json:"key,omitempty"`
+	URL   string         `json:"url,omitempty"`
+	Size  int64          `json:"size,omitempty"`
+	Files map[string]int `json:"files,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// exportProjectHandler ставит в очередь exports сборку полного архива
+// проекта id — для клиентов, уходящих с платформы, и запросов регуляторов —
+// и сразу отвечает 202 с id задания. Ход и итог со ссылкой на архив отдаёт
+// /jobs/events; сама выгрузка записывается в журнал аудита.
+func exportProjectHandler(db, clickhouse *sql.DB, s3 *objectstore.S3, exports *worker.Pool, jobs *progress.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		tenantID := tenant.FromContext(r.Context())
+
+		var exists bool
+		err = db.QueryRowContext(r.Context(), "SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND tenant_id = $2)", projectID, tenantID).
+			Scan(&exists)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if !exists {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+
+		job := jobs.Start(tenantID)
+		if err := audit(r.Context(), db, "export", "project", projectID, map[string]string{"jobId": job.ID}); err != nil {
+			job.Finish(bundleSummary{Error: err.Error()})
+			response.InternalError(w, r, err)
+			return
+		}
+		err = exports.Submit(r.Context(), "export_project", func(ctx context.Context) error {
+			summary, err := exportProject(ctx, db, clickhouse, s3, tenantID, projectID, job)
+			if err != nil {
+				job.Finish(bundleSummary{Error: err.Error()})
+				return fmt.Errorf("export project %d: %w", projectID, err)
+			}
+			job.Finish(summary)
+			return nil
+		})
+		if err != nil {
+			job.Finish(bundleSummary{Error: err.Error()})
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusAccepted, map[string]interface{}{"projectId": projectID, "jobId": job.ID})
+	}
+}
+
+// exportProject собирает архив во временном файле и загружает его в S3.
+func exportProject(ctx context.Context, db, clickhouse *sql.DB, s3 *objectstore.S3, tenantID, projectID int, job *progress.Job) (bundleSummary, error) {
+	f, err := os.CreateTemp("", "project-*.zip")
+	if err != nil {
+		return bundleSummary{}, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	files, err := writeProjectBundle(ctx, db, clickhouse, s3, tenantID, projectID, f, job)
+	if err != nil {
+		return bundleSummary{}, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return bundleSummary{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return bundleSummary{}, err
+	}
+
+	key := fmt.Sprintf("exports/%d/project-%d-%s.zip", tenantID, projectID, clk.Now().UTC().Format("20060102T150405Z"))
+	if err := s3.Put(ctx, key, "application/zip", f, size); err != nil {
+		return bundleSummary{}, err
+	}
+	url, err := s3.PresignGet(key, projectExportLinkTime)
+	if err != nil {
+		return bundleSummary{}, err
+	}
+	return bundleSummary{Key: key, URL: url, Size: size, Files: files}, nil
+}
+
+// writeProjectBundle пишет архив проекта в w. Данные Postgres читаются из
+// одной транзакции REPEATABLE READ; журнал goods_log из ClickHouse — после
+// неё и может содержать события, пришедшие за время выгрузки.
+func writeProjectBundle(ctx context.Context, db, clickhouse *sql.DB, s3 *objectstore.S3, tenantID, projectID int, w io.Writer, job *progress.Job) (map[string]int, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	zw := zip.NewWriter(w)
+	files := make(map[string]int)
+	section := func(name string, write func(enc *json.Encoder) (int, error)) error {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		n, err := write(json.NewEncoder(fw))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		files[name] = n
+		job.Progress(bundleProgress{File: name, Rows: n})
+		return nil
+	}
+
+	err = section(bundleProjectFile, func(enc *json.Encoder) (int, error) {
+		return 1, writeBundleProject(ctx, tx, tenantID, projectID, enc)
+	})
+	if err == nil {
+		err = section(bundleGoodsFile, func(enc *json.Encoder) (int, error) {
+			return writeBundleGoods(ctx, tx, projectID, enc)
+		})
+	}
+	if err == nil {
+		err = section(bundleAttachmentsFile, func(enc *json.Encoder) (int, error) {
+			return writeBundleAttachments(ctx, tx, s3, projectID, enc)
+		})
+	}
+	if err == nil {
+		err = section(bundleAuditFile, func(enc *json.Encoder) (int, error) {
+			return writeBundleAudit(ctx, tx, tenantID, projectID, enc)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	tx.Rollback()
+
+	err = section(bundleHistoryFile, func(enc *json.Encoder) (int, error) {
+		return writeBundleHistory(ctx, clickhouse, projectID, enc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fw, err := zw.Create(bundleManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	manifest := BundleManifest{
+		Version:   projectBundleVersion,
+		TenantID:  tenantID,
+		ProjectID: projectID,
+		CreatedAt: clk.Now().UTC(),
+		Files:     files,
+	}
+	if err := json.NewEncoder(fw).Encode(manifest); err != nil {
+		return nil, err
+	}
+	return files, zw.Close()
+}
+
+func writeBundleProject(ctx context.Context, tx *sql.Tx, tenantID, projectID int, enc *json.Encoder) error {
+	var p BundleProject
+	err := tx.QueryRowContext(ctx, "SELECT id, name, archived, removed, created_at FROM projects WHERE id = $1 AND tenant_id = $2", projectID, tenantID).
+		Scan(&p.ID, &p.Name, &p.Archived, &p.Removed, &p.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if p.Settings, err = loadProjectSettings(ctx, tx, projectID); err != nil {
+		return err
+	}
+	return enc.Encode(p)
+}
+
+func writeBundleGoods(ctx context.Context, tx *sql.Tx, projectID int, enc *json.Encoder) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, category_id, name, description, priority, removed, removed_at, labels, created_at, views, version
+		FROM goods WHERE project_id = $1 ORDER BY id`, projectID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var g BundleGood
+		if err := rows.Scan(&g.ID, &g.CategoryID, &g.Name, decrypted{&g.Description}, &g.Priority, &g.Removed, &g.RemovedAt, &g.Labels, &g.CreatedAt, &g.Views, &g.Version); err != nil {
+			return n, err
+		}
+		if err := enc.Encode(g); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+func writeBundleAttachments(ctx context.Context, tx *sql.Tx, s3 *objectstore.S3, projectID int, enc *json.Encoder) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT a.id, a.good_id, a.file_name, a.content_type, a.object_key, a.created_at
+		FROM good_attachments a JOIN goods g ON g.id = a.good_id
+		WHERE g.project_id = $1 ORDER BY a.id`, projectID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var a BundleAttachment
+		if err := rows.Scan(&a.ID, &a.GoodID, &a.FileName, &a.ContentType, &a.Key, &a.CreatedAt); err != nil {
+			return n, err
+		}
+		if a.URL, err = s3.PresignGet(a.Key, projectExportLinkTime); err != nil {
+			return n, err
+		}
+		if err := enc.Encode(a); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// writeBundleAudit пишет записи аудита о самом проекте и о его товарах.
+func writeBundleAudit(ctx context.Context, tx *sql.Tx, tenantID, projectID int, enc *json.Encoder) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, action, entity, entity_id, payload, created_at
+		FROM audit_log
+		WHERE tenant_id = $1 AND (
+			(entity = 'project' AND entity_id = $2) OR
+			(entity = 'good' AND entity_id IN (SELECT id FROM goods WHERE project_id = $2)))
+		ORDER BY id`, tenantID, projectID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.Entity, &e.EntityID, &e.Payload, &e.CreatedAt); err != nil {
+			return n, err
+		}
+		if err := enc.Encode(e); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+func writeBundleHistory(ctx context.Context, clickhouse *sql.DB, projectID int, enc *json.Encoder) (int, error) {
+	rows, err := clickhouse.QueryContext(ctx, `SELECT Id, EventType, EventTime, Name, Description, Priority, Removed, Diff, Editor
+		FROM goods_log WHERE ProjectId = ? ORDER BY EventTime, Id`, projectID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var (
+			e       BundleEvent
+			removed uint8
+			diff    string
+		)
+		if err := rows.Scan(&e.GoodID, &e.Event, &e.Time, &e.Name, &e.Description, &e.Priority, &removed, &diff, &e.Editor); err != nil {
+			return n, err
+		}
+		e.Removed = removed != 0
+		if json.Valid([]byte(diff)) {
+			e.Diff = json.RawMessage(diff)
+		}
+		if err := enc.Encode(e); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// Стратегии занятия приоритета, на котором уже стоит другой товар проекта.
+// gap хранит приоритеты с шагом и трактует запрошенный приоритет как
+// позицию в проекте (см. gapPriority).
+const (
+	priorityShift  = "shift"
+	prioritySwap   = "swap"
+	priorityReject = "reject"
+	priorityGap    = "gap"
+)
+
+var (
+	errPriorityTaken = errs.Conflict("errors.good.priorityTaken")
+	errGoodNotFound  = storage.ErrGoodNotFound
+)
+
+func validPriorityStrategy(s string) bool {
+	return s == priorityShift || s == prioritySwap || s == priorityReject || s == priorityGap
+}
+
+// reservePriorities выдаёт проекту n подряд идущих приоритетов в конце и
+// возвращает предыдущий выданный: новые товары получают base+1 … base+n.
+// Счётчик проекта блокируется до конца транзакции, так что параллельные
+// вставки не получают одинаковых приоритетов. Для чужого или
+// несуществующего проекта возвращается sql.ErrNoRows.
+func reservePriorities(ctx context.Context, q querier, tenantID, projectID, n int) (int, error) {
+	var last int
+	err := q.QueryRowContext(ctx, `INSERT INTO project_priority_counters (project_id, last_priority)
+		SELECT id, $3 FROM projects WHERE id = $1 AND tenant_id = $2
+		ON CONFLICT (project_id) DO UPDATE SET last_priority = project_priority_counters.last_priority + $3
+		RETURNING last_priority`,
+		projectID, tenantID, n).Scan(&last)
+	return last - n, err
+}
+
+// raisePriorityCounter подтягивает счётчик проекта к явно выставленному
+// приоритету, чтобы следующий новый товар встал после него.
+func raisePriorityCounter(ctx context.Context, q execer, projectID, priority int) error {
+	_, err := q.ExecContext(ctx, "UPDATE project_priority_counters SET last_priority = $2 WHERE project_id = $1 AND last_priority < $2",
+		projectID, priority)
+	return err
+}
+
+// makeRoomForPriority освобождает позицию to в проекте для товара goodID,
+// который сейчас стоит на from (новый товар — на позиции после последнего,
+// goodID == 0). shift сдвигает товары между from и to на одну позицию,
+// swap переносит занявший to товар на from, reject возвращает
+// errPriorityTaken. Вызывающий должен держать блокировку проекта.
+func makeRoomForPriority(ctx context.Context, tx *sql.Tx, strategy string, tenantID, projectID, goodID, from, to int) error {
+	var occupant int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM goods WHERE tenant_id = $1 AND project_id = $2 AND priority = $3 AND id <> $4 LIMIT 1",
+		tenantID, projectID, to, goodID).Scan(&occupant)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch strategy {
+	case priorityReject:
+		return errPriorityTaken
+	case prioritySwap:
+		_, err = tx.ExecContext(ctx, "UPDATE goods SET priority = $1 WHERE id = $2 AND project_id = $3", from, occupant, projectID)
+	default:
+		if to < from {
+			_, err = tx.ExecContext(ctx, `UPDATE goods SET priority = priority + 1
+				WHERE tenant_id = $1 AND project_id = $2 AND priority >= $3 AND priority < $4 AND id <> $5`,
+				tenantID, projectID, to, from, goodID)
+		} else {
+			_, err = tx.ExecContext(ctx, `UPDATE goods SET priority = priority - 1
+				WHERE tenant_id = $1 AND project_id = $2 AND priority > $3 AND priority <= $4 AND id <> $5`,
+				tenantID, projectID, from, to, goodID)
+		}
+	}
+	return err
+}
+
+// gapPriority подбирает приоритет для товара goodID на позиции position
+// (с 1) среди остальных товаров проекта при стратегии gap. Если между
+// соседями есть место, товар встаёт посередине и никого не трогает; если
+// соседи идут подряд, следующие за позицией товары перенумеровываются с
+// шагом gap, пока не найдётся товар с приоритетом выше уже выданного.
+// Вызывающий должен держать блокировку проекта.
+func gapPriority(ctx context.Context, tx *sql.Tx, tenantID, projectID, goodID, position, gap int) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, priority FROM goods
+		WHERE tenant_id = $1 AND project_id = $2 AND id <> $3
+		ORDER BY priority, id OFFSET $4`,
+		tenantID, projectID, goodID, max(position-2, 0))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	prev := 0
+	if position > 1 {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return 0, err
+			}
+			rows.Close()
+			// Позиция за последним товаром: товар встаёт в конец.
+			var last int
+			err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(priority), 0) FROM goods WHERE tenant_id = $1 AND project_id = $2 AND id <> $3",
+				tenantID, projectID, goodID).Scan(&last)
+			return last + gap, err
+		}
+		var id int
+		if err := rows.Scan(&id, &prev); err != nil {
+			return 0, err
+		}
+	}
+
+	priority := prev + gap
+	var ids, priorities []int
+	for last := priority; rows.Next(); last += gap {
+		var id, current int
+		if err := rows.Scan(&id, &current); err != nil {
+			return 0, err
+		}
+		if len(ids) == 0 && current-prev > 1 {
+			priority = prev + (current-prev)/2
+			break
+		}
+		if current > last {
+			break
+		}
+		ids = append(ids, id)
+		priorities = append(priorities, last+gap)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE goods g SET priority = u.priority
+			FROM unnest($1::int[], $2::int[]) AS u(id, priority) WHERE g.id = u.id`,
+			pq.Array(ids), pq.Array(priorities))
+		if err != nil {
+			return 0, err
+		}
+		if err := raisePriorityCounter(ctx, tx, projectID, priorities[len(priorities)-1]); err != nil {
+			return 0, err
+		}
+	}
+	return priority, nil
+}
+
+// moveGood ставит товар goodID проекта projectID на позицию to, освобождая
+// её по стратегии проекта, и возвращает новый приоритет товара: при
+// стратегии gap он может отличаться от to. Проект блокируется до конца
+// транзакции.
+func moveGood(ctx context.Context, tx *sql.Tx, tenantID, projectID, goodID, to int) (int, error) {
+	var from int
+	err := tx.QueryRowContext(ctx, "SELECT priority FROM goods WHERE id = $1 AND project_id = $2 AND tenant_id = $3 FOR UPDATE",
+		goodID, projectID, tenantID).Scan(&from)
+	if err == sql.ErrNoRows {
+		return 0, errGoodNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := storage.CheckWritable(ctx, tx, tenantID, projectID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM projects WHERE id = $1 FOR UPDATE", projectID); err != nil {
+		return 0, err
+	}
+	settings, err := loadProjectSettings(ctx, tx, projectID)
+	if err != nil {
+		return 0, err
+	}
+	if settings.PriorityStrategy == priorityGap {
+		to, err = gapPriority(ctx, tx, tenantID, projectID, goodID, to, settings.PriorityGapSize())
+	} else {
+		err = makeRoomForPriority(ctx, tx, settings.PriorityStrategy, tenantID, projectID, goodID, from, to)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE goods SET priority = $1 WHERE id = $2 AND project_id = $3", to, goodID, projectID); err != nil {
+		return 0, err
+	}
+	return to, raisePriorityCounter(ctx, tx, projectID, to)
+}
+
+// setGoodPriority переводит товар проекта projectID с приоритета from на to
+// тем же путём, что и reprioritize (moveGood), и возвращает итоговый
+// приоритет. Если to не задан (<= 0) или совпадает с from, приоритет не
+// меняется.
+func setGoodPriority(ctx context.Context, tx *sql.Tx, tenantID, projectID, goodID, from, to int) (int, error) {
+	if to <= 0 || to == from {
+		return from, nil
+	}
+	return moveGood(ctx, tx, tenantID, projectID, goodID, to)
+}
+
+type PriorityChange = storage.PriorityChange
+
+// GoodsReordered — событие goods_reordered: все изменения приоритетов одной
+// перестановки в проекте ProjectID.
+type GoodsReordered struct {
+	ProjectID  int              `json:"project_id"`
+	Priorities []PriorityChange `json:"priorities"`
+}
+
+type Reorder struct {
+	DryRun bool `json:"dry_run"`
+	GoodsReordered
+}
+
+// reorderGood переставляет товар проекта projectID через moveGood и
+// возвращает изменившиеся приоритеты товаров проекта.
+func reorderGood(ctx context.Context, tx *sql.Tx, tenantID, projectID, goodID, to int) ([]PriorityChange, error) {
+	var found bool
+	err := tx.QueryRowContext(ctx, "SELECT true FROM goods WHERE id = $1 AND project_id = $2 AND tenant_id = $3", goodID, projectID, tenantID).Scan(&found)
+	if err == sql.ErrNoRows {
+		return nil, errGoodNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Проект блокируется до снимка, чтобы в изменения не попали чужие.
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM projects WHERE id = $1 FOR UPDATE", projectID); err != nil {
+		return nil, err
+	}
+
+	before, err := projectPriorities(ctx, tx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := moveGood(ctx, tx, tenantID, projectID, goodID, to); err != nil {
+		return nil, err
+	}
+	if _, err := bumpGoodVersion(ctx, tx, goodID); err != nil {
+		return nil, err
+	}
+	after, err := projectPriorities(ctx, tx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return priorityChanges(before, after), nil
+}
+
+func priorityChanges(before, after map[int]int) []PriorityChange {
+	changes := []PriorityChange{}
+	for id, priority := range after {
+		if previous := before[id]; previous != priority {
+			changes = append(changes, PriorityChange{ID: id, Priority: priority, Previous: previous})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Priority < changes[j].Priority })
+	return changes
+}
+
+// previewReprioritizeHandler выполняет перестановку товара id проекта
+// projectId так же, как reprioritize, но откатывает транзакцию и возвращает
+// только товары проекта, чей приоритет изменился бы.
+func previewReprioritizeHandler(goods storage.GoodsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goodID, projectID, err := goodParams(r)
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		var newPriority NewPriority
+		if err := json.NewDecoder(r.Body).Decode(&newPriority); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if newPriority.NewPriority <= 0 {
+			response.BadRequest(w, r, fmt.Errorf("invalid newPriority %d", newPriority.NewPriority))
+			return
+		}
+
+		changes, err := goods.Reorder(r.Context(), tenant.FromContext(r.Context()), projectID, goodID, newPriority.NewPriority, true)
+		if err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, map[string][]PriorityChange{"priorities": changes})
+	}
+}
+
+func projectPriorities(ctx context.Context, tx *sql.Tx, projectID int) (map[int]int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, priority FROM goods WHERE project_id = $1", projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	priorities := make(map[int]int)
+	for rows.Next() {
+		var id, priority int
+		if err := rows.Scan(&id, &priority); err != nil {
+			return nil, err
+		}
+		priorities[id] = priority
+	}
+	return priorities, rows.Err()
+}
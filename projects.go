@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/metrics"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errProjectNotFound = storage.ErrProjectNotFound
+	errProjectArchived = storage.ErrProjectArchived
+	errProjectRemoved  = storage.ErrProjectRemoved
+	errQuotaExceeded   = bulk.ErrQuotaExceeded
+)
+
+type ProjectArchive struct {
+	Archived *bool `json:"archived"`
+}
+
+// ensureDefaultProjects создаёт проект по умолчанию арендаторам, у которых
+// ещё нет ни одного проекта.
+func ensureDefaultProjects(ctx context.Context, q execer) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO projects (tenant_id, name)
+		SELECT t.id, $1 FROM tenants t
+		WHERE NOT EXISTS (SELECT 1 FROM projects p WHERE p.tenant_id = t.id)`, defaultProjectName)
+	return err
+}
+
+// goodNameTemplates — шаблоны имени товара по умолчанию для языков,
+// которые можно выбрать в default_locale проекта.
+var goodNameTemplates = map[string]string{
+	"ru": goodNameTemplate,
+	"en": "Item {id}",
+}
+
+func defaultGoodName(locale string, id int) string {
+	template, ok := goodNameTemplates[locale]
+	if !ok {
+		template = goodNameTemplate
+	}
+	return strings.ReplaceAll(template, "{id}", strconv.Itoa(id))
+}
+
+func archiveProjectHandler(db *sql.DB, redisClient deps.Cache, natsConn deps.Publisher, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		req := ProjectArchive{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				response.BadRequest(w, r, err)
+				return
+			}
+		}
+		archived := req.Archived == nil || *req.Archived
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.Begin()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var project Projects
+		err = tx.QueryRow(`UPDATE projects SET archived = $1, archived_at = CASE WHEN $1 THEN COALESCE(archived_at, now()) END
+			WHERE id = $2 AND tenant_id = $3 AND NOT removed
+			RETURNING id, name, created_at`,
+			archived, projectID, tenantID).Scan(&project.ID, &project.Name, &project.CreatedAt)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		action := "archive"
+		if !archived {
+			action = "unarchive"
+		}
+		if err := audit(r.Context(), tx, action, "project", projectID, struct{}{}); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		data, err := json.Marshal(map[string]interface{}{"id": projectID, "archived": archived})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		err = effects.Submit(r.Context(), "project_"+action+"d", func(ctx context.Context) error {
+			if err := invalidateGoodsLists(ctx, redisClient, tenantID); err != nil {
+				log.Printf("cache: invalidate goods lists: %v", err)
+			}
+			return publish(ctx, natsConn, "project_"+action+"d", data)
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, map[string]interface{}{
+			"id":       project.ID,
+			"name":     project.Name,
+			"archived": archived,
+		})
+	}
+}
+
+// removeProjectHandler мягко удаляет проект: его товары остаются в базе, но
+// пропадают из списков и поиска, а их изменение отклоняется с 409.
+func removeProjectHandler(db *sql.DB, redisClient deps.Cache, budgets *cachebudget.Budget, natsConn deps.Publisher, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.Begin()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var project Projects
+		err = tx.QueryRow(`UPDATE projects SET removed = true, removed_at = now()
+			WHERE id = $1 AND tenant_id = $2 AND NOT removed
+			RETURNING id, name, created_at`,
+			projectID, tenantID).Scan(&project.ID, &project.Name, &project.CreatedAt)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		rows, err := tx.Query("SELECT id FROM goods WHERE project_id = $1", projectID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		var goodIDs []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				response.InternalError(w, r, err)
+				return
+			}
+			goodIDs = append(goodIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := audit(r.Context(), tx, "remove", "project", projectID, map[string]int{"goods": len(goodIDs)}); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		data, err := json.Marshal(map[string]interface{}{"id": projectID, "removed": true})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		err = effects.Submit(r.Context(), "project_removed", func(ctx context.Context) error {
+			if err := invalidateProject(ctx, redisClient, tenantID, projectID, goodIDs); err != nil {
+				log.Printf("cache: invalidate project %d: %v", projectID, err)
+			}
+			budgets.ForgetProject(projectID)
+			return publish(ctx, natsConn, "project_removed", data)
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, map[string]interface{}{
+			"id":      project.ID,
+			"name":    project.Name,
+			"removed": true,
+		})
+	}
+}
+
+type ProjectsMerge struct {
+	SourceID      int `json:"sourceId"`
+	DestinationID int `json:"destinationId"`
+}
+
+// mergeProjectsHandler переносит все товары исходного проекта в конец
+// целевого и мягко удаляет исходный проект.
+func mergeProjectsHandler(db *sql.DB, budgets *cachebudget.Budget, cacheTTL time.Duration, natsConn deps.Publisher, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dryRun, err := dryRunParam(r)
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		var req ProjectsMerge
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if req.SourceID == req.DestinationID {
+			response.BadRequest(w, r, errors.New("sourceId and destinationId must differ"))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.Begin()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var locked, archived int
+		err = tx.QueryRow(`SELECT COUNT(*), COUNT(*) FILTER (WHERE archived) FROM (
+				SELECT id, archived FROM projects WHERE id IN ($1, $2) AND tenant_id = $3 AND NOT removed ORDER BY id FOR UPDATE
+			) p`,
+			req.SourceID, req.DestinationID, tenantID).Scan(&locked, &archived)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if locked != 2 {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+		if archived != 0 {
+			response.Fail(w, r, errProjectArchived)
+			return
+		}
+
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM goods WHERE project_id = $1", req.SourceID).Scan(&count); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		maxPriority, err := reservePriorities(r.Context(), tx, tenantID, req.DestinationID, count)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		rows, err := tx.Query(`UPDATE goods g SET project_id = $1, priority = $2 + s.rn, version = g.version + 1
+			FROM (SELECT id, priority, ROW_NUMBER() OVER (ORDER BY priority, id) AS rn FROM goods WHERE project_id = $3) s
+			WHERE g.id = s.id
+			RETURNING g.id, g.project_id, g.name, g.description, g.priority, g.removed, g.created_at, s.priority, g.version`,
+			req.DestinationID, maxPriority, req.SourceID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		var moved []Goods
+		var before []Goods
+		var versions []int64
+		for rows.Next() {
+			var good Goods
+			var oldPriority int
+			var version int64
+			err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, decrypted{&good.Description}, &good.Priority, &good.Removed, &good.CreatedAt, &oldPriority, &version)
+			if err != nil {
+				rows.Close()
+				response.InternalError(w, r, err)
+				return
+			}
+			moved = append(moved, good)
+			versions = append(versions, version)
+
+			old := good
+			old.ProjectID, old.Priority = req.SourceID, oldPriority
+			before = append(before, old)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		encrypted, err := encryptedProjects(r.Context(), tx, []int{req.SourceID, req.DestinationID})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		sealed := encrypted[req.SourceID] || encrypted[req.DestinationID]
+
+		_, err = tx.Exec("UPDATE projects SET removed = true, removed_at = now() WHERE id = $1", req.SourceID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		err = audit(r.Context(), tx, "merge", "project", req.DestinationID, map[string]interface{}{
+			"sourceId":      req.SourceID,
+			"destinationId": req.DestinationID,
+			"movedGoods":    len(moved),
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if dryRun {
+			ids := make([]int, len(moved))
+			for i, good := range moved {
+				ids[i] = good.ID
+			}
+			response.JSON(w, r, http.StatusOK, newDryRun(ids))
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		metrics.GoodsUpdated(req.DestinationID, len(moved))
+
+		for i, good := range moved {
+			good, version := good, versions[i]
+			data, err := json.Marshal(good)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			event, err := json.Marshal(updatedEvent(before[i], good, sealed))
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			err = effects.Submit(r.Context(), "good_updated", func(ctx context.Context) error {
+				budgets.Set(ctx, good.ProjectID, goodCacheKey(tenantID, good.ID), data, cacheTTL)
+				return publishGood(ctx, natsConn, "good_updated", good.ID, version, event)
+			})
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		response.JSON(w, r, http.StatusOK, map[string]int{
+			"sourceId":      req.SourceID,
+			"destinationId": req.DestinationID,
+			"movedGoods":    len(moved),
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/goodslog"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/scheduler"
+	"hezzl-test/internal/search"
+	"hezzl-test/internal/storage"
+	"log"
+	"net/http"
+	"time"
+)
+
+// rebuildJobs сопоставляет цели POST /admin/rebuild задачам планировщика:
+// задачи не запускаются по расписанию, а прогресс виден в GET /admin/jobs.
+var rebuildJobs = map[string]string{
+	"cache":      "rebuild_cache",
+	"search":     "rebuild_search",
+	"clickhouse": "rebuild_clickhouse",
+}
+
+func registerRebuildJobs(s *scheduler.Scheduler, db, clickhouse *sql.DB, goods storage.GoodsRepository, redisClient deps.Cache, cacheTTL time.Duration, elastic *search.Elastic) {
+	s.Register(scheduler.Job{
+		Name: rebuildJobs["cache"],
+		Run: func(ctx context.Context) error {
+			return rebuildCache(ctx, db, goods, redisClient, cacheTTL)
+		},
+	})
+	s.Register(scheduler.Job{
+		Name: rebuildJobs["search"],
+		Run: func(ctx context.Context) error {
+			return reindex(ctx, db, elastic)
+		},
+	})
+	s.Register(scheduler.Job{
+		Name: rebuildJobs["clickhouse"],
+		Run: func(ctx context.Context) error {
+			return rebuildGoodsLog(ctx, db, clickhouse)
+		},
+	})
+}
+
+func rebuildHandler(s *scheduler.Scheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		name, ok := rebuildJobs[target]
+		if !ok {
+			response.BadRequest(w, r, fmt.Errorf("unknown target %q", target))
+			return
+		}
+		if err := s.Trigger(name); err != nil {
+			response.Fail(w, r, errJobNotTriggered)
+			return
+		}
+		response.JSON(w, r, http.StatusAccepted, map[string]string{"target": target, "job": name})
+	}
+}
+
+// rebuildCache сбрасывает закэшированные товары и списки каждого арендатора
+// и заново прогревает первую страницу списка.
+func rebuildCache(ctx context.Context, db *sql.DB, goods storage.GoodsRepository, redisClient deps.Cache, cacheTTL time.Duration) error {
+	tenants, err := tenantIDs(db)
+	if err != nil {
+		return err
+	}
+
+	for i, tenantID := range tenants {
+		if err := deleteKeys(ctx, redisClient, fmt.Sprintf("goods:%d:*", tenantID)); err != nil {
+			return err
+		}
+		if err := invalidateGoodsLists(ctx, redisClient, tenantID); err != nil {
+			return err
+		}
+		if err := warmTenantGoods(ctx, goods, redisClient, cacheTTL, tenantID, "rebuild"); err != nil {
+			return err
+		}
+		scheduler.ReportProgress(ctx, i+1, len(tenants))
+	}
+	log.Printf("rebuild_cache: %d tenants rebuilt", len(tenants))
+	return nil
+}
+
+// rebuildGoodsLog дописывает в goods_log события new_good_created для
+// товаров, создание которых пропустил goodslog.Consumer. Postgres хранит
+// только текущее состояние, поэтому остальные события восстановить нельзя;
+// время события берётся из created_at, а повторный запуск ничего не
+// дублирует.
+func rebuildGoodsLog(ctx context.Context, db, clickhouse *sql.DB) error {
+	logged := make(map[int]bool)
+	rows, err := clickhouse.QueryContext(ctx, "SELECT DISTINCT Id FROM goods_log WHERE EventType = 'new_good_created'")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		logged[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM goods").Scan(&count); err != nil {
+		return err
+	}
+
+	// Описания зашифрованных проектов в журнал не пишутся, как и в событиях.
+	rows, err = db.QueryContext(ctx, `SELECT g.id, g.project_id, g.name,
+			CASE WHEN COALESCE(s.encrypt_description, false) THEN '' ELSE g.description END,
+			g.priority, g.removed, g.created_at
+		FROM goods g LEFT JOIN project_settings s ON s.project_id = g.project_id
+		ORDER BY g.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var missing []Goods
+	done, inserted := 0, 0
+	for rows.Next() {
+		var good Goods
+		if err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, decrypted{&good.Description}, &good.Priority, &good.Removed, &good.CreatedAt); err != nil {
+			return err
+		}
+		done++
+		if !logged[good.ID] {
+			missing = append(missing, good)
+		}
+
+		if len(missing) == reindexBatchSize {
+			if err := insertGoodsLog(ctx, clickhouse, missing); err != nil {
+				return err
+			}
+			inserted += len(missing)
+			missing = missing[:0]
+			scheduler.ReportProgress(ctx, done, count)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := insertGoodsLog(ctx, clickhouse, missing); err != nil {
+		return err
+	}
+	inserted += len(missing)
+	scheduler.ReportProgress(ctx, done, count)
+
+	log.Printf("rebuild_clickhouse: %d of %d goods restored", inserted, done)
+	return nil
+}
+
+// insertGoodsLog пишет в goods_log события new_good_created товаров goods.
+func insertGoodsLog(ctx context.Context, clickhouse *sql.DB, goods []Goods) error {
+	records := make([]goodslog.Record, len(goods))
+	for i, good := range goods {
+		records[i] = goodslog.Record{
+			ID:          good.ID,
+			ProjectID:   good.ProjectID,
+			Name:        good.Name,
+			Description: good.Description,
+			Priority:    good.Priority,
+			Removed:     good.Removed,
+			EventType:   "new_good_created",
+			EventTime:   good.CreatedAt,
+		}
+	}
+	return goodslog.Insert(ctx, clickhouse, records)
+}
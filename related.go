@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+const (
+	relatedCategoryScore = 2.0
+	relatedLabelScore    = 1.0
+	relatedCoEditScore   = 0.5
+	relatedMaxLimit      = 50
+)
+
+type RelatedGood struct {
+	Goods
+	Score float64 `json:"score"`
+}
+
+func relatedGoodsHandler(db *sql.DB, redisClient deps.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goodID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		limit := defaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > relatedMaxLimit {
+				response.BadRequest(w, r, fmt.Errorf("invalid limit %q", v))
+				return
+			}
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+		cacheKey := fmt.Sprintf("goods:related:%d:%d:%d", tenantID, goodID, limit)
+
+		if cached, err := redisClient.Get(r.Context(), cacheKey).Bytes(); err == nil {
+			var related []RelatedGood
+			if err := json.Unmarshal(cached, &related); err == nil {
+				response.JSON(w, r, http.StatusOK, related)
+				return
+			}
+		}
+
+		var exists bool
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM goods WHERE id = $1 AND tenant_id = $2)", goodID, tenantID).Scan(&exists)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if !exists {
+			response.Fail(w, r, errGoodNotFound)
+			return
+		}
+
+		rows, err := db.Query(`SELECT g.id, g.project_id, g.category_id, g.name, g.description, g.priority, g.removed, g.labels, g.created_at, gr.score
+			FROM good_relations gr JOIN goods g ON g.id = gr.related_id
+			WHERE gr.good_id = $1 AND g.tenant_id = $2 AND NOT g.removed AND g.project_id NOT IN (SELECT id FROM projects WHERE removed)
+			ORDER BY gr.score DESC, g.priority
+			LIMIT $3`,
+			goodID, tenantID, limit)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		related := []RelatedGood{}
+		for rows.Next() {
+			var g RelatedGood
+			err := rows.Scan(&g.ID, &g.ProjectID, &g.CategoryID, &g.Name, decrypted{&g.Description}, &g.Priority, &g.Removed, &g.Labels, &g.CreatedAt, &g.Score)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			related = append(related, g)
+		}
+
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if data, err := json.Marshal(related); err == nil {
+			redisClient.Set(r.Context(), cacheKey, data, relatedCacheTime)
+		}
+
+		response.JSON(w, r, http.StatusOK, related)
+	}
+}
+
+// computeGoodRelations пересчитывает good_relations: общая категория и каждая
+// общая метка добавляют вес, а совместные правки в ClickHouse (товары одного
+// проекта, изменённые в один и тот же час) — ещё немного сверху.
+func computeGoodRelations(ctx context.Context, db, clickhouse *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM good_relations"); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO good_relations (good_id, related_id, score)
+		SELECT good_id, related_id, SUM(score) FROM (
+			SELECT a.id AS good_id, b.id AS related_id, $1::real AS score
+			FROM goods a JOIN goods b ON b.category_id = a.category_id AND b.tenant_id = a.tenant_id AND b.id <> a.id
+			WHERE NOT a.removed AND NOT b.removed
+			UNION ALL
+			SELECT a.id, b.id, $2::real
+			FROM goods a
+			CROSS JOIN LATERAL jsonb_each_text(a.labels) l
+			JOIN goods b ON b.tenant_id = a.tenant_id AND b.id <> a.id AND b.labels @> jsonb_build_object(l.key, l.value)
+			WHERE NOT a.removed AND NOT b.removed
+		) s
+		GROUP BY good_id, related_id`,
+		relatedCategoryScore, relatedLabelScore)
+	if err != nil {
+		return err
+	}
+
+	var goodIDs, relatedIDs []int64
+	var scores []float64
+	rows, err := clickhouse.QueryContext(ctx, `SELECT a.Id, b.Id, count()
+		FROM (SELECT DISTINCT Id, ProjectId, toStartOfHour(EventTime) AS h FROM goods_log
+			WHERE EventType = 'good_updated' AND EventTime > now() - INTERVAL 30 DAY) a
+		INNER JOIN (SELECT DISTINCT Id, ProjectId, toStartOfHour(EventTime) AS h FROM goods_log
+			WHERE EventType = 'good_updated' AND EventTime > now() - INTERVAL 30 DAY) b
+		ON a.ProjectId = b.ProjectId AND a.h = b.h
+		WHERE a.Id != b.Id
+		GROUP BY a.Id, b.Id`)
+	if err != nil {
+		log.Printf("related_goods: co-edits are skipped: %v", err)
+	} else {
+		for rows.Next() {
+			var goodID, relatedID int64
+			var count int
+			if err := rows.Scan(&goodID, &relatedID, &count); err != nil {
+				rows.Close()
+				return err
+			}
+			goodIDs = append(goodIDs, goodID)
+			relatedIDs = append(relatedIDs, relatedID)
+			scores = append(scores, relatedCoEditScore*float64(count))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if len(goodIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `INSERT INTO good_relations (good_id, related_id, score)
+			SELECT e.good_id, e.related_id, e.score
+			FROM unnest($1::int[], $2::int[], $3::real[]) AS e(good_id, related_id, score)
+			JOIN goods a ON a.id = e.good_id
+			JOIN goods b ON b.id = e.related_id AND b.tenant_id = a.tenant_id
+			ON CONFLICT (good_id, related_id) DO UPDATE SET score = good_relations.score + EXCLUDED.score`,
+			pq.Array(goodIDs), pq.Array(relatedIDs), pq.Array(scores))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
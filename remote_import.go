@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/progress"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/worker"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// googleSheetPath выделяет id таблицы из ссылки на Google Sheets.
+var googleSheetPath = regexp.MustCompile(`^/spreadsheets/d/([^/]+)`)
+
+var errRemoteTooLarge = fmt.Errorf("remote file exceeds %d bytes", importMaxBytes)
+
+// remoteImportURL проверяет адрес удалённого CSV и превращает ссылку на
+// Google Sheets (в том числе на редактор) в ссылку на выгрузку листа в CSV.
+func remoteImportURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errs.Validation(fmt.Errorf("invalid url %q", raw))
+	}
+
+	if u.Host == "docs.google.com" {
+		m := googleSheetPath.FindStringSubmatch(u.Path)
+		if m == nil {
+			return "", errs.Validation(fmt.Errorf("unsupported google docs url %q", raw))
+		}
+		gid := u.Query().Get("gid")
+		if gid == "" {
+			// Редактор передаёт лист во фрагменте: #gid=123.
+			if fragment, err := url.ParseQuery(u.Fragment); err == nil {
+				gid = fragment.Get("gid")
+			}
+		}
+		q := url.Values{"format": {"csv"}}
+		if gid != "" {
+			q.Set("gid", gid)
+		}
+		u = &url.URL{Scheme: "https", Host: u.Host, Path: "/spreadsheets/d/" + m[1] + "/export", RawQuery: q.Encode()}
+	}
+	return u.String(), nil
+}
+
+// remoteImportHandler ставит в очередь imports загрузку товаров в проект из
+// CSV по ссылке url и сразу отвечает 202 с id задания, ход которого
+// отдаёт /jobs/events. Файл скачивается воркером с ограничением размера
+// importMaxBytes и времени remoteImportFetchTimeout, результат публикуется
+// событиями new_good_created и goods_imported.
+func remoteImportHandler(db *sql.DB, redisClient deps.Cache, natsConn deps.Publisher, imports *worker.Pool, jobs *progress.Tracker, outbound http.RoundTripper) http.HandlerFunc {
+	client := &http.Client{Transport: outbound}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		source, err := remoteImportURL(r.URL.Query().Get("url"))
+		if err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		tenantID, projectID, settings, ok := importTarget(w, r, db)
+		if !ok {
+			return
+		}
+
+		job := jobs.Start(tenantID)
+		err = imports.Submit(r.Context(), "import_remote", func(ctx context.Context) error {
+			result, err := importRemote(ctx, client, db, tenantID, projectID, settings, source, job)
+			if err != nil {
+				job.Finish(importSummary{Error: err.Error()})
+				return fmt.Errorf("import %s into project %d: %w", source, projectID, err)
+			}
+			job.Finish(importSummary{Result: &result})
+			log.Printf("import: %s into project %d: inserted %d, rejected %d", source, projectID, result.Inserted, result.Rejected)
+			return goodsImported(ctx, redisClient, natsConn, tenantID, projectID, settings, result)
+		})
+		if err != nil {
+			job.Finish(importSummary{Error: err.Error()})
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusAccepted, map[string]interface{}{"projectId": projectID, "url": source, "jobId": job.ID})
+	}
+}
+
+func importRemote(ctx context.Context, client *http.Client, db *sql.DB, tenantID, projectID int, settings ProjectSettings, source string, job *progress.Job) (bulk.Result, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, remoteImportFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(fetchCtx, http.MethodGet, source, nil)
+	if err != nil {
+		return bulk.Result{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return bulk.Result{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return bulk.Result{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if resp.ContentLength > importMaxBytes {
+		return bulk.Result{}, errRemoteTooLarge
+	}
+
+	csvSrc, err := newCSVSource(&limitedReader{r: resp.Body, n: importMaxBytes})
+	if err != nil {
+		return bulk.Result{}, err
+	}
+	src := &trackedSource{Source: sealingSource{Source: csvSrc, settings: settings}, job: job}
+	return bulk.Load(ctx, db, importInto(tenantID, projectID, settings), src)
+}
+
+// limitedReader, в отличие от io.LimitReader, не обрезает файл молча, а
+// возвращает errRemoteTooLarge, если данных больше n.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errRemoteTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errRemoteTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"hezzl-test/internal/queries"
+	"hezzl-test/internal/storage"
+	"time"
+)
+
+// postgresGoods дополняет storage.PostgresGoods списками, созданием,
+// изменением и перестановкой товаров. Каждый метод держит свою транзакцию
+// и выполняет в ней то же, что пакетная загрузка, перенос и отложенные
+// изменения: настройки проекта, стратегию приоритетов, счётчик проекта и
+// аудит.
+type postgresGoods struct {
+	*storage.PostgresGoods
+	db         *sql.DB
+	clickhouse *sql.DB
+	stmts      *stmtCache
+}
+
+func newPostgresGoods(db, clickhouse *sql.DB, stmts *stmtCache) *postgresGoods {
+	return &postgresGoods{
+		PostgresGoods: storage.NewPostgresGoods(db, fieldKeys),
+		db:            db,
+		clickhouse:    clickhouse,
+		stmts:         stmts,
+	}
+}
+
+var _ storage.GoodsRepository = (*postgresGoods)(nil)
+
+// goodsRows — курсор storage.GoodsRows по строкам queryGoodsPage.
+type goodsRows struct {
+	*sql.Rows
+}
+
+func (r goodsRows) Good() (storage.Good, error) {
+	var good storage.Good
+	err := r.Scan(&good.ID, &good.ProjectID, &good.CategoryID, &good.Name, decrypted{&good.Description}, &good.Priority, &good.Removed, &good.Labels, &good.CreatedAt, &good.Views, &good.Favorite)
+	return good, err
+}
+
+func (g *postgresGoods) List(ctx context.Context, q storage.GoodsQuery) (storage.Page, storage.GoodsRows, error) {
+	meta, rows, err := queryGoodsPage(ctx, g.stmts, q)
+	if err != nil {
+		return storage.Page{}, nil, err
+	}
+	return storage.Page{Total: meta.Total, Removed: meta.Removed}, goodsRows{rows}, nil
+}
+
+func (g *postgresGoods) ListAsOf(ctx context.Context, tenantID int, at time.Time, limit, offset int) (storage.Page, []storage.Good, error) {
+	return loadGoodsAsOf(ctx, g.db, g.clickhouse, tenantID, at, limit, offset)
+}
+
+// goodFromRow собирает Goods из строки goods, прочитанной запросом sqlc;
+// описание расшифровывается.
+func goodFromRow(row queries.Good) (Goods, error) {
+	good := Goods{
+		ID:        int(row.ID),
+		ProjectID: int(row.ProjectID),
+		Name:      row.Name,
+		Priority:  int(row.Priority),
+		Removed:   row.Removed,
+		CreatedAt: row.CreatedAt,
+		Views:     row.Views,
+	}
+	if row.CategoryID.Valid {
+		categoryID := int(row.CategoryID.Int32)
+		good.CategoryID = &categoryID
+	}
+	if len(row.Labels) > 0 {
+		if err := json.Unmarshal(row.Labels, &good.Labels); err != nil {
+			return good, err
+		}
+	}
+	if err := (decrypted{&good.Description}).Scan(row.Description); err != nil {
+		return good, err
+	}
+	return good, nil
+}
+
+// int32s приводит id к типу параметров запросов sqlc.
+func int32s(ids []int) []int32 {
+	out := make([]int32, len(ids))
+	for i, id := range ids {
+		out[i] = int32(id)
+	}
+	return out
+}
+
+// duplicatesError — Create нашёл в проекте товары с похожими названиями.
+type duplicatesError struct {
+	Candidates []DuplicateCandidate
+}
+
+func (e *duplicatesError) Error() string {
+	return "goods with similar names exist"
+}
+
+func (g *postgresGoods) Create(ctx context.Context, tenantID int, good storage.NewGood) (storage.Written, error) {
+	var written storage.Written
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return written, err
+	}
+	defer tx.Rollback()
+
+	if err := storage.CheckWritable(ctx, g.stmts.Tx(tx), tenantID, good.ProjectID); err != nil {
+		return written, err
+	}
+
+	settings, err := loadProjectSettings(ctx, tx, good.ProjectID)
+	if err != nil {
+		return written, err
+	}
+	// При стратегии gap новый товар встаёт в конец с шагом.
+	step := 1
+	if settings.PriorityStrategy == priorityGap {
+		step = settings.PriorityGapSize()
+	}
+
+	base, err := reservePriorities(ctx, tx, tenantID, good.ProjectID, step)
+	if err == sql.ErrNoRows {
+		return written, errProjectNotFound
+	}
+	if err != nil {
+		return written, err
+	}
+	priority := base + step
+
+	if err := checkGoodsQuota(ctx, tx, settings, 1); err != nil {
+		return written, err
+	}
+
+	if good.Name != "" && !good.AllowDuplicate {
+		candidates, err := findDuplicates(ctx, tx, tenantID, good.ProjectID, good.Name)
+		if err != nil {
+			return written, err
+		}
+		if len(candidates) > 0 {
+			return written, &duplicatesError{Candidates: candidates}
+		}
+	}
+
+	// Явный приоритет ставит товар на эту позицию; занявший её товар
+	// обрабатывается по стратегии проекта из project_settings. При
+	// стратегии gap приоритет — номер позиции среди товаров проекта.
+	if good.Priority > 0 && (good.Priority < priority || settings.PriorityStrategy == priorityGap) {
+		if _, err := tx.ExecContext(ctx, "SELECT id FROM projects WHERE id = $1 AND tenant_id = $2 FOR UPDATE", good.ProjectID, tenantID); err != nil {
+			return written, err
+		}
+		requested := good.Priority
+		if settings.PriorityStrategy == priorityGap {
+			requested, err = gapPriority(ctx, tx, tenantID, good.ProjectID, 0, good.Priority, step)
+		} else {
+			err = makeRoomForPriority(ctx, tx, settings.PriorityStrategy, tenantID, good.ProjectID, 0, priority, good.Priority)
+		}
+		if err != nil {
+			return written, err
+		}
+		priority = requested
+	}
+
+	description, err := sealDescription(settings, good.Description)
+	if err != nil {
+		return written, err
+	}
+
+	created := storage.Good{
+		ProjectID:   good.ProjectID,
+		Name:        good.Name,
+		Description: good.Description,
+		Priority:    priority,
+		Removed:     good.Removed,
+		Labels:      good.Labels,
+	}
+	err = g.stmts.Tx(tx).QueryRowContext(ctx, `INSERT INTO goods (tenant_id, project_id, category_id, name, description, priority, removed, labels, created_at)
+		SELECT $1, id, (SELECT id FROM categories WHERE id = $9 AND tenant_id = $1), $3, $4, $5, $6, COALESCE($7::jsonb, '{}'), $8
+		FROM projects WHERE id = $2 AND tenant_id = $1 AND NOT removed
+		RETURNING id, category_id, created_at`,
+		tenantID, good.ProjectID, good.Name, description, priority, good.Removed, good.Labels, clk.Now(), good.CategoryID).
+		Scan(&created.ID, &created.CategoryID, &created.CreatedAt)
+	if err == sql.ErrNoRows {
+		return written, errProjectNotFound
+	}
+	if err != nil {
+		return written, err
+	}
+
+	// Имя по умолчанию зависит от id, поэтому проставляется после вставки.
+	if created.Name == "" {
+		created.Name = defaultGoodName(settings.DefaultLocale, created.ID)
+		if _, err := tx.ExecContext(ctx, "UPDATE goods SET name = $1 WHERE id = $2", created.Name, created.ID); err != nil {
+			return written, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return written, err
+	}
+	return storage.Written{
+		Good:      created,
+		Version:   1,
+		Encrypted: settings.EncryptDescription,
+		CacheTTL:  settings.CacheTime(0),
+	}, nil
+}
+
+func (g *postgresGoods) Update(ctx context.Context, tenantID, projectID, id int, update storage.GoodUpdate) (storage.Written, error) {
+	var written storage.Written
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return written, err
+	}
+	defer tx.Rollback()
+
+	if err := storage.CheckWritable(ctx, g.stmts.Tx(tx), tenantID, projectID); err != nil {
+		return written, err
+	}
+
+	q := queries.New(g.stmts.Tx(tx))
+	row, err := q.LockGood(ctx, queries.LockGoodParams{ID: int32(id), TenantID: int32(tenantID)})
+	if err == sql.ErrNoRows || (err == nil && int(row.ProjectID) != projectID) {
+		return written, errGoodNotFound
+	}
+	if err != nil {
+		return written, err
+	}
+	old, err := goodFromRow(row)
+	if err != nil {
+		return written, err
+	}
+
+	settings, err := loadProjectSettings(ctx, tx, projectID)
+	if err != nil {
+		return written, err
+	}
+	description, err := sealDescription(settings, update.Description)
+	if err != nil {
+		return written, err
+	}
+
+	priority, err := setGoodPriority(ctx, tx, tenantID, projectID, id, old.Priority, update.Priority)
+	if err != nil {
+		return written, err
+	}
+
+	var labelsJSON json.RawMessage
+	if update.Labels != nil {
+		if labelsJSON, err = json.Marshal(update.Labels); err != nil {
+			return written, err
+		}
+	}
+	err = q.UpdateGood(ctx, queries.UpdateGoodParams{
+		Name:        update.Name,
+		Description: description,
+		Priority:    int32(priority),
+		Removed:     update.Removed,
+		Labels:      labelsJSON,
+		ID:          int32(id),
+		ProjectID:   int32(projectID),
+	})
+	if err != nil {
+		return written, err
+	}
+
+	updated := old
+	updated.Name, updated.Description, updated.Priority, updated.Removed = update.Name, update.Description, priority, update.Removed
+	if update.Labels != nil {
+		updated.Labels = update.Labels
+	}
+
+	version, err := bumpGoodVersion(ctx, tx, id)
+	if err != nil {
+		return written, err
+	}
+	if err := audit(ctx, tx, "update", "good", id, diffGoods(old, updated)); err != nil {
+		return written, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return written, err
+	}
+	return storage.Written{
+		Good:      goodToStorage(updated),
+		Old:       goodToStorage(old),
+		Version:   version,
+		Encrypted: settings.EncryptDescription,
+		CacheTTL:  settings.CacheTime(0),
+	}, nil
+}
+
+func (g *postgresGoods) Reorder(ctx context.Context, tenantID, projectID, id, priority int, dryRun bool) ([]storage.PriorityChange, error) {
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	changes, err := reorderGood(ctx, tx, tenantID, projectID, id, priority)
+	if err != nil || dryRun {
+		return changes, err
+	}
+	return changes, tx.Commit()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/labels"
+	"hezzl-test/internal/metrics"
+	"hezzl-test/internal/queries"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ScheduledUpdate — изменение товара good_id, которое применится в
+// apply_at. Поля те же, что у тела /good/update.
+type ScheduledUpdate struct {
+	ID          int           `json:"id"`
+	GoodID      int           `json:"good_id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Priority    int           `json:"priority"`
+	Removed     bool          `json:"removed"`
+	Labels      labels.Labels `json:"labels,omitempty"`
+	ApplyAt     time.Time     `json:"apply_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// scheduleGoodUpdateHandler сохраняет отложенное изменение товара. Его
+// применит задача scheduled_updates, как если бы в apply_at пришёл
+// PATCH /good/update.
+func scheduleGoodUpdateHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update ScheduledUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if err := update.Labels.Validate(); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if err := checkDescription(update.Description); err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+		if !update.ApplyAt.After(clk.Now()) {
+			response.BadRequest(w, r, errors.New("apply_at must be in the future"))
+			return
+		}
+		// Колонки без часового пояса: время хранится в UTC, как и now() задачи.
+		update.ApplyAt = update.ApplyAt.UTC()
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var projectID int
+		err = tx.QueryRowContext(r.Context(), "SELECT project_id FROM goods WHERE id = $1 AND tenant_id = $2", update.GoodID, tenantID).
+			Scan(&projectID)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errGoodNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := storage.CheckWritable(r.Context(), tx, tenantID, projectID); err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		settings, err := loadProjectSettings(r.Context(), tx, projectID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		description, err := sealDescription(settings, update.Description)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		err = tx.QueryRowContext(r.Context(), `INSERT INTO scheduled_updates (tenant_id, good_id, name, description, priority, removed, labels, apply_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id, created_at`,
+			tenantID, update.GoodID, update.Name, description, update.Priority, update.Removed, update.Labels, update.ApplyAt, clk.Now().UTC()).
+			Scan(&update.ID, &update.CreatedAt)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := audit(r.Context(), tx, "schedule_update", "good", update.GoodID, map[string]interface{}{
+			"scheduled_update_id": update.ID,
+			"apply_at":            update.ApplyAt,
+		}); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusAccepted, update)
+	}
+}
+
+// applyScheduledUpdates применяет наступившие отложенные изменения по
+// одному в отдельной транзакции. Изменение, которое нельзя применить
+// (проект архивирован или удалён), помечается обработанным с ошибкой.
+func applyScheduledUpdates(ctx context.Context, db *sql.DB, budgets *cachebudget.Budget, cacheTTL time.Duration, natsConn deps.Publisher) error {
+	applied, failed := 0, 0
+	for ctx.Err() == nil {
+		ok, err := applyScheduledUpdate(ctx, db, budgets, cacheTTL, natsConn)
+		if err == errNoScheduledUpdates {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if ok {
+			applied++
+		} else {
+			failed++
+		}
+	}
+	if applied+failed > 0 {
+		log.Printf("scheduled_updates: %d applied, %d rejected", applied, failed)
+	}
+	return ctx.Err()
+}
+
+var errNoScheduledUpdates = errors.New("no scheduled updates due")
+
+func applyScheduledUpdate(ctx context.Context, db *sql.DB, budgets *cachebudget.Budget, cacheTTL time.Duration, natsConn deps.Publisher) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var update ScheduledUpdate
+	var tenantID int
+	err = tx.QueryRowContext(ctx, `SELECT id, tenant_id, good_id, name, description, priority, removed, labels, apply_at, created_at
+		FROM scheduled_updates WHERE applied_at IS NULL AND apply_at <= $1
+		ORDER BY apply_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`, clk.Now().UTC()).
+		Scan(&update.ID, &tenantID, &update.GoodID, &update.Name, decrypted{&update.Description}, &update.Priority, &update.Removed, &update.Labels, &update.ApplyAt, &update.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, errNoScheduledUpdates
+	}
+	if err != nil {
+		return false, err
+	}
+	ctx = tenant.WithTenant(ctx, tenantID)
+
+	row, err := queries.New(tx).LockGood(ctx, queries.LockGoodParams{ID: int32(update.GoodID), TenantID: int32(tenantID)})
+	if err != nil {
+		return false, err
+	}
+	old, err := goodFromRow(row)
+	if err != nil {
+		return false, err
+	}
+
+	// Изменение, которое нельзя применить (проект архивирован или удалён,
+	// приоритет занят при стратегии reject), помечается применённым с
+	// ошибкой, чтобы не повторяться. Такие ошибки возникают до записи в
+	// товары, так что транзакцию можно фиксировать.
+	reject := func(err error) (bool, error) {
+		if !errors.Is(err, errs.ErrConflict) {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE scheduled_updates SET applied_at = $1, error = $2 WHERE id = $3",
+			clk.Now().UTC(), err.Error(), update.ID); err != nil {
+			return false, err
+		}
+		return false, tx.Commit()
+	}
+
+	if err := storage.CheckWritable(ctx, tx, tenantID, old.ProjectID); err != nil {
+		return reject(err)
+	}
+
+	settings, err := loadProjectSettings(ctx, tx, old.ProjectID)
+	if err != nil {
+		return false, err
+	}
+	description, err := sealDescription(settings, update.Description)
+	if err != nil {
+		return false, err
+	}
+
+	priority, err := setGoodPriority(ctx, tx, tenantID, old.ProjectID, old.ID, old.Priority, update.Priority)
+	if err != nil {
+		return reject(err)
+	}
+	update.Priority = priority
+
+	var version int64
+	err = tx.QueryRowContext(ctx, `UPDATE goods SET name = $1, description = $2, priority = $3, removed = $4, removed_at = CASE WHEN $4 THEN COALESCE(removed_at, now()) END,
+			labels = COALESCE($5::jsonb, labels), version = version + 1
+		WHERE id = $6
+		RETURNING version`,
+		update.Name, description, update.Priority, update.Removed, update.Labels, old.ID).Scan(&version)
+	if err != nil {
+		return false, err
+	}
+
+	updated := old
+	updated.Name, updated.Description, updated.Priority, updated.Removed = update.Name, update.Description, update.Priority, update.Removed
+	if update.Labels != nil {
+		updated.Labels = update.Labels
+	}
+	diff := diffGoods(old, updated)
+
+	if err := audit(ctx, tx, "update", "good", old.ID, diff); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE scheduled_updates SET applied_at = $1 WHERE id = $2", clk.Now().UTC(), update.ID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	metrics.GoodsUpdated(old.ProjectID, 1)
+	if updated.Removed && !old.Removed {
+		metrics.GoodsRemoved(old.ProjectID, 1)
+	}
+
+	data, err := json.Marshal(updated)
+	if err != nil {
+		return true, err
+	}
+	event, err := json.Marshal(updatedEvent(old, updated, settings.EncryptDescription))
+	if err != nil {
+		return true, err
+	}
+	budgets.Set(ctx, updated.ProjectID, goodCacheKey(tenantID, old.ID), data, settings.CacheTime(cacheTTL))
+	if err := publishGood(ctx, natsConn, "good_updated", old.ID, version, event); err != nil {
+		log.Printf("scheduled_updates: publish good %d: %v", old.ID, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/clock"
+	"hezzl-test/internal/localcache"
+)
+
+// dueUpdate — ответы на выборку наступившего изменения и товара, к
+// которому оно относится.
+func dueUpdate(now time.Time) []fakeQuery {
+	return []fakeQuery{
+		{
+			match:   "FROM scheduled_updates WHERE applied_at IS NULL AND apply_at <= $1",
+			args:    argEquals(0, now),
+			columns: []string{"id", "tenant_id", "good_id", "name", "description", "priority", "removed", "labels", "apply_at", "created_at"},
+			rows:    [][]driver.Value{{int64(4), int64(testTenantID), int64(11), "Green tea", "Fresh", int64(2), false, nil, now.Add(-time.Minute), now.Add(-time.Hour)}},
+		},
+		{
+			match:   "FROM goods WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+			columns: goodColumns,
+			rows:    [][]driver.Value{{int64(11), int64(3), "Tea", "Fresh", int64(2), false, now.Add(-24 * time.Hour), nil, int64(testTenantID), []byte("{}"), nil, int64(0), int64(5)}},
+		},
+	}
+}
+
+func TestApplyScheduledUpdate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	useClock(t, clock.NewManual(now))
+
+	queries := append(dueUpdate(now),
+		fakeQuery{match: "SELECT archived, removed FROM projects", columns: []string{"archived", "removed"}, rows: [][]driver.Value{{false, false}}},
+		fakeQuery{match: "FROM project_settings WHERE project_id = $1", columns: []string{"priority_strategy"}},
+		fakeQuery{match: "UPDATE goods SET name = $1", args: argEquals(0, "Green tea"), columns: []string{"version"}, rows: [][]driver.Value{{int64(6)}}},
+		fakeQuery{match: "INSERT INTO audit_log", args: argEquals(1, "update")},
+		fakeQuery{match: "UPDATE scheduled_updates SET applied_at = $1 WHERE id = $2", args: argEquals(0, now)},
+	)
+	db, fake := newFakeDB(t, queries...)
+
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	publisher, msgs := publishedEvents(t)
+
+	ok, err := applyScheduledUpdate(context.Background(), db, cachebudget.New(cache, 1<<20), time.Minute, publisher)
+	if err != nil || !ok {
+		t.Fatalf("applyScheduledUpdate = %v, %v", ok, err)
+	}
+	if got := fake.txLog(); !reflect.DeepEqual(got, []string{"BEGIN", "COMMIT"}) {
+		t.Errorf("transactions = %v", got)
+	}
+	if _, err := cache.Get(context.Background(), goodCacheKey(testTenantID, 11)).Result(); err != nil {
+		t.Errorf("good is not cached: %v", err)
+	}
+	if len(*msgs) != 1 || (*msgs)[0].Subject != "good_updated" {
+		t.Fatalf("published %v", *msgs)
+	}
+	if got := occurredAt(t, (*msgs)[0]); !got.Equal(now) {
+		t.Errorf("occurred_at = %v, want %v", got, now)
+	}
+}
+
+func TestApplyScheduledUpdateArchivedProject(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	useClock(t, clock.NewManual(now))
+
+	// Изменение архивного проекта помечается применённым с ошибкой в той же
+	// транзакции, товар не меняется.
+	queries := append(dueUpdate(now),
+		fakeQuery{match: "SELECT archived, removed FROM projects", columns: []string{"archived", "removed"}, rows: [][]driver.Value{{true, false}}},
+		fakeQuery{match: "UPDATE scheduled_updates SET applied_at = $1, error = $2", args: argEquals(0, now)},
+	)
+	db, fake := newFakeDB(t, queries...)
+
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	publisher, msgs := publishedEvents(t)
+
+	ok, err := applyScheduledUpdate(context.Background(), db, cachebudget.New(cache, 1<<20), time.Minute, publisher)
+	if err != nil || ok {
+		t.Fatalf("applyScheduledUpdate = %v, %v; want false, nil", ok, err)
+	}
+	if got := fake.txLog(); !reflect.DeepEqual(got, []string{"BEGIN", "COMMIT"}) {
+		t.Errorf("transactions = %v", got)
+	}
+	if len(*msgs) != 0 {
+		t.Errorf("published %d events for a rejected update", len(*msgs))
+	}
+}
+
+func TestApplyScheduledUpdateNothingDue(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	useClock(t, clock.NewManual(now))
+
+	db, _ := newFakeDB(t, fakeQuery{
+		match:   "FROM scheduled_updates WHERE applied_at IS NULL AND apply_at <= $1",
+		args:    argEquals(0, now),
+		columns: []string{"id"},
+	})
+	publisher, _ := publishedEvents(t)
+	_, err := applyScheduledUpdate(context.Background(), db, nil, time.Minute, publisher)
+	if err != errNoScheduledUpdates {
+		t.Fatalf("err = %v, want errNoScheduledUpdates", err)
+	}
+}
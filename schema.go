@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"hezzl-test/internal/schema"
+	"log"
+	"strings"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// checkSchema сверяет живую схему базы с миграциями. При расхождении
+// в строгом режиме сервис не запускается, в мягком — пишет его в лог.
+// Недоступная база не мешает запуску: её подхватит монитор здоровья.
+func checkSchema(ctx context.Context, db *sql.DB, strict bool) {
+	drift, err := schemaDrift(ctx, db)
+	if err != nil {
+		log.Printf("schema: check skipped: %v", err)
+		return
+	}
+	if len(drift) == 0 {
+		return
+	}
+	msg := "schema: database does not match migrations:\n\t" + strings.Join(drift, "\n\t")
+	if strict {
+		log.Fatal(msg)
+	}
+	log.Print(msg)
+}
+
+func schemaDrift(ctx context.Context, db *sql.DB) ([]string, error) {
+	expected, want, err := schema.FromMigrations(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		return nil, err
+	}
+	got, err := schema.Version(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	live, err := schema.Live(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []string
+	if got != want {
+		drift = append(drift, fmt.Sprintf("version %d, want %d", got, want))
+	}
+	return append(drift, schema.Diff(expected, live)...), nil
+}
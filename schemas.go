@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"hezzl-test/internal/jsonschema"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/router"
+)
+
+// bodySchemaFiles — JSON Schema тел запросов, по файлу на форму тела.
+//
+//go:embed schemas/*.json
+var bodySchemaFiles embed.FS
+
+// bodySchemas — схема тела по маршруту "METHOD path". Схемы запрещают
+// неизвестные поля, поэтому новое поле тела нужно добавить и в структуру,
+// и в схему.
+var bodySchemas = map[string]string{
+	"POST /good/create":                "good.json",
+	"PATCH /good/update":               "good.json",
+	"POST /good/update/schedule":       "good-schedule.json",
+	"PATCH /good/category":             "good-category.json",
+	"POST /good/attachments":           "attachment.json",
+	"POST /category/create":            "category.json",
+	"PATCH /category/update":           "category.json",
+	"POST /digest/subscription":        "digest-subscription.json",
+	"PATCH /project/archive":           "project-archive.json",
+	"PATCH /project/settings":          "project-settings.json",
+	"PATCH /admin/project/quota":       "project-quota.json",
+	"POST /projects/merge":             "projects-merge.json",
+	"POST /admin/tenants":              "tenant.json",
+	"POST /admin/tokens":               "project-token.json",
+	"POST /admin/events/routes":        "event-route.json",
+	"POST /goods/transfer":             "goods-transfer.json",
+	"POST /goods/trash/restore":        "trash-action.json",
+	"POST /goods/trash/purge":          "trash-action.json",
+	"PATCH /goods/reprioritize":        "new-priority.json",
+	"POST /goods/reprioritize/preview": "new-priority.json",
+}
+
+// loadBodySchemas разбирает схемы маршрутов; ошибка в схеме — ошибка
+// сборки, поэтому сервис с ней не запускается.
+func loadBodySchemas() map[string]*jsonschema.Schema {
+	parsed := make(map[string]*jsonschema.Schema)
+	schemas := make(map[string]*jsonschema.Schema, len(bodySchemas))
+	for route, name := range bodySchemas {
+		s, ok := parsed[name]
+		if !ok {
+			data, err := bodySchemaFiles.ReadFile("schemas/" + name)
+			if err != nil {
+				log.Fatalf("schemas: %s: %v", route, err)
+			}
+			if s, err = jsonschema.Parse(data); err != nil {
+				log.Fatalf("schemas: %s: %v", name, err)
+			}
+			parsed[name] = s
+		}
+		schemas[route] = s
+	}
+	return schemas
+}
+
+type SchemaRef struct {
+	Route  string `json:"route"`
+	Schema string `json:"schema"`
+}
+
+// schemaRoutes отдаёт схемы для генерации клиентов: /schemas/ — список
+// маршрутов со ссылками на схемы их тел, /schemas/<file> — сама схема.
+func schemaRoutes() []router.Route {
+	refs := make([]SchemaRef, 0, len(bodySchemas))
+	for route, name := range bodySchemas {
+		refs = append(refs, SchemaRef{Route: route, Schema: "/schemas/" + name})
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Route < refs[j].Route })
+
+	routes := []router.Route{{Method: "GET", Path: "/schemas/", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, r, http.StatusOK, refs)
+	})}}
+	files, _ := bodySchemaFiles.ReadDir("schemas")
+	for _, f := range files {
+		name := f.Name()
+		data, _ := bodySchemaFiles.ReadFile(path.Join("schemas", name))
+		routes = append(routes, router.Route{Method: "GET", Path: "/schemas/" + name, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/schema+json")
+			http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+		})})
+	}
+	return routes
+}
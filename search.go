@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"hezzl-test/internal/bind"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/scheduler"
+	"hezzl-test/internal/search"
+	"hezzl-test/internal/tenant"
+	"log"
+	"net/http"
+)
+
+const reindexBatchSize = 500
+
+// searchGoodsParams — параметры GET /goods/search.
+type searchGoodsParams struct {
+	pageQuery
+	Text      string `query:"q"`
+	ProjectID int    `query:"projectId"`
+	Engine    string `query:"engine" enum:"pg,es"`
+}
+
+func searchGoodsHandler(db *sql.DB, elastic *search.Elastic) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := searchGoodsParams{pageQuery: pageQuery{Limit: defaultLimit}}
+		if err := bind.Query(r.URL.Query(), &params); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		limit, offset := params.Limit, params.Offset
+
+		query := search.Query{
+			TenantID:  tenant.FromContext(r.Context()),
+			Text:      params.Text,
+			ProjectID: params.ProjectID,
+			Limit:     limit,
+			Offset:    offset,
+		}
+
+		list := GoodsList{
+			Meta:  response.Meta{Limit: limit, Offset: offset},
+			Goods: []Goods{},
+		}
+
+		var err error
+		switch params.Engine {
+		case "", "pg":
+			err = searchGoodsPostgres(r.Context(), db, query, &list)
+		case "es":
+			if query.ExcludeProjects, err = hiddenProjectIDs(r.Context(), db, query.TenantID); err == nil {
+				err = searchGoodsElastic(r.Context(), elastic, query, &list)
+			}
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		list.link(r)
+		response.Pagination(w, r, list.Meta)
+		response.JSON(w, r, http.StatusOK, list)
+	}
+}
+
+func searchGoodsPostgres(ctx context.Context, db *sql.DB, query search.Query, list *GoodsList) error {
+	pattern := "%" + query.Text + "%"
+
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM goods
+		WHERE tenant_id = $1 AND NOT removed AND (name ILIKE $2 OR description ILIKE $2) AND ($3 = 0 OR project_id = $3)
+			AND project_id NOT IN (SELECT id FROM projects WHERE archived OR removed)`,
+		query.TenantID, pattern, query.ProjectID).Scan(&list.Meta.Total)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id, project_id, name, description, priority, removed, created_at FROM goods
+		WHERE tenant_id = $1 AND NOT removed AND (name ILIKE $2 OR description ILIKE $2) AND ($3 = 0 OR project_id = $3)
+			AND project_id NOT IN (SELECT id FROM projects WHERE archived OR removed)
+		ORDER BY priority LIMIT $4 OFFSET $5`,
+		query.TenantID, pattern, query.ProjectID, query.Limit, query.Offset)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var good Goods
+		err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, decrypted{&good.Description}, &good.Priority, &good.Removed, &good.CreatedAt)
+		if err != nil {
+			return err
+		}
+		list.Goods = append(list.Goods, good)
+	}
+	return rows.Err()
+}
+
+func searchGoodsElastic(ctx context.Context, elastic *search.Elastic, query search.Query, list *GoodsList) error {
+	result, err := elastic.Search(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	list.Meta.Total = result.Total
+	for _, doc := range result.Hits {
+		list.Goods = append(list.Goods, Goods{
+			ID:          doc.ID,
+			ProjectID:   doc.ProjectID,
+			Name:        doc.Name,
+			Description: doc.Description,
+			Priority:    doc.Priority,
+			Removed:     doc.Removed,
+			CreatedAt:   doc.CreatedAt,
+		})
+	}
+	return nil
+}
+
+func hiddenProjectIDs(ctx context.Context, db *sql.DB, tenantID int) ([]int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id FROM projects WHERE tenant_id = $1 AND (archived OR removed)", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// reindex пересобирает индекс Elasticsearch из Postgres: индекс удаляется,
+// создаётся заново и заполняется пачками по reindexBatchSize товаров.
+func reindex(ctx context.Context, db *sql.DB, elastic *search.Elastic) error {
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM goods").Scan(&count); err != nil {
+		return err
+	}
+
+	if err := elastic.DropIndex(ctx); err != nil {
+		return err
+	}
+	if err := elastic.EnsureIndex(ctx); err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, tenant_id, project_id, name, description, priority, removed, created_at FROM goods ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	total := 0
+	batch := make([]search.Document, 0, reindexBatchSize)
+	for rows.Next() {
+		var doc search.Document
+		err := rows.Scan(&doc.ID, &doc.TenantID, &doc.ProjectID, &doc.Name, decrypted{&doc.Description}, &doc.Priority, &doc.Removed, &doc.CreatedAt)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, doc)
+
+		if len(batch) == reindexBatchSize {
+			if err := elastic.Bulk(ctx, batch); err != nil {
+				return err
+			}
+			total += len(batch)
+			batch = batch[:0]
+			scheduler.ReportProgress(ctx, total, count)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := elastic.Bulk(ctx, batch); err != nil {
+		return err
+	}
+	total += len(batch)
+	scheduler.ReportProgress(ctx, total, count)
+
+	log.Printf("reindex: %d goods indexed", total)
+	return elastic.Refresh(ctx)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hezzl-test/internal/bulk"
+	"hezzl-test/internal/labels"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// Fixtures — формат файла с детерминированным набором данных: проекты
+// арендатора и их товары в порядке приоритета.
+type Fixtures struct {
+	Projects []FixtureProject `json:"projects"`
+}
+
+type FixtureProject struct {
+	Name  string        `json:"name"`
+	Goods []FixtureGood `json:"goods"`
+}
+
+type FixtureGood struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Labels      labels.Labels `json:"labels,omitempty"`
+}
+
+var (
+	seedAdjectives = []string{"Compact", "Durable", "Ergonomic", "Lightweight", "Premium", "Rustic", "Sleek", "Smart", "Vintage", "Wireless"}
+	seedMaterials  = []string{"Bamboo", "Ceramic", "Cotton", "Glass", "Granite", "Leather", "Linen", "Oak", "Steel", "Wool"}
+	seedProducts   = []string{"Backpack", "Chair", "Desk Lamp", "Headphones", "Kettle", "Mug", "Notebook", "Speaker", "Table", "Watch"}
+	seedPhrases    = []string{
+		"Designed for everyday use.",
+		"Ships in recyclable packaging.",
+		"Backed by a two-year warranty.",
+		"Hand-finished by local craftspeople.",
+		"Easy to clean and maintain.",
+		"A customer favourite this season.",
+		"Pairs well with the rest of the collection.",
+	}
+	seedShops  = []string{"Store", "Outlet", "Market", "Boutique", "Warehouse"}
+	seedColors = []string{"black", "blue", "green", "grey", "red", "white"}
+	seedTiers  = []string{"basic", "standard", "premium"}
+)
+
+// runSeed обрабатывает подкоманду seed:
+//
+//	hezzl-test seed --projects 5 --goods 1000
+//	hezzl-test seed --fixtures fixtures/demo.json
+func runSeed(ctx context.Context, db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	projects := fs.Int("projects", 5, "number of projects to generate")
+	goods := fs.Int("goods", 1000, "number of goods to generate, spread across projects")
+	tenantID := fs.Int("tenant", defaultTenantID, "tenant to seed")
+	randomSeed := fs.Int64("seed", 1, "random seed; the same seed generates the same data")
+	fixtures := fs.String("fixtures", "", "load this fixtures file instead of generating data")
+	out := fs.String("out", "", "write generated data to this fixtures file instead of loading it")
+	fs.Parse(args)
+
+	var data Fixtures
+	if *fixtures != "" {
+		f, err := os.Open(*fixtures)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(&data); err != nil {
+			return fmt.Errorf("parse %s: %w", *fixtures, err)
+		}
+	} else {
+		data = generateFixtures(*projects, *goods, rand.New(rand.NewSource(*randomSeed)))
+	}
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	}
+
+	return loadFixtures(ctx, db, *tenantID, data)
+}
+
+func generateFixtures(projects, goods int, rng *rand.Rand) Fixtures {
+	var data Fixtures
+	if projects <= 0 {
+		return data
+	}
+
+	data.Projects = make([]FixtureProject, projects)
+	for i := range data.Projects {
+		data.Projects[i].Name = fmt.Sprintf("%s %s", pick(rng, seedAdjectives), pick(rng, seedShops))
+	}
+	for i := 0; i < goods; i++ {
+		p := &data.Projects[i%projects]
+		p.Goods = append(p.Goods, FixtureGood{
+			Name:        fmt.Sprintf("%s %s %s", pick(rng, seedAdjectives), pick(rng, seedMaterials), pick(rng, seedProducts)),
+			Description: pick(rng, seedPhrases) + " " + pick(rng, seedPhrases),
+			Labels:      labels.Labels{"color": pick(rng, seedColors), "tier": pick(rng, seedTiers)},
+		})
+	}
+	return data
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
+
+// loadFixtures создаёт проекты арендатора и загружает их товары через
+// bulk.Load; приоритеты идут подряд в порядке товаров в файле.
+func loadFixtures(ctx context.Context, db *sql.DB, tenantID int, data Fixtures) error {
+	for _, p := range data.Projects {
+		if strings.TrimSpace(p.Name) == "" {
+			return fmt.Errorf("fixtures: project name is required")
+		}
+
+		var projectID int
+		err := db.QueryRowContext(ctx, "INSERT INTO projects (name, tenant_id) VALUES ($1, $2) RETURNING id", p.Name, tenantID).Scan(&projectID)
+		if err != nil {
+			return err
+		}
+
+		result, err := bulk.Load(ctx, db, importInto(tenantID, projectID, ProjectSettings{}), &fixtureSource{goods: p.Goods})
+		if err != nil {
+			return err
+		}
+		log.Printf("seed: project %d %q: %d goods, %d rejected", projectID, p.Name, result.Inserted, result.Rejected)
+		for _, rejection := range result.Errors {
+			log.Printf("seed: project %q: good %d: %s", p.Name, rejection.Line, rejection.Reason)
+		}
+	}
+	return nil
+}
+
+type fixtureSource struct {
+	goods []FixtureGood
+	next  int
+}
+
+func (s *fixtureSource) Next() (bulk.Row, error) {
+	if s.next >= len(s.goods) {
+		return bulk.Row{}, io.EOF
+	}
+	g := s.goods[s.next]
+	s.next++
+
+	if err := g.Labels.Validate(); err != nil {
+		return bulk.Row{}, &bulk.RowError{Line: s.next, Reason: err.Error()}
+	}
+	return bulk.Row{Line: s.next, Name: g.Name, Description: g.Description, Labels: g.Labels}, nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"time"
+)
+
+// ProjectSettings — переопределения поведения сервиса для товаров проекта.
+// CacheTTL в секундах; nil означает срок по умолчанию. WebhooksEnabled
+// выключает уведомления по notification_rules для всего проекта.
+// EncryptDescription хранит описания товаров зашифрованными (см. fieldKeys).
+// MaxGoods ограничивает число товаров проекта вне корзины, nil — без
+// ограничения; меняется только через /admin/project/quota. PriorityGap —
+// шаг приоритетов при стратегии gap, nil означает defaultPriorityGap.
+type ProjectSettings struct {
+	ProjectID          int    `json:"project_id"`
+	PriorityStrategy   string `json:"priority_strategy"`
+	PriorityGap        *int   `json:"priority_gap"`
+	CacheTTL           *int   `json:"cache_ttl"`
+	DefaultLocale      string `json:"default_locale"`
+	WebhooksEnabled    bool   `json:"webhooks_enabled"`
+	EncryptDescription bool   `json:"encrypt_description"`
+	MaxGoods           *int   `json:"max_goods"`
+}
+
+// ProjectSettingsPatch меняет только переданные поля; cache_ttl = 0 и
+// priority_gap = 0 возвращают значения по умолчанию.
+type ProjectSettingsPatch struct {
+	PriorityStrategy   *string `json:"priority_strategy"`
+	PriorityGap        *int    `json:"priority_gap"`
+	CacheTTL           *int    `json:"cache_ttl"`
+	DefaultLocale      *string `json:"default_locale"`
+	WebhooksEnabled    *bool   `json:"webhooks_enabled"`
+	EncryptDescription *bool   `json:"encrypt_description"`
+}
+
+func (p ProjectSettingsPatch) Validate() error {
+	if p.PriorityStrategy != nil && !validPriorityStrategy(*p.PriorityStrategy) {
+		return fmt.Errorf("invalid priority_strategy %q", *p.PriorityStrategy)
+	}
+	if p.PriorityGap != nil && *p.PriorityGap < 0 {
+		return fmt.Errorf("invalid priority_gap %d", *p.PriorityGap)
+	}
+	if p.CacheTTL != nil && (*p.CacheTTL < 0 || time.Duration(*p.CacheTTL)*time.Second > maxProjectCacheTime) {
+		return fmt.Errorf("cache_ttl must be between 0 and %d seconds", int(maxProjectCacheTime.Seconds()))
+	}
+	if p.DefaultLocale != nil {
+		if _, ok := goodNameTemplates[*p.DefaultLocale]; !ok {
+			return fmt.Errorf("unsupported default_locale %q", *p.DefaultLocale)
+		}
+	}
+	if p.EncryptDescription != nil && *p.EncryptDescription && !fieldKeys.Enabled() {
+		return errors.New("encryption keys are not configured")
+	}
+	return nil
+}
+
+func defaultProjectSettings(projectID int) ProjectSettings {
+	return ProjectSettings{
+		ProjectID:        projectID,
+		PriorityStrategy: priorityShift,
+		DefaultLocale:    defaultLocale,
+		WebhooksEnabled:  true,
+	}
+}
+
+// CacheTime — срок жизни кэша товаров проекта; без cache_ttl — def.
+func (s ProjectSettings) CacheTime(def time.Duration) time.Duration {
+	if s.CacheTTL == nil {
+		return def
+	}
+	return time.Duration(*s.CacheTTL) * time.Second
+}
+
+// PriorityGapSize — шаг приоритетов при стратегии gap.
+func (s ProjectSettings) PriorityGapSize() int {
+	if s.PriorityGap == nil {
+		return defaultPriorityGap
+	}
+	return *s.PriorityGap
+}
+
+// GoodsLimit — ограничение числа товаров для bulk.Load, 0 — без ограничения.
+func (s ProjectSettings) GoodsLimit() int {
+	if s.MaxGoods == nil {
+		return 0
+	}
+	return *s.MaxGoods
+}
+
+// checkGoodsQuota возвращает errQuotaExceeded, если добавление n товаров
+// превысит ограничение проекта. Вызывается в транзакции, где проект уже
+// заблокирован резервированием приоритетов.
+func checkGoodsQuota(ctx context.Context, q querier, settings ProjectSettings, n int) error {
+	if settings.MaxGoods == nil {
+		return nil
+	}
+	var count int
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM goods WHERE project_id = $1 AND NOT removed", settings.ProjectID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count+n > *settings.MaxGoods {
+		return errQuotaExceeded
+	}
+	return nil
+}
+
+// loadProjectSettings возвращает настройки проекта или значения по
+// умолчанию, если проект их не менял.
+func loadProjectSettings(ctx context.Context, q querier, projectID int) (ProjectSettings, error) {
+	s := defaultProjectSettings(projectID)
+	err := q.QueryRowContext(ctx, `SELECT priority_strategy, priority_gap, cache_ttl, default_locale, webhooks_enabled, encrypt_description, max_goods
+		FROM project_settings WHERE project_id = $1`, projectID).
+		Scan(&s.PriorityStrategy, &s.PriorityGap, &s.CacheTTL, &s.DefaultLocale, &s.WebhooksEnabled, &s.EncryptDescription, &s.MaxGoods)
+	if err == sql.ErrNoRows {
+		return s, nil
+	}
+	return s, err
+}
+
+func getProjectSettingsHandler(db *sql.DB, projects storage.ProjectsRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		project, err := projects.Get(r.Context(), tenant.FromContext(r.Context()), projectID)
+		if err == nil && project.Removed {
+			err = errProjectNotFound
+		}
+		if err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		settings, err := loadProjectSettings(r.Context(), db, projectID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, settings)
+	}
+}
+
+func updateProjectSettingsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		var patch ProjectSettingsPatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if err := patch.Validate(); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var exists bool
+		err = tx.QueryRowContext(r.Context(), "SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND tenant_id = $2 AND NOT removed)",
+			projectID, tenant.FromContext(r.Context())).Scan(&exists)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if !exists {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+
+		settings := defaultProjectSettings(projectID)
+		err = tx.QueryRowContext(r.Context(), `INSERT INTO project_settings (project_id, priority_strategy, cache_ttl, default_locale, webhooks_enabled, encrypt_description, priority_gap)
+			VALUES ($1, COALESCE($2, 'shift'), NULLIF($3, 0), COALESCE($4, $6), COALESCE($5, true), COALESCE($7, false), NULLIF($8, 0))
+			ON CONFLICT (project_id) DO UPDATE SET
+				priority_strategy = COALESCE($2, project_settings.priority_strategy),
+				priority_gap = CASE WHEN $8::int IS NULL THEN project_settings.priority_gap ELSE NULLIF($8, 0) END,
+				cache_ttl = CASE WHEN $3::int IS NULL THEN project_settings.cache_ttl ELSE NULLIF($3, 0) END,
+				default_locale = COALESCE($4, project_settings.default_locale),
+				webhooks_enabled = COALESCE($5, project_settings.webhooks_enabled),
+				encrypt_description = COALESCE($7, project_settings.encrypt_description),
+				updated_at = now()
+			RETURNING priority_strategy, priority_gap, cache_ttl, default_locale, webhooks_enabled, encrypt_description, max_goods`,
+			projectID, patch.PriorityStrategy, patch.CacheTTL, patch.DefaultLocale, patch.WebhooksEnabled, defaultLocale, patch.EncryptDescription, patch.PriorityGap).
+			Scan(&settings.PriorityStrategy, &settings.PriorityGap, &settings.CacheTTL, &settings.DefaultLocale, &settings.WebhooksEnabled, &settings.EncryptDescription, &settings.MaxGoods)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := audit(r.Context(), tx, "settings", "project", projectID, patch); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, settings)
+	}
+}
+
+type ProjectQuota struct {
+	MaxGoods *int `json:"max_goods"`
+}
+
+// updateProjectQuotaHandler задаёт проекту id наибольшее число товаров;
+// max_goods = null снимает ограничение. Уже превышенное ограничение товары
+// не удаляет, а только запрещает добавлять новые.
+func updateProjectQuotaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		var quota ProjectQuota
+		if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if quota.MaxGoods != nil && *quota.MaxGoods < 0 {
+			response.BadRequest(w, r, fmt.Errorf("invalid max_goods %d", *quota.MaxGoods))
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var exists bool
+		err = tx.QueryRowContext(r.Context(), "SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND tenant_id = $2 AND NOT removed)",
+			projectID, tenant.FromContext(r.Context())).Scan(&exists)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if !exists {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+
+		_, err = tx.ExecContext(r.Context(), `INSERT INTO project_settings (project_id, default_locale, max_goods)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (project_id) DO UPDATE SET max_goods = $3, updated_at = now()`,
+			projectID, defaultLocale, quota.MaxGoods)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := audit(r.Context(), tx, "quota", "project", projectID, quota); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		settings, err := loadProjectSettings(r.Context(), tx, projectID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, settings)
+	}
+}
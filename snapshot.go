@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Заголовки сообщений снимка: потребитель собирает снимок по Snapshot-Id
+// и считает его полным, получив сообщение с Snapshot-Last: true.
+const (
+	snapshotIDHeader    = "Snapshot-Id"
+	snapshotChunkHeader = "Snapshot-Chunk"
+	snapshotLastHeader  = "Snapshot-Last"
+)
+
+type SnapshotResult struct {
+	SnapshotID string `json:"snapshot_id"`
+	Subject    string `json:"subject"`
+	Chunks     int    `json:"chunks"`
+	Goods      int    `json:"goods"`
+}
+
+// ensureSnapshotStream создаёт JetStream-поток для снимков, если его нет.
+func ensureSnapshotStream(js nats.JetStreamContext) error {
+	_, err := js.StreamInfo(snapshotStream)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     snapshotStream,
+			Subjects: []string{snapshotSubject + ".>"},
+			MaxAge:   snapshotRetention,
+		})
+	}
+	return err
+}
+
+// snapshotHandler публикует все товары арендатора в JetStream пачками по
+// snapshotChunkSize, чтобы новый потребитель мог собрать состояние без
+// доступа к базе. Товары читаются одним согласованным снимком базы.
+func snapshotHandler(db *sql.DB, natsConn *nats.Conn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if natsConn == nil {
+			response.Error(w, r, http.StatusServiceUnavailable, response.CodeUnavailable, "errors.dependency.unavailable")
+			return
+		}
+		js, err := natsConn.JetStream()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := ensureSnapshotStream(js); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+		result := SnapshotResult{
+			SnapshotID: fmt.Sprintf("%d-%s", tenantID, ids.NewID()),
+			Subject:    fmt.Sprintf("%s.%d", snapshotSubject, tenantID),
+		}
+
+		err = publishSnapshot(r.Context(), db, js, tenantID, &result)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, result)
+	}
+}
+
+func publishSnapshot(ctx context.Context, db *sql.DB, js nats.JetStreamContext, tenantID int, result *SnapshotResult) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, project_id, category_id, name, description, priority, removed, labels, created_at
+		FROM goods WHERE tenant_id = $1 ORDER BY id`, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	chunk := make([]Goods, 0, snapshotChunkSize)
+	flush := func(last bool) error {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		msg := nats.NewMsg(result.Subject)
+		msg.Data = data
+		msg.Header.Set(tenant.NATSHeader, strconv.Itoa(tenantID))
+		msg.Header.Set(snapshotIDHeader, result.SnapshotID)
+		msg.Header.Set(snapshotChunkHeader, strconv.Itoa(result.Chunks))
+		msg.Header.Set(snapshotLastHeader, strconv.FormatBool(last))
+		if _, err := js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+			return err
+		}
+		result.Chunks++
+		result.Goods += len(chunk)
+		chunk = chunk[:0]
+		return nil
+	}
+
+	for rows.Next() {
+		var good Goods
+		err := rows.Scan(&good.ID, &good.ProjectID, &good.CategoryID, &good.Name, decrypted{&good.Description}, &good.Priority, &good.Removed, &good.Labels, &good.CreatedAt)
+		if err != nil {
+			return err
+		}
+		chunk = append(chunk, good)
+		if len(chunk) == snapshotChunkSize {
+			if err := flush(false); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// Последнее сообщение отправляется всегда, даже пустое: по нему
+	// потребитель понимает, что снимок закончился.
+	return flush(true)
+}
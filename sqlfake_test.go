@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeQuery — ожидаемый запрос к fakeDB: текст запроса должен содержать
+// match. args, если задан, проверяет аргументы; columns и rows — ответ
+// запроса, err — его ошибка.
+type fakeQuery struct {
+	match   string
+	args    func(t *testing.T, args []driver.Value)
+	columns []string
+	rows    [][]driver.Value
+	err     error
+}
+
+// fakeDB — *sql.DB, который отвечает на запросы по сценарию в заданном
+// порядке. BEGIN, COMMIT и ROLLBACK в сценарий не входят, а только
+// записываются в журнал tx.
+type fakeDB struct {
+	t *testing.T
+
+	mu      sync.Mutex
+	queries []fakeQuery
+	tx      []string
+}
+
+func newFakeDB(t *testing.T, queries ...fakeQuery) (*sql.DB, *fakeDB) {
+	t.Helper()
+	f := &fakeDB{t: t, queries: queries}
+	db := sql.OpenDB(f)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		db.Close()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, q := range f.queries {
+			t.Errorf("query %q was not executed", q.match)
+		}
+	})
+	return db, f
+}
+
+// txLog возвращает журнал транзакций, например [BEGIN COMMIT].
+func (f *fakeDB) txLog() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.tx...)
+}
+
+func (f *fakeDB) next(query string, args []driver.NamedValue) (fakeQuery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queries) == 0 {
+		f.t.Errorf("unexpected query %q", query)
+		return fakeQuery{}, fmt.Errorf("fakedb: unexpected query")
+	}
+	q := f.queries[0]
+	if !strings.Contains(query, q.match) {
+		f.t.Errorf("query %q does not contain %q", query, q.match)
+		return fakeQuery{}, fmt.Errorf("fakedb: unexpected query")
+	}
+	f.queries = f.queries[1:]
+	if q.args != nil {
+		values := make([]driver.Value, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		q.args(f.t, values)
+	}
+	return q, q.err
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("fakedb: use newFakeDB")
+}
+
+type fakeConn struct{ f *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakedb: prepared statements are not supported")
+}
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.tx = append(c.f.tx, "BEGIN")
+	return fakeTx(c), nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, err := c.f.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: q.columns, rows: q.rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := c.f.next(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+// CheckNamedValue пропускает аргументы любых типов: проверять их — дело
+// сценария.
+func (c fakeConn) CheckNamedValue(v *driver.NamedValue) error {
+	if valuer, ok := v.Value.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		v.Value = value
+		return err
+	}
+	return nil
+}
+
+type fakeTx fakeConn
+
+func (tx fakeTx) Commit() error   { return tx.end("COMMIT") }
+func (tx fakeTx) Rollback() error { return tx.end("ROLLBACK") }
+
+func (tx fakeTx) end(op string) error {
+	tx.f.mu.Lock()
+	defer tx.f.mu.Unlock()
+	tx.f.tx = append(tx.f.tx, op)
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// goodColumns — столбцы goods в порядке queries.Good, для ответов на
+// запросы sqlc с SELECT *.
+var goodColumns = []string{"id", "project_id", "name", "description", "priority", "removed", "created_at", "removed_at", "tenant_id", "labels", "category_id", "views", "version"}
+
+// argEquals проверяет аргумент i запроса.
+func argEquals(i int, want driver.Value) func(t *testing.T, args []driver.Value) {
+	return func(t *testing.T, args []driver.Value) {
+		t.Helper()
+		if i >= len(args) {
+			t.Errorf("got %d args, want at least %d", len(args), i+1)
+			return
+		}
+		if fmt.Sprint(args[i]) != fmt.Sprint(want) {
+			t.Errorf("arg %d = %v, want %v", i, args[i], want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"hezzl-test/internal/queries"
+	"sync"
+)
+
+type rowsQuerier interface {
+	querier
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// stmtCache готовит часто выполняемые запросы один раз и переиспользует
+// *sql.Stmt: database/sql подготавливает его на каждом соединении пула при
+// первом использовании. Выключенный кэш отправляет запросы как есть — это
+// нужно за PgBouncer в режиме transaction pooling, где подготовленный запрос
+// не переживает транзакцию. Если запрос не удалось подготовить, он тоже
+// выполняется без подготовки.
+type stmtCache struct {
+	db      *sql.DB
+	enabled bool
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache(db *sql.DB, enabled bool) *stmtCache {
+	return &stmtCache{db: db, enabled: enabled, stmts: make(map[string]*sql.Stmt)}
+}
+
+func (c *stmtCache) prepare(ctx context.Context, query string) *sql.Stmt {
+	if !c.enabled {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt
+	}
+	// Текст запроса списка зависит от селектора меток, поэтому число
+	// различных запросов ограничено: остальные выполняются без подготовки.
+	if len(c.stmts) >= maxPreparedStatements {
+		return nil
+	}
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	c.stmts[query] = stmt
+	return stmt
+}
+
+func (c *stmtCache) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(ctx, query)
+}
+
+func (c *stmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := c.prepare(ctx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c *stmtCache) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt := c.prepare(ctx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+func (c *stmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if stmt := c.prepare(ctx, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+// Tx возвращает обёртку, выполняющую подготовленные запросы внутри tx.
+func (c *stmtCache) Tx(tx *sql.Tx) *txStmts {
+	return &txStmts{cache: c, tx: tx}
+}
+
+func (c *stmtCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for query, stmt := range c.stmts {
+		stmt.Close()
+		delete(c.stmts, query)
+	}
+}
+
+type txStmts struct {
+	cache *stmtCache
+	tx    *sql.Tx
+}
+
+// Запросы sqlc выполняются через кэш так же, как написанные вручную.
+var (
+	_ queries.DBTX = (*stmtCache)(nil)
+	_ queries.DBTX = (*txStmts)(nil)
+)
+
+func (t *txStmts) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+func (t *txStmts) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := t.cache.prepare(ctx, query); stmt != nil {
+		return t.tx.StmtContext(ctx, stmt).QueryContext(ctx, args...)
+	}
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *txStmts) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt := t.cache.prepare(ctx, query); stmt != nil {
+		return t.tx.StmtContext(ctx, stmt).QueryRowContext(ctx, args...)
+	}
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *txStmts) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if stmt := t.cache.prepare(ctx, query); stmt != nil {
+		return t.tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
+	}
+	return t.tx.ExecContext(ctx, query, args...)
+}
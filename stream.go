@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"io"
+	"net/http"
+	"sync"
+)
+
+var streamWriters = sync.Pool{
+	New: func() interface{} { return bufio.NewWriterSize(nil, listStreamBufferSize) },
+}
+
+// streamGoodsPage пишет GoodsList с товарами из rows, не собирая страницу в
+// памяти: элементы массива кодируются по одному в буфер фиксированного
+// размера, который сбрасывается в соединение по мере заполнения. Копия ответа
+// дублируется в cache, если он не nil. Возвращает число записанных товаров.
+func streamGoodsPage(w http.ResponseWriter, r *http.Request, meta response.Meta, rows storage.GoodsRows, cache *cappedBuffer) (int, error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	buf := streamWriters.Get().(*bufio.Writer)
+	buf.Reset(w)
+	defer func() {
+		buf.Reset(nil)
+		streamWriters.Put(buf)
+	}()
+
+	var out io.Writer = buf
+	if cache != nil {
+		out = io.MultiWriter(buf, cache)
+	}
+
+	enc := json.NewEncoder(out)
+	if r.URL.Query().Has("pretty") {
+		enc.SetIndent("", "  ")
+	}
+
+	encode := enc.Encode
+	if !response.CanonicalFormat(r) {
+		encode = func(v interface{}) error {
+			data, err := json.Marshal(v)
+			if err == nil {
+				data, err = response.Reformat(r, data)
+			}
+			if err != nil {
+				return err
+			}
+			_, err = out.Write(data)
+			return err
+		}
+	}
+
+	// В прежнем формате список отдаётся массивом без meta.
+	legacy := response.IsLegacy(r)
+	if !legacy {
+		if _, err := io.WriteString(out, `{"meta":`); err != nil {
+			return 0, err
+		}
+		if err := encode(meta); err != nil {
+			return 0, err
+		}
+		if _, err := io.WriteString(out, `,"goods":`); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := io.WriteString(out, "["); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for rows.Next() {
+		stored, err := rows.Good()
+		if err != nil {
+			return count, err
+		}
+		good := goodFromStorage(stored)
+		if count > 0 {
+			if _, err := io.WriteString(out, ","); err != nil {
+				return count, err
+			}
+		}
+		good.link()
+		if err := encode(good); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	end := "]}\n"
+	if legacy {
+		end = "]\n"
+	} else if links := pageLinks(r, meta); links != nil {
+		if _, err := io.WriteString(out, `],"links":`); err != nil {
+			return count, err
+		}
+		if err := encode(links); err != nil {
+			return count, err
+		}
+		end = "}\n"
+	}
+	if _, err := io.WriteString(out, end); err != nil {
+		return count, err
+	}
+	return count, buf.Flush()
+}
+
+// cappedBuffer копит записанное, пока оно укладывается в max; после
+// переполнения содержимое сбрасывается и дальнейшие записи игнорируются.
+type cappedBuffer struct {
+	bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+	if b.Len()+len(p) > b.max {
+		b.overflow = true
+		b.Reset()
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
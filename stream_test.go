@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/labels"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+)
+
+// discardWriter — http.ResponseWriter, который ничего не хранит, чтобы в
+// бенчмарках считались только аллокации кодирования.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// storedGoods — страница из n товаров проекта 3.
+func storedGoods(n int) []storage.Good {
+	goods := make([]storage.Good, n)
+	for i := range goods {
+		goods[i] = storage.Good{
+			ID:          i + 1,
+			ProjectID:   3,
+			Name:        fmt.Sprintf("good %d", i+1),
+			Description: "description of a good in the list",
+			Priority:    i + 1,
+			Labels:      labels.Labels{"color": "red", "size": "xl"},
+			CreatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return goods
+}
+
+// BenchmarkGoodsListJSON сравнивает кодирование страницы /goods/list:
+// baseline — прежний response.JSON с новым буфером на каждый ответ, pooled —
+// response.JSON с буферами из пула, stream — streamGoodsPage без копии для
+// кэша и с ней.
+func BenchmarkGoodsListJSON(b *testing.B) {
+	r := httptest.NewRequest(http.MethodGet, "/goods/list", nil)
+	for _, n := range []int{10, 100, 1000} {
+		stored := storedGoods(n)
+		meta := response.Meta{Total: n, Limit: n}
+		list := GoodsList{Meta: meta, Goods: make([]Goods, n)}
+		for i, good := range stored {
+			list.Goods[i] = goodFromStorage(good)
+			list.Goods[i].link()
+		}
+
+		b.Run("baseline/goods="+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			w := &discardWriter{header: http.Header{}}
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				if err := json.NewEncoder(&buf).Encode(list); err != nil {
+					b.Fatal(err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+				w.WriteHeader(http.StatusOK)
+				w.Write(buf.Bytes())
+			}
+		})
+		b.Run("pooled/goods="+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			w := &discardWriter{header: http.Header{}}
+			for i := 0; i < b.N; i++ {
+				response.JSON(w, r, http.StatusOK, list)
+			}
+		})
+		b.Run("stream/goods="+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			w := &discardWriter{header: http.Header{}}
+			for i := 0; i < b.N; i++ {
+				if _, err := streamGoodsPage(w, r, meta, &goodsPage{goods: stored}, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("stream_cached/goods="+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			w := &discardWriter{header: http.Header{}}
+			for i := 0; i < b.N; i++ {
+				cache := &cappedBuffer{max: listCacheMaxBytes}
+				if _, err := streamGoodsPage(w, r, meta, &goodsPage{goods: stored}, cache); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
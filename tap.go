@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"hezzl-test/internal/bind"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tap"
+	"net/http"
+	"time"
+)
+
+func listTapHandler(t *tap.Tap) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := struct {
+			Limit     int    `query:"limit" min:"1"`
+			RequestID string `query:"requestId"`
+		}{Limit: tapListLimit}
+		if err := bind.Query(r.URL.Query(), &params); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		records, err := t.Records(r.Context(), params.Limit)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if id := params.RequestID; id != "" {
+			matched := []tap.Record{}
+			for _, rec := range records {
+				if rec.RequestID == id {
+					matched = append(matched, rec)
+				}
+			}
+			records = matched
+		}
+
+		response.JSON(w, r, http.StatusOK, records)
+	}
+}
+
+func armTapHandler(t *tap.Tap) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.URL.Query().Get("requestId")
+		if requestID == "" {
+			response.BadRequest(w, r, errors.New("requestId is required"))
+			return
+		}
+
+		ttl := tapArmTime
+		if v := r.URL.Query().Get("ttl"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				response.BadRequest(w, r, errors.New("invalid ttl"))
+				return
+			}
+			ttl = d
+		}
+
+		if err := t.Arm(r.Context(), requestID, ttl); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.NoContent(w)
+	}
+}
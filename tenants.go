@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/queries"
+	"hezzl-test/internal/response"
+	"net/http"
+	"time"
+)
+
+var errTenantExists = errs.Conflict("errors.tenant.exists")
+
+type Tenant struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func listTenantsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := queries.New(db).ListTenants(r.Context())
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		tenants := make([]Tenant, 0, len(rows))
+		for _, row := range rows {
+			tenants = append(tenants, Tenant{ID: int(row.ID), Name: row.Name, CreatedAt: row.CreatedAt})
+		}
+
+		response.JSON(w, r, http.StatusOK, tenants)
+	}
+}
+
+func createTenantHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var t Tenant
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if t.Name == "" {
+			response.BadRequest(w, r, errors.New("name is required"))
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+		q := queries.New(tx)
+
+		row, err := q.CreateTenant(r.Context(), t.Name)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errTenantExists)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		t.ID, t.CreatedAt = int(row.ID), row.CreatedAt
+
+		err = q.CreateProject(r.Context(), queries.CreateProjectParams{TenantID: row.ID, Name: defaultProjectName})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusCreated, t)
+	}
+}
+
+func tenantIDs(db *sql.DB) ([]int, error) {
+	rows, err := queries.New(db).TenantIDs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(rows))
+	for _, id := range rows {
+		ids = append(ids, int(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hezzl-test/internal/errs"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errTokenNotFound  = errs.NotFound("errors.token.notFound")
+	errTokenForbidden = errs.Forbidden("errors.token.forbidden")
+)
+
+const (
+	capabilityRead  = "read"
+	capabilityWrite = "write"
+
+	// Префикс отличает токены проектов от JWT в Authorization.
+	projectTokenPrefix = "pt_"
+)
+
+// projectTokenRoutes — маршруты, доступные по токену проекта, и нужная им
+// возможность. Проект каждого из них задаётся параметром projectId, и он
+// должен совпадать с проектом токена.
+var projectTokenRoutes = map[string]string{
+	"/goods/search":             capabilityRead,
+	"/goods/trash":              capabilityRead,
+	"/analytics/goods/activity": capabilityRead,
+	"/goods/import":             capabilityWrite,
+	"/goods/import/remote":      capabilityWrite,
+}
+
+type ProjectToken struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"project_id"`
+	Capability  string    `json:"capability"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// Token возвращается один раз, при выпуске.
+	Token string `json:"token,omitempty"`
+}
+
+type NewProjectToken struct {
+	ProjectID   int    `json:"project_id"`
+	Capability  string `json:"capability"`
+	Description string `json:"description"`
+	// TTL — срок жизни в секундах; 0 означает projectTokenTTL.
+	TTL int `json:"ttl"`
+}
+
+func hashProjectToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createProjectTokenHandler выпускает токен доступа к одному проекту с
+// возможностью read или write.
+func createProjectTokenHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req NewProjectToken
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if req.Capability != capabilityRead && req.Capability != capabilityWrite {
+			response.BadRequest(w, r, fmt.Errorf("invalid capability %q", req.Capability))
+			return
+		}
+		ttl := projectTokenTTL
+		if req.TTL != 0 {
+			ttl = time.Duration(req.TTL) * time.Second
+		}
+		if ttl <= 0 || ttl > maxProjectTokenTTL {
+			response.BadRequest(w, r, fmt.Errorf("ttl must be between 1 and %d seconds", int(maxProjectTokenTTL.Seconds())))
+			return
+		}
+
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		token := ProjectToken{
+			ProjectID:   req.ProjectID,
+			Capability:  req.Capability,
+			Description: req.Description,
+			Token:       projectTokenPrefix + base64.RawURLEncoding.EncodeToString(secret),
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		err = tx.QueryRowContext(r.Context(), `INSERT INTO api_tokens (tenant_id, project_id, capability, description, token_hash, expires_at)
+			SELECT $1, id, $3, $4, $5, now() + $6 * interval '1 second' FROM projects WHERE id = $2 AND tenant_id = $1 AND NOT removed
+			RETURNING id, created_at, expires_at`,
+			tenantID, req.ProjectID, req.Capability, req.Description, hashProjectToken(token.Token), int(ttl.Seconds())).
+			Scan(&token.ID, &token.CreatedAt, &token.ExpiresAt)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := audit(r.Context(), tx, "create", "api_token", token.ID, map[string]interface{}{
+			"project_id": token.ProjectID,
+			"capability": token.Capability,
+			"expires_at": token.ExpiresAt,
+		}); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusCreated, token)
+	}
+}
+
+// listProjectTokensHandler отдаёт действующие токены арендатора без
+// самих значений токенов.
+func listProjectTokensHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := db.QueryContext(r.Context(), `SELECT id, project_id, capability, description, created_at, expires_at
+			FROM api_tokens WHERE tenant_id = $1 AND revoked_at IS NULL AND expires_at > now()
+			ORDER BY id`, tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		tokens := []ProjectToken{}
+		for rows.Next() {
+			var t ProjectToken
+			if err := rows.Scan(&t.ID, &t.ProjectID, &t.Capability, &t.Description, &t.CreatedAt, &t.ExpiresAt); err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			tokens = append(tokens, t)
+		}
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, tokens)
+	}
+}
+
+func revokeProjectTokenHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(r.Context(), "UPDATE api_tokens SET revoked_at = now() WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL",
+			id, tenant.FromContext(r.Context()))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			response.Fail(w, r, errTokenNotFound)
+			return
+		}
+		if err := audit(r.Context(), tx, "revoke", "api_token", id, struct{}{}); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		response.NoContent(w)
+	}
+}
+
+// projectTokens принимает токены проектов в Authorization. Запрос с таким
+// токеном пропускается только на маршруты из projectTokenRoutes с
+// projectId проекта токена, а дальше идёт как запрос арендатора токена от
+// пользователя token:<id>: они кладутся прямо в контекст, и tenant.Middleware
+// их не переопределяет. Остальные запросы проходят без изменений.
+func projectTokens(db *sql.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "+projectTokenPrefix)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var id, tenantID, projectID int
+			var capability string
+			err := db.QueryRowContext(r.Context(), `SELECT id, tenant_id, project_id, capability FROM api_tokens
+				WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > now()`,
+				hashProjectToken(projectTokenPrefix+raw)).Scan(&id, &tenantID, &projectID, &capability)
+			if err == sql.ErrNoRows {
+				response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "errors.token.invalid")
+				return
+			}
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+
+			need, ok := projectTokenRoutes[r.URL.Path]
+			if r.Method != http.MethodGet {
+				need = capabilityWrite
+			}
+			if !ok || (need == capabilityWrite && capability != capabilityWrite) ||
+				r.URL.Query().Get("projectId") != strconv.Itoa(projectID) {
+				response.Fail(w, r, errTokenForbidden)
+				return
+			}
+
+			ctx := tenant.WithUser(tenant.WithTenant(r.Context(), tenantID), "token:"+strconv.Itoa(id))
+			r = r.Clone(ctx)
+			r.Header.Del("Authorization")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
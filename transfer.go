@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/metrics"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type GoodsTransfer struct {
+	IDs       []int  `json:"ids"`
+	ProjectID int    `json:"projectId"`
+	Mode      string `json:"mode"`
+}
+
+// transferGoodsHandler переносит (mode=move) или копирует (mode=copy) товары
+// в другой проект. Товары встают в конец целевого проекта в прежнем
+// относительном порядке; целевой проект блокируется на время транзакции,
+// чтобы параллельные переносы не выдали одинаковые приоритеты.
+func transferGoodsHandler(db *sql.DB, redisClient deps.Cache, budgets *cachebudget.Budget, cacheTTL time.Duration, natsConn deps.Publisher, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dryRun, err := dryRunParam(r)
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		var req GoodsTransfer
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if len(req.IDs) == 0 {
+			response.BadRequest(w, r, errors.New("ids are required"))
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = "move"
+		}
+		if req.Mode != "move" && req.Mode != "copy" {
+			response.BadRequest(w, r, errors.New(`mode must be "move" or "copy"`))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.Begin()
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var projectID int
+		var archived bool
+		err = tx.QueryRow("SELECT id, archived FROM projects WHERE id = $1 AND tenant_id = $2 AND NOT removed FOR UPDATE",
+			req.ProjectID, tenantID).Scan(&projectID, &archived)
+		if err == sql.ErrNoRows {
+			response.Fail(w, r, errProjectNotFound)
+			return
+		}
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if archived {
+			response.Fail(w, r, errProjectArchived)
+			return
+		}
+
+		rows, err := tx.Query(`SELECT id, project_id, name, description, priority, removed, labels, created_at FROM goods
+			WHERE id = ANY($1) AND tenant_id = $2
+			ORDER BY priority, id
+			FOR UPDATE`,
+			pq.Array(req.IDs), tenantID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		var goods []Goods
+		for rows.Next() {
+			var good Goods
+			err := rows.Scan(&good.ID, &good.ProjectID, &good.Name, decrypted{&good.Description}, &good.Priority, &good.Removed, &good.Labels, &good.CreatedAt)
+			if err != nil {
+				rows.Close()
+				response.InternalError(w, r, err)
+				return
+			}
+			goods = append(goods, good)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if len(goods) != len(uniqueInts(req.IDs)) {
+			response.Fail(w, r, errGoodNotFound)
+			return
+		}
+
+		for _, good := range goods {
+			if req.Mode == "copy" {
+				continue
+			}
+			if err := storage.CheckWritable(r.Context(), tx, tenantID, good.ProjectID); err != nil {
+				response.Fail(w, r, err)
+				return
+			}
+		}
+
+		settings, err := loadProjectSettings(r.Context(), tx, projectID)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		added := 0
+		for _, good := range goods {
+			if !good.Removed && (req.Mode == "copy" || good.ProjectID != projectID) {
+				added++
+			}
+		}
+		if err := checkGoodsQuota(r.Context(), tx, settings, added); err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+
+		maxPriority, err := reservePriorities(r.Context(), tx, tenantID, projectID, len(goods))
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		before := make([]Goods, len(goods))
+		copy(before, goods)
+		versions := make([]int64, len(goods))
+
+		sources := make([]int, len(goods))
+		for i, good := range goods {
+			sources[i] = good.ProjectID
+		}
+		encrypted, err := encryptedProjects(r.Context(), tx, sources)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		for i := range goods {
+			good := &goods[i]
+			good.ProjectID = projectID
+			good.Priority = maxPriority + i + 1
+
+			// Описание запечатывается ключом целевого проекта: при переносе
+			// из проекта с другой настройкой шифрования иначе осталось бы
+			// прежним.
+			var description string
+			description, err = sealDescription(settings, good.Description)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			if req.Mode == "copy" {
+				err = tx.QueryRow(`INSERT INTO goods (tenant_id, project_id, name, description, priority, removed, labels, created_at)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+					RETURNING id, created_at, version`,
+					tenantID, good.ProjectID, good.Name, description, good.Priority, good.Removed, good.Labels).Scan(&good.ID, &good.CreatedAt, &versions[i])
+			} else {
+				err = tx.QueryRow("UPDATE goods SET project_id = $1, priority = $2, description = $3, version = version + 1 WHERE id = $4 RETURNING version",
+					good.ProjectID, good.Priority, description, good.ID).Scan(&versions[i])
+			}
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		if dryRun {
+			ids := make([]int, len(before))
+			for i, good := range before {
+				ids[i] = good.ID
+			}
+			response.JSON(w, r, http.StatusOK, newDryRun(ids))
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		if req.Mode == "copy" {
+			metrics.GoodsCreated(projectID, len(goods))
+		} else {
+			metrics.GoodsUpdated(projectID, len(goods))
+		}
+
+		subject := "good_updated"
+		if req.Mode == "copy" {
+			subject = "new_good_created"
+		}
+		for i, good := range goods {
+			good, version := good, versions[i]
+			data, err := json.Marshal(good)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			var event []byte
+			if req.Mode == "copy" {
+				event, err = json.Marshal(goodEvent(good, settings.EncryptDescription))
+			} else {
+				event, err = json.Marshal(updatedEvent(before[i], good, settings.EncryptDescription || encrypted[before[i].ProjectID]))
+			}
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			err = effects.Submit(r.Context(), subject, func(ctx context.Context) error {
+				budgets.Set(ctx, good.ProjectID, goodCacheKey(tenantID, good.ID), data, cacheTTL)
+				return publishGood(ctx, natsConn, subject, good.ID, version, event)
+			})
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		// Страницы списков кэшируются на арендатора целиком, так что сброс
+		// убирает и товары, ушедшие из исходных проектов.
+		err = effects.Submit(r.Context(), "invalidate_goods_lists", func(ctx context.Context) error {
+			return invalidateGoodsLists(ctx, redisClient, tenantID)
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		linkGoods(goods)
+		response.JSON(w, r, http.StatusOK, map[string][]Goods{"goods": goods})
+	}
+}
+
+func uniqueInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	unique := make([]int, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
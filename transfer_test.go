@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"hezzl-test/internal/cachebudget"
+	"hezzl-test/internal/crypt"
+	"hezzl-test/internal/localcache"
+)
+
+func TestTransferGoodsMoveReseals(t *testing.T) {
+	keys, err := crypt.Parse("k1:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useKeys(t, keys)
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	db, fake := newFakeDB(t,
+		fakeQuery{match: "FROM projects WHERE id = $1 AND tenant_id = $2 AND NOT removed FOR UPDATE",
+			columns: []string{"id", "archived"}, rows: [][]driver.Value{{int64(4), false}}},
+		fakeQuery{match: "FROM goods",
+			columns: []string{"id", "project_id", "name", "description", "priority", "removed", "labels", "created_at"},
+			rows:    [][]driver.Value{{int64(11), int64(3), "Tea", "green tea", int64(2), false, []byte("{}"), created}}},
+		fakeQuery{match: "SELECT archived, removed FROM projects",
+			columns: []string{"archived", "removed"}, rows: [][]driver.Value{{false, false}}},
+		// Целевой проект шифрует описания, исходный — нет.
+		fakeQuery{match: "FROM project_settings WHERE project_id = $1",
+			columns: []string{"priority_strategy", "priority_gap", "cache_ttl", "default_locale", "webhooks_enabled", "encrypt_description", "max_goods"},
+			rows:    [][]driver.Value{{"append", nil, nil, "ru", false, true, nil}}},
+		fakeQuery{match: "INSERT INTO project_priority_counters",
+			columns: []string{"last_priority"}, rows: [][]driver.Value{{int64(6)}}},
+		fakeQuery{match: "WHERE project_id = ANY($1) AND encrypt_description",
+			columns: []string{"array_agg"}, rows: [][]driver.Value{{[]byte("{}")}}},
+		fakeQuery{match: "UPDATE goods SET project_id = $1",
+			args: func(t *testing.T, args []driver.Value) {
+				sealed, _ := args[2].(string)
+				if !keys.Current(sealed) {
+					t.Errorf("description %q is not sealed with the target project key", args[2])
+				}
+				if plain, err := keys.Decrypt(sealed); err != nil || plain != "green tea" {
+					t.Errorf("description decrypts to %q, %v", plain, err)
+				}
+			},
+			columns: []string{"version"}, rows: [][]driver.Value{{int64(3)}}},
+	)
+
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	ctx := context.Background()
+	cache.Set(ctx, "goods:list:7:3:page", "[]", 0)
+
+	publisher, effects, events := recordEvents(t)
+	h := transferGoodsHandler(db, cache, cachebudget.New(cache, 1<<20), time.Minute, publisher, effects)
+	w := serveBody(t, h, "POST", "/goods/transfer", `{"ids":[11],"projectId":4}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if len(events()["good_updated"]) != 1 {
+		t.Error("good_updated is not published")
+	}
+	if got := fake.txLog(); len(got) != 2 || got[1] != "COMMIT" {
+		t.Errorf("transactions = %v", got)
+	}
+	if _, err := cache.Get(ctx, "goods:list:7:3:page").Result(); err == nil {
+		t.Error("source project list is still cached")
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/objectstore"
+	"hezzl-test/internal/queries"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/tenant"
+	"hezzl-test/internal/worker"
+	"log"
+	"math"
+	"net/http"
+	"time"
+)
+
+// TrashedGood — удалённый товар корзины: когда удалён и сколько дней
+// осталось до окончательной очистки задачей retention_purge.
+type TrashedGood struct {
+	Goods
+	RemovedAt     time.Time `json:"removed_at"`
+	RetentionDays int       `json:"retention_days_left"`
+}
+
+type TrashList struct {
+	Meta  response.Meta       `json:"meta"`
+	Goods []TrashedGood       `json:"goods"`
+	Links *response.PageLinks `json:"links,omitempty"`
+}
+
+type TrashAction struct {
+	IDs []int `json:"ids"`
+}
+
+func retentionDaysLeft(removedAt time.Time) int {
+	left := removedRetention - clk.Now().Sub(removedAt)
+	if left <= 0 {
+		return 0
+	}
+	return int(math.Ceil(left.Hours() / 24))
+}
+
+func listTrashHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID, err := queryInt(r, "projectId")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		limit, offset, err := pageParams(r)
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+		list := TrashList{
+			Meta:  response.Meta{Limit: limit, Offset: offset},
+			Goods: []TrashedGood{},
+		}
+
+		q := queries.New(db)
+		total, err := q.CountTrash(r.Context(), queries.CountTrashParams{TenantID: int32(tenantID), ProjectID: int32(projectID)})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		list.Meta.Total = int(total)
+		list.Meta.Removed = list.Meta.Total
+
+		rows, err := q.ListTrash(r.Context(), queries.ListTrashParams{
+			TenantID:  int32(tenantID),
+			ProjectID: int32(projectID),
+			Limit:     int32(limit),
+			Offset:    int32(offset),
+		})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		for _, row := range rows {
+			good, err := goodFromRow(row.Good)
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			list.Goods = append(list.Goods, TrashedGood{Goods: good, RemovedAt: row.TrashedAt, RetentionDays: retentionDaysLeft(row.TrashedAt)})
+		}
+
+		list.Links = pageLinks(r, list.Meta)
+		for i := range list.Goods {
+			list.Goods[i].link()
+		}
+		response.Pagination(w, r, list.Meta)
+		response.JSON(w, r, http.StatusOK, list)
+	}
+}
+
+// restoreTrashHandler возвращает удалённые товары из корзины. Товары, которых
+// нет в корзине или чей проект в архиве, пропускаются.
+func restoreTrashHandler(db *sql.DB, redisClient deps.Cache, natsConn deps.Publisher, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TrashAction
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if len(req.IDs) == 0 {
+			response.BadRequest(w, r, errors.New("ids are required"))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		rows, err := queries.New(db).RestoreGoods(r.Context(), queries.RestoreGoodsParams{Ids: int32s(req.IDs), TenantID: int32(tenantID)})
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		restored := make([]Goods, len(rows))
+		versions := make([]int64, len(rows))
+		for i, row := range rows {
+			if restored[i], err = goodFromRow(row); err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			versions[i] = row.Version
+		}
+
+		projects := make([]int, len(restored))
+		for i, good := range restored {
+			projects[i] = good.ProjectID
+		}
+		encrypted, err := encryptedProjects(r.Context(), db, projects)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		for i, good := range restored {
+			good, version := good, versions[i]
+			old := good
+			old.Removed = true
+			data, err := json.Marshal(updatedEvent(old, good, encrypted[good.ProjectID]))
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			err = effects.Submit(r.Context(), "good_updated", func(ctx context.Context) error {
+				return publishGood(ctx, natsConn, "good_updated", good.ID, version, data)
+			})
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+		if len(restored) > 0 {
+			err = effects.Submit(r.Context(), "trash_restored", func(ctx context.Context) error {
+				return invalidateGoodsLists(ctx, redisClient, tenantID)
+			})
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		linkGoods(restored)
+		response.JSON(w, r, http.StatusOK, map[string][]Goods{"goods": restored})
+	}
+}
+
+// purgeTrashHandler окончательно удаляет товары из корзины вместе с их
+// вложениями, не дожидаясь retention_purge.
+func purgeTrashHandler(db *sql.DB, redisClient deps.Cache, s3 *objectstore.S3, natsConn deps.Publisher, effects *worker.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dryRun, err := dryRunParam(r)
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		var req TrashAction
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+		if len(req.IDs) == 0 {
+			response.BadRequest(w, r, errors.New("ids are required"))
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+		defer tx.Rollback()
+
+		purged, objectKeys, err := purgeGoods(r.Context(), tx, tenantID, req.IDs, true)
+		if err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		if dryRun {
+			ids := make([]int, len(purged))
+			for i, good := range purged {
+				ids[i] = good.ID
+			}
+			response.JSON(w, r, http.StatusOK, newDryRun(ids))
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			response.InternalError(w, r, err)
+			return
+		}
+
+		deleteAttachmentObjects(r.Context(), s3, objectKeys)
+
+		ids := []int{}
+		for _, good := range purged {
+			good := good
+			ids = append(ids, good.ID)
+			data, err := json.Marshal(map[string]int{"id": good.ID, "project_id": good.ProjectID})
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+			err = effects.Submit(r.Context(), "good_deleted", func(ctx context.Context) error {
+				return publishGood(ctx, natsConn, "good_deleted", good.ID, good.Version, data)
+			})
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+		if len(purged) > 0 {
+			err = effects.Submit(r.Context(), "trash_purged", func(ctx context.Context) error {
+				return dropPurgedGoods(ctx, redisClient, tenantID, purged)
+			})
+			if err != nil {
+				response.InternalError(w, r, err)
+				return
+			}
+		}
+
+		response.JSON(w, r, http.StatusOK, map[string][]int{"ids": ids})
+	}
+}
+
+// purgedGood — товар, окончательно удалённый purgeGoods, и его версия для
+// события good_deleted.
+type purgedGood struct {
+	ID        int
+	ProjectID int
+	Version   int64
+}
+
+// purgeGoods окончательно удаляет из корзины товары ids арендатора и пишет
+// удаление каждого в аудит. Метаданные вложений удаляются каскадом, а ключи
+// их объектов возвращаются: удалить объекты deleteAttachmentObjects нужно
+// после фиксации транзакции. С writableOnly товары архивных и удалённых
+// проектов не трогаются.
+func purgeGoods(ctx context.Context, tx *sql.Tx, tenantID int, ids []int, writableOnly bool) ([]purgedGood, []string, error) {
+	q := queries.New(tx)
+	objectKeys, err := q.TrashObjectKeys(ctx, queries.TrashObjectKeysParams{Ids: int32s(ids), TenantID: int32(tenantID), WritableOnly: writableOnly})
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := q.PurgeGoods(ctx, queries.PurgeGoodsParams{Ids: int32s(ids), TenantID: int32(tenantID), WritableOnly: writableOnly})
+	if err != nil {
+		return nil, nil, err
+	}
+	purged := make([]purgedGood, len(rows))
+	for i, row := range rows {
+		purged[i] = purgedGood{ID: int(row.ID), ProjectID: int(row.ProjectID), Version: row.Version}
+	}
+
+	ctx = tenant.WithTenant(ctx, tenantID)
+	for _, good := range purged {
+		if err := audit(ctx, tx, "purge", "good", good.ID, map[string]int{"project_id": good.ProjectID}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return purged, objectKeys, nil
+}
+
+// dropPurgedGoods убирает из кэша карточки окончательно удалённых товаров и
+// списки товаров арендатора.
+func dropPurgedGoods(ctx context.Context, redisClient deps.Cache, tenantID int, purged []purgedGood) error {
+	keys := make([]string, len(purged))
+	for i, good := range purged {
+		keys[i] = goodCacheKey(tenantID, good.ID)
+	}
+	if err := redisClient.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	return invalidateGoodsLists(ctx, redisClient, tenantID)
+}
+
+// deleteAttachmentObjects удаляет объекты вложений удалённых товаров.
+// Ошибки только логируются: строки вложений уже удалены, и повторить
+// удаление объекта будет не по чему.
+func deleteAttachmentObjects(ctx context.Context, s3 *objectstore.S3, keys []string) {
+	for _, key := range keys {
+		if err := s3.Delete(ctx, key); err != nil {
+			log.Printf("attachments: delete %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hezzl-test/internal/deps"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/tenant"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Просмотры копятся в Redis по ключу на товар и раз в viewsFlushInterval
+// переносятся в goods.views задачей views_flush.
+const viewsKeyPrefix = "views:good:"
+
+func viewsKey(id int) string {
+	return viewsKeyPrefix + strconv.Itoa(id)
+}
+
+// getGoodHandler отдаёт один товар и засчитывает ему просмотр. В views
+// входят и ещё не перенесённые в базу просмотры.
+func getGoodHandler(goods storage.GoodsRepository, redisClient deps.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := queryInt(r, "id")
+		if err != nil {
+			response.BadRequest(w, r, err)
+			return
+		}
+
+		stored, err := goods.Get(r.Context(), tenant.FromContext(r.Context()), id)
+		if err != nil {
+			response.Fail(w, r, err)
+			return
+		}
+		good := goodFromStorage(stored)
+
+		pending, err := redisClient.IncrBy(r.Context(), viewsKey(good.ID), 1).Result()
+		if err != nil {
+			log.Printf("views: count good %d: %v", good.ID, err)
+		}
+		good.Views += pending
+		good.link()
+
+		response.JSON(w, r, http.StatusOK, good)
+	}
+}
+
+// flushViews переносит накопленные в Redis просмотры в goods.views. Ключ
+// забирается GETDEL, поэтому просмотры, пришедшие во время переноса,
+// попадут в следующий запуск.
+func flushViews(ctx context.Context, db *sql.DB, redisClient deps.Cache) error {
+	iter := redisClient.Scan(ctx, 0, viewsKeyPrefix+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	flushed := 0
+	for _, key := range keys {
+		id, err := strconv.Atoi(strings.TrimPrefix(key, viewsKeyPrefix))
+		if err != nil {
+			continue
+		}
+		n, err := redisClient.GetDel(ctx, key).Int64()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE goods SET views = views + $1 WHERE id = $2", n, id); err != nil {
+			// Снятый счётчик возвращается, чтобы не потерять просмотры.
+			if rerr := redisClient.IncrBy(ctx, key, n).Err(); rerr != nil {
+				log.Printf("views_flush: restore %s: %v", key, rerr)
+			}
+			return fmt.Errorf("flush views of good %d: %w", id, err)
+		}
+		flushed++
+	}
+	log.Printf("views_flush: %d goods flushed", flushed)
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"hezzl-test/internal/localcache"
+	"hezzl-test/internal/response"
+	"hezzl-test/internal/storage"
+	"hezzl-test/internal/storage/mocks"
+)
+
+func TestGetGoodHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	goods := mocks.NewMockGoodsRepository(ctrl)
+	goods.EXPECT().Get(gomock.Any(), testTenantID, 5).Return(storage.Good{ID: 5, ProjectID: 3, Name: "Tea", Description: "Green", Priority: 2, Views: 10}, nil)
+
+	cache := localcache.New(time.Minute)
+	defer cache.Close()
+	cache.Set(context.Background(), viewsKey(5), 4, 0)
+
+	w := serve(t, getGoodHandler(goods, cache), "GET", "/good?id=5")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var good Goods
+	decodeBody(t, w, &good)
+	if good.ID != 5 || good.Name != "Tea" || good.Description != "Green" {
+		t.Fatalf("good = %+v", good)
+	}
+	// 10 просмотров в базе, 4 ещё не перенесены и 1 — этот запрос.
+	if good.Views != 15 {
+		t.Fatalf("views = %d, want 15", good.Views)
+	}
+}
+
+func TestGetGoodHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		err    error
+		status int
+		key    string
+	}{
+		{name: "bad id", target: "/good?id=x", status: http.StatusBadRequest, key: "errors.request.invalid"},
+		{name: "not found", target: "/good?id=5", err: storage.ErrGoodNotFound, status: http.StatusNotFound, key: "errors.good.notFound"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			goods := mocks.NewMockGoodsRepository(ctrl)
+			if tt.err != nil {
+				goods.EXPECT().Get(gomock.Any(), testTenantID, 5).Return(storage.Good{}, tt.err)
+			}
+
+			w := serve(t, getGoodHandler(goods, nil), "GET", tt.target)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			var body response.ErrorBody
+			decodeBody(t, w, &body)
+			if body.Key != tt.key {
+				t.Fatalf("key = %q, want %q", body.Key, tt.key)
+			}
+		})
+	}
+}